@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"kore-on/cmd/koreonctl/conf"
+	"kore-on/cmd/koreonctl/conf/templates"
+	"kore-on/pkg/helm"
+	"kore-on/pkg/logger"
+	"kore-on/pkg/model"
+	"kore-on/pkg/utils"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+type strAddonStatusCmd struct {
+	output     string
+	kubeconfig string
+	privateKey string
+	user       string
+}
+
+func addonListCmd() *cobra.Command {
+	addonList := &strAddonStatusCmd{}
+
+	cmd := &cobra.Command{
+		Use:          "list [flags]",
+		Short:        "Show addon applications in addon.toml",
+		Long:         "This command lists the applications in addon.toml with the desired chart version and namespace.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return addonList.list()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&addonList.output, "output", "o", "text", "output format (text, json)")
+
+	return cmd
+}
+
+func addonStatusCmd() *cobra.Command {
+	addonStatus := &strAddonStatusCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "status [flags]",
+		Short: "Show helm release status of addon applications",
+		Long: "This command shows the installed chart version, revision, status and namespace of the helm release\n" +
+			"of each application in addon.toml, and the drift from the desired state.\n" +
+			"The releases are listed with the local helm and --kubeconfig, or with helm on the k8s-master-ip host.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return addonStatus.status()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&addonStatus.output, "output", "o", "text", "output format (text, json)")
+	f.StringVar(&addonStatus.kubeconfig, "kubeconfig", "", "kubeconfig file path to query the cluster from this host")
+	f.StringVarP(&addonStatus.privateKey, "private-key", "p", "", "Specify ssh key path")
+	f.StringVarP(&addonStatus.user, "user", "u", "", "login user")
+
+	return cmd
+}
+
+func (c *strAddonStatusCmd) list() error {
+	addonToml, err := c.addonToml()
+	if err != nil {
+		return err
+	}
+
+	return c.print(helm.Desired(addonToml.Apps), templates.AddonListText)
+}
+
+func (c *strAddonStatusCmd) status() error {
+	addonToml, err := c.addonToml()
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	if c.kubeconfig != "" {
+		out, err = c.localReleases()
+	} else {
+		out, err = c.remoteReleases(addonToml)
+	}
+	if err != nil {
+		return err
+	}
+
+	releases, err := helm.ParseReleases(out)
+	if err != nil {
+		return err
+	}
+
+	return c.print(helm.Compare(addonToml.Apps, releases), templates.AddonStatusText)
+}
+
+// addonToml - addon.toml with the chart version of the catalog apps resolved as the deployment does
+func (c *strAddonStatusCmd) addonToml() (model.AddonToml, error) {
+	workDir, err := checkDirTree()
+	if err != nil {
+		logger.Error(err)
+		os.Exit(1)
+	}
+
+	addonToml, err := utils.GetAddonTomlConfig(workDir + "/" + conf.AddOnConfigFile)
+	if err != nil {
+		return addonToml, err
+	}
+
+	k8sVersion := ""
+	koreOnConfigFilePath := workDir + "/config/" + conf.KoreOnConfigFile
+	if utils.FileExists(koreOnConfigFilePath) {
+		koreonToml, err := utils.GetKoreonTomlConfig(koreOnConfigFilePath)
+		if err != nil {
+			return addonToml, err
+		}
+		k8sVersion = koreonToml.Kubernetes.Version
+	}
+
+	names := []string{}
+	for name := range addonToml.Apps {
+		names = append(names, name)
+	}
+	if _, err := utils.SetAddonChartVersion(addonToml.Apps, names, k8sVersion); err != nil {
+		return addonToml, err
+	}
+	return addonToml, nil
+}
+
+// localReleases - helm list with the local helm binary and the kubeconfig
+func (c *strAddonStatusCmd) localReleases() ([]byte, error) {
+	binary, err := exec.LookPath("helm")
+	if err != nil {
+		return nil, fmt.Errorf("helm is not found in PATH. install helm or query through the k8s-master-ip host without --kubeconfig: %w", err)
+	}
+	kubeconfig, err := filepath.Abs(c.kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := exec.Command(binary, helm.ListArgs(kubeconfig)...).Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("helm list: %s", strings.TrimSpace(string(e.Stderr)))
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// remoteReleases - helm list on the k8s-master-ip host with the kubeconfig of the cluster
+func (c *strAddonStatusCmd) remoteReleases(addonToml model.AddonToml) ([]byte, error) {
+	if addonToml.Addon.K8sMasterIP == "" {
+		return nil, fmt.Errorf("k8s-master-ip in addon.toml or --kubeconfig is required")
+	}
+	if c.privateKey == "" || c.user == "" {
+		return nil, fmt.Errorf("--private-key and --user are required to connect to %s", addonToml.Addon.K8sMasterIP)
+	}
+
+	port := addonToml.Addon.SSHPort
+	if port == 0 {
+		port = 22
+	}
+	s := &utils.SSH{
+		IP:   addonToml.Addon.K8sMasterIP,
+		User: c.user,
+		Cert: c.privateKey,
+		Port: port,
+	}
+	s.Connect()
+	defer s.Close()
+
+	command := "helm " + strings.Join(helm.ListArgs(conf.Addon["KubeConfigDir"]+"/"+conf.KoreOnKubeConfig), " ")
+	if c.user != "root" {
+		command = "sudo -n " + command
+	}
+	return s.Output(command)
+}
+
+func (c *strAddonStatusCmd) print(data []model.AddonAppStatus, text string) error {
+	switch c.output {
+	case "json":
+		b, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case "text", "":
+		funcMap := template.FuncMap{
+			"join":  strings.Join,
+			"drift": color.New(color.FgRed).SprintFunc(),
+		}
+		temp, err := template.New("AddonStatusText").Funcs(funcMap).Parse(text)
+		if err != nil {
+			return err
+		}
+		if err := temp.Execute(os.Stdout, data); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown output format %q (text, json)", c.output)
+	}
+
+	return nil
+}
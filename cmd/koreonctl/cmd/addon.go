@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"syscall"
 
 	"kore-on/cmd/koreonctl/conf"
@@ -27,6 +28,10 @@ type strAddonCmd struct {
 	osRelease      string
 	osArchitecture string
 	osCurrentUser  string
+	command        string
+	app            string
+	revision       int
+	kubeconfig     string
 }
 
 func addonCmd() *cobra.Command {
@@ -44,7 +49,23 @@ func addonCmd() *cobra.Command {
 	}
 
 	// SubCommand add
-	cmd.AddCommand(addonInitCmd())
+	cmd.AddCommand(
+		addonInitCmd(),
+		addonListCmd(),
+		addonStatusCmd(),
+		addonOperationCmd("diff", "diff [app] [flags]", cobra.MaximumNArgs(1),
+			"Show diff of Applications against the live helm releases",
+			"This command renders the manifest of the application in addon.toml (all applications without [app])\n"+
+				"and shows the diff against the manifest of the live helm release."),
+		addonOperationCmd("upgrade", "upgrade <app> [flags]", cobra.ExactArgs(1),
+			"Upgrade an Application in kubernetes cluster",
+			"This command upgrades the helm release of the application\n"+
+				"to the chart_version and values in addon.toml."),
+		addonOperationCmd("rollback", "rollback <app> [flags]", cobra.ExactArgs(1),
+			"Rollback an Application in kubernetes cluster",
+			"This command rolls back the helm release of the application\n"+
+				"to the previous revision or to --revision."),
+	)
 
 	// SubCommand validation
 	utils.CheckCommand(cmd)
@@ -54,12 +75,45 @@ func addonCmd() *cobra.Command {
 	f.BoolVarP(&addon.dryRun, "dry-run", "d", false, "dryRun")
 	f.StringVarP(&addon.privateKey, "private-key", "p", "", "Specify ssh key path")
 	f.StringVarP(&addon.user, "user", "u", "", "login user")
+	f.StringVar(&addon.kubeconfig, "kubeconfig", "", "kubeconfig file path to deploy from this host instead of k8s-master-ip (certificates embedded)")
 	f.BoolVar(&addon.installHelm, "install-helm", false, "Helm installation options")
 	f.StringVar(&addon.helmBinaryFile, "helm-binary-file", "", "helm binary file")
 
 	return cmd
 }
 
+func addonOperationCmd(command string, use string, args cobra.PositionalArgs, short string, long string) *cobra.Command {
+	addon := &strAddonCmd{}
+
+	cmd := &cobra.Command{
+		Use:          use,
+		Short:        short,
+		Long:         long,
+		Args:         args,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				addon.app = args[0]
+			}
+			return addon.run()
+		},
+	}
+
+	addon.command = command
+
+	f := cmd.Flags()
+	f.BoolVar(&addon.verbose, "vvv", false, "verbose")
+	f.BoolVarP(&addon.dryRun, "dry-run", "d", false, "dryRun")
+	f.StringVarP(&addon.privateKey, "private-key", "p", "", "Specify ssh key path")
+	f.StringVarP(&addon.user, "user", "u", "", "login user")
+	f.StringVar(&addon.kubeconfig, "kubeconfig", "", "kubeconfig file path to deploy from this host instead of k8s-master-ip (certificates embedded)")
+	if command == "rollback" {
+		f.IntVar(&addon.revision, "revision", 0, "helm release revision to roll back to (default: previous revision)")
+	}
+
+	return cmd
+}
+
 func (c *strAddonCmd) run() error {
 	// 설치 directory tree check
 	workDir, err := checkDirTree()
@@ -121,6 +175,11 @@ func (c *strAddonCmd) addon(workDir string) error {
 
 	commandArgs = append(commandArgs, cmdDefault...)
 
+	// helm in the container reaches the api server of the kubeconfig through the host network
+	if c.kubeconfig != "" {
+		commandArgs = append(commandArgs, "--network", "host")
+	}
+
 	if !addonToml.Addon.ClosedNetwork {
 		commandArgs = append(commandArgs, "--pull")
 		commandArgs = append(commandArgs, "always")
@@ -144,12 +203,32 @@ func (c *strAddonCmd) addon(workDir string) error {
 		commandArgsVol = append(commandArgsVol, "--mount")
 		commandArgsVol = append(commandArgsVol, fmt.Sprintf("type=bind,source=%s,target=/home/%s,readonly", keyPath, key))
 	}
+	if c.kubeconfig != "" {
+		kubeconfigPath, _ := filepath.Abs(c.kubeconfig)
+		if !utils.FileExists(kubeconfigPath) {
+			logger.Fatal(fmt.Errorf("[ERROR]: kubeconfig %s is not found", kubeconfigPath))
+		}
+		if err := utils.CheckKubeconfig(kubeconfigPath); err != nil {
+			logger.Fatal(err)
+		}
+		commandArgsVol = append(commandArgsVol, "--mount")
+		commandArgsVol = append(commandArgsVol, fmt.Sprintf("type=bind,source=%s,target=/home/kubeconfig,readonly", kubeconfigPath))
+	}
 
 	commandArgsKoreonctl := []string{
 		koreOnImage,
 		"./" + koreonImageName,
 		"addon",
 	}
+	if c.command != "" {
+		commandArgsKoreonctl = append(commandArgsKoreonctl, c.command)
+	}
+	if c.app != "" {
+		commandArgsKoreonctl = append(commandArgsKoreonctl, c.app)
+	}
+	if c.revision > 0 {
+		commandArgsKoreonctl = append(commandArgsKoreonctl, "--revision", strconv.Itoa(c.revision))
+	}
 
 	//- koreonctl commands
 	if c.verbose {
@@ -160,7 +239,10 @@ func (c *strAddonCmd) addon(workDir string) error {
 		commandArgsKoreonctl = append(commandArgsKoreonctl, "--dry-run")
 	}
 
-	if c.privateKey != "" {
+	if c.kubeconfig != "" {
+		// local deployment does not need the ssh login
+		commandArgsKoreonctl = append(commandArgsKoreonctl, "--kubeconfig", "/home/kubeconfig")
+	} else if c.privateKey != "" {
 		commandArgsKoreonctl = append(commandArgsKoreonctl, "--private-key")
 		key := filepath.Base(c.privateKey)
 		commandArgsKoreonctl = append(commandArgsKoreonctl, "/home/"+key)
@@ -171,7 +253,7 @@ func (c *strAddonCmd) addon(workDir string) error {
 	if c.user != "" {
 		commandArgsKoreonctl = append(commandArgsKoreonctl, "--user")
 		commandArgsKoreonctl = append(commandArgsKoreonctl, c.user)
-	} else {
+	} else if c.kubeconfig == "" {
 		logger.Fatal(fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an ssh login user must be specified"))
 	}
 	//-end koreonctl commands
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"kore-on/cmd/koreonctl/conf"
+	"kore-on/pkg/airgap"
+	"kore-on/pkg/logger"
+	"kore-on/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+type strAirgapBundleCmd struct {
+	bundleDir  string
+	k8sVersion string
+	harbor     string
+	privateKey string
+	publicKey  string
+}
+
+func airgapBundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "airgap [flags]",
+		Short:        "Manage the air gap bundle manifest",
+		Long:         "This command creates, signs and verifies the manifest (SHA-256 sums and versions) of an air gap bundle.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	// SubCommand add
+	cmd.AddCommand(
+		airgapManifestCmd(),
+		airgapSignCmd(),
+		airgapKeygenCmd(),
+		airgapVerifyCmd(),
+	)
+
+	// SubCommand validation
+	utils.CheckCommand(cmd)
+
+	return cmd
+}
+
+func airgapManifestCmd() *cobra.Command {
+	manifest := &strAirgapBundleCmd{}
+
+	cmd := &cobra.Command{
+		Use:          "manifest [flags]",
+		Short:        "Create the bundle manifest",
+		Long:         "This command calculates the SHA-256 sums of every file in archive, bin and extends and writes archive/manifest.json.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return manifest.manifest()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&manifest.bundleDir, "dir", "", "bundle directory (default: koreonctl directory)")
+	f.StringVar(&manifest.k8sVersion, "k8s", "", "kubernetes version of the bundle (default: keep the existing manifest)")
+	f.StringVar(&manifest.harbor, "harbor", "", "harbor version of the bundle (default: latest)")
+	f.StringVar(&manifest.privateKey, "sign-key", "", "sign the manifest with this ed25519 private key")
+
+	return cmd
+}
+
+func airgapSignCmd() *cobra.Command {
+	sign := &strAirgapBundleCmd{}
+
+	cmd := &cobra.Command{
+		Use:          "sign [flags]",
+		Short:        "Sign the bundle manifest",
+		Long:         "This command signs archive/manifest.json with a local ed25519 private key.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sign.sign()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&sign.bundleDir, "dir", "", "bundle directory (default: koreonctl directory)")
+	f.StringVar(&sign.privateKey, "key", "", "ed25519 private key file")
+
+	return cmd
+}
+
+func airgapKeygenCmd() *cobra.Command {
+	keygen := &strAirgapBundleCmd{}
+
+	cmd := &cobra.Command{
+		Use:          "keygen [flags]",
+		Short:        "Create a key pair for bundle signing",
+		Long:         "This command creates an ed25519 key pair. Keep the private key on the internet side and copy the public key to the closed network.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return airgap.GenerateKey(keygen.privateKey, keygen.publicKey)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&keygen.privateKey, "private-key", "bundle-sign.key", "private key file to create")
+	f.StringVar(&keygen.publicKey, "public-key", "bundle-sign.pub", "public key file to create")
+
+	return cmd
+}
+
+func airgapVerifyCmd() *cobra.Command {
+	verify := &strAirgapBundleCmd{}
+
+	cmd := &cobra.Command{
+		Use:          "verify [flags]",
+		Short:        "Verify the bundle with its manifest",
+		Long:         "This command checks the size and SHA-256 sum of every bundle file and the manifest signature. A signed bundle needs the public key.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bundleDir, err := verify.dir()
+			if err != nil {
+				return err
+			}
+			return verifyBundle(bundleDir, verify.publicKey)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&verify.bundleDir, "dir", "", "bundle directory (default: koreonctl directory)")
+	f.StringVar(&verify.publicKey, "public-key", "", "ed25519 public key to check the manifest signature")
+
+	return cmd
+}
+
+func (c *strAirgapBundleCmd) dir() (string, error) {
+	if c.bundleDir != "" {
+		return filepath.Abs(c.bundleDir)
+	}
+	return checkDirTree()
+}
+
+func (c *strAirgapBundleCmd) manifest() error {
+	bundleDir, err := c.dir()
+	if err != nil {
+		return err
+	}
+
+	// keep versions, images and charts written by prepare-airgap
+	base, err := airgap.Load(bundleDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if c.k8sVersion != "" {
+		if base == nil {
+			base = &airgap.Manifest{}
+		}
+		if base.Versions, err = utils.ResolveSupportVersion(c.k8sVersion, c.harbor); err != nil {
+			return err
+		}
+	}
+
+	logger.Infof("Calculating SHA-256 sums of %s", bundleDir)
+	m, err := airgap.Build(bundleDir, base)
+	if err != nil {
+		return err
+	}
+	if m.KoreOnVersion == "" {
+		m.KoreOnVersion = conf.KoreOnVersion
+	}
+	if err := m.Save(bundleDir); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d files\n", filepath.Join(bundleDir, airgap.ManifestFile), len(m.Files))
+
+	if c.privateKey != "" {
+		return c.sign()
+	}
+	// the old signature does not match the new manifest
+	if airgap.IsSigned(bundleDir) {
+		if err := os.Remove(filepath.Join(bundleDir, airgap.SignatureFile)); err != nil {
+			return err
+		}
+		logger.Warn("Air gap bundle > previous signature removed. Sign the new manifest with 'koreonctl airgap sign'.")
+	}
+	return nil
+}
+
+func (c *strAirgapBundleCmd) sign() error {
+	bundleDir, err := c.dir()
+	if err != nil {
+		return err
+	}
+	if c.privateKey == "" {
+		return fmt.Errorf("[ERROR]: %s", "To sign the manifest a private key must be specified")
+	}
+
+	if err := airgap.Sign(bundleDir, c.privateKey); err != nil {
+		return err
+	}
+	fmt.Printf("%s: signed\n", filepath.Join(bundleDir, airgap.SignatureFile))
+	return nil
+}
+
+// verifyBundle - Check the bundle files and the manifest signature.
+// The bundle must have a manifest, a signed bundle needs the public key.
+func verifyBundle(bundleDir string, publicKey string) error {
+	m, problems, err := airgap.VerifyBundle(bundleDir, publicKey)
+	if err != nil {
+		return err
+	}
+
+	if publicKey != "" {
+		logger.Infof("Air gap bundle > manifest signature verified")
+	} else {
+		logger.Warn("Air gap bundle > manifest is not signed. Only the file checksums are checked.")
+	}
+
+	for _, p := range problems {
+		logger.Errorf("Air gap bundle > %s", p.String())
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("air gap bundle verification failed: %d problem(s)", len(problems))
+	}
+
+	logger.Infof("Air gap bundle > verified %d files (kubernetes %s)", len(m.Files), m.Versions.Kubernetes)
+	return nil
+}
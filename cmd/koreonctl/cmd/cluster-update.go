@@ -35,7 +35,7 @@ func clusterUpdateCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:          "update [flags]",
 		Short:        "Update kubernetes cluster(node scale in/out)",
-		Long:         "This command update the Kubernetes cluster nodes (node scale in/out)",
+		Long:         "This command update the Kubernetes cluster nodes (node scale in/out).\nWithout node changes, the calico network configuration of koreon.toml is applied.",
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return clusterUpdate.run()
@@ -20,6 +20,8 @@ import (
 type strCreateCmd struct {
 	dryRun         bool
 	verbose        bool
+	skipVerify     bool
+	publicKey      string
 	privateKey     string
 	user           string
 	osRelease      string
@@ -51,6 +53,8 @@ func createCmd() *cobra.Command {
 	f.BoolVarP(&create.dryRun, "dry-run", "d", false, "dryRun")
 	f.StringVarP(&create.privateKey, "private-key", "p", "", "Specify ssh key path")
 	f.StringVarP(&create.user, "user", "u", "", "login user")
+	f.StringVar(&create.publicKey, "bundle-public-key", "", "public key to check the air gap bundle signature (required for a signed bundle)")
+	f.BoolVar(&create.skipVerify, "skip-verify", false, "load the air gap bundle without checking its manifest and signature")
 
 	return cmd
 }
@@ -109,6 +113,9 @@ func (c *strCreateCmd) create(workDir string) error {
 	}
 
 	if koreonToml.KoreOn.ClosedNetwork {
+		if err := c.verifyBundle(workDir); err != nil {
+			logger.Fatal(err)
+		}
 		podmanLoad(workDir+"/archive/koreon/"+conf.KoreOnImageArchive, commandArgs)
 	}
 
@@ -202,6 +209,15 @@ func (c *strCreateCmd) create(workDir string) error {
 	return nil
 }
 
+// verifyBundle - Check the air gap bundle before it is loaded, only --skip-verify loads it unchecked
+func (c *strCreateCmd) verifyBundle(workDir string) error {
+	if c.skipVerify {
+		logger.Warn("Air gap bundle > verification skipped")
+		return nil
+	}
+	return verifyBundle(workDir, c.publicKey)
+}
+
 func podmanLoad(koreon_img string, commandArgs []string) error {
 	logger.Info("The loading of Korean images has begun in a closed network.")
 	commandPodman := []string{
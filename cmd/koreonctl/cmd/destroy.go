@@ -141,7 +141,7 @@ func destroyStorageCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:          "storage [flags]",
 		Short:        "Destroy storage",
-		Long:         "This command deletes the installed storage(NFS) and deletes related services and directories.",
+		Long:         "This command deletes the default StorageClass of the storage-type, the installed storage(NFS) and deletes related services and directories.",
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return destroyStorageCmd.run()
@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"kore-on/cmd/koreonctl/conf"
+	"kore-on/pkg/logger"
+	"kore-on/pkg/mirror"
+	"kore-on/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+type strImagesCmd struct {
+	k8sVersion   string
+	imageList    string
+	from         string
+	to           string
+	cacheDir     string
+	platform     string
+	allPlatforms bool
+	parallel     int
+	retries      int
+	src          mirror.RegistryOptions
+	dest         mirror.RegistryOptions
+}
+
+func imagesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "images [flags]",
+		Short:        "List and copy the container images of a kubernetes version",
+		Long:         "This command lists the container images of the supported versions and copies them between registries and OCI archives without podman or docker.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	// SubCommand add
+	cmd.AddCommand(
+		imagesListCmd(),
+		imagesCopyCmd(),
+		imagesUploadCmd(),
+	)
+
+	// SubCommand validation
+	utils.CheckCommand(cmd)
+
+	return cmd
+}
+
+func imagesListCmd() *cobra.Command {
+	list := &strImagesCmd{}
+
+	cmd := &cobra.Command{
+		Use:          "list [flags]",
+		Short:        "List the container images of a kubernetes version",
+		Long:         "This command prints the container images that prepare-airgap uploads for the given kubernetes version, one per line.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			images, err := list.images(nil)
+			if err != nil {
+				return err
+			}
+			for _, image := range images {
+				fmt.Println(image)
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&list.k8sVersion, "k8s", "", "kubernetes version or constraint (default: latest)")
+
+	return cmd
+}
+
+func imagesCopyCmd() *cobra.Command {
+	imageCopy := &strImagesCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "copy [flags] [image...]",
+		Short: "Copy container images to a registry or an OCI archive",
+		Long: `This command copies container images registry to registry, OCI archive to registry or registry to OCI archive.
+Without image arguments the images of the kubernetes version (see 'koreonctl images list') are copied.
+
+Source and destination:
+  (empty)                  upstream registries of the image names (source only)
+  harbor.local[/prefix]    registry; images keep their full name (harbor.local/docker.io/calico/node:v3.25.1)
+  http://localhost:5000    registry without TLS
+  oci:/path/images[.tar]   OCI layout directory or tar archive
+
+An interrupted copy can be run again: blobs already in the destination are skipped and
+partially downloaded blobs are resumed from the cache directory.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return imageCopy.copy(args)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&imageCopy.from, "from", "", "source registry or oci:<path> (default: upstream registries)")
+	f.StringVar(&imageCopy.to, "to", "", "destination registry or oci:<path>")
+	f.StringVar(&imageCopy.k8sVersion, "k8s", "", "kubernetes version or constraint of the image list (default: latest)")
+	f.StringVar(&imageCopy.imageList, "image-list", "", "file with the images to copy, one per line")
+	f.IntVar(&imageCopy.parallel, "parallel", 4, "number of images copied at the same time")
+	f.IntVar(&imageCopy.retries, "retries", 3, "retries of an interrupted blob download")
+	f.StringVar(&imageCopy.platform, "platform", "linux/amd64", "platform copied from multi-arch images")
+	f.BoolVar(&imageCopy.allPlatforms, "all-platforms", false, "copy every platform of multi-arch images")
+	f.StringVar(&imageCopy.cacheDir, "cache-dir", "", "directory for partially copied blobs (default: $TMPDIR/koreon-image-cache)")
+	f.StringVar(&imageCopy.src.Username, "src-username", "", "source registry user")
+	f.StringVar(&imageCopy.src.Password, "src-password", "", "source registry password")
+	f.StringVar(&imageCopy.src.CAFile, "src-ca-file", "", "source registry CA certificate")
+	f.BoolVar(&imageCopy.src.Insecure, "src-insecure", false, "skip TLS verification of the source registry")
+	f.StringVar(&imageCopy.dest.Username, "dest-username", "", "destination registry user")
+	f.StringVar(&imageCopy.dest.Password, "dest-password", "", "destination registry password")
+	f.StringVar(&imageCopy.dest.CAFile, "dest-ca-file", "", "destination registry CA certificate (e.g. harbor ca.crt)")
+	f.BoolVar(&imageCopy.dest.Insecure, "dest-insecure", false, "skip TLS verification of the destination registry")
+	f.SortFlags = false
+
+	return cmd
+}
+
+func imagesUploadCmd() *cobra.Command {
+	upload := &strImagesCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "upload [flags] [image...]",
+		Short: "Upload container images to the external registry",
+		Long: `This command uploads container images to [private-registry.external] of koreon.toml.
+The url, path-prefix, credential and ca-file of the external registry are used as the destination.
+Without image arguments the images of the kubernetes version of koreon.toml, or every image of the source archive, are uploaded.
+In the closed network use an OCI archive made by 'koreonctl images copy --to oci:<path>' as the source.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return upload.upload(args)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&upload.from, "from", "", "source registry or oci:<path> (default: upstream registries)")
+	f.StringVar(&upload.k8sVersion, "k8s", "", "kubernetes version or constraint of the image list (default: kubernetes version of koreon.toml)")
+	f.StringVar(&upload.imageList, "image-list", "", "file with the images to upload, one per line")
+	f.IntVar(&upload.parallel, "parallel", 4, "number of images copied at the same time")
+	f.IntVar(&upload.retries, "retries", 3, "retries of an interrupted blob download")
+	f.StringVar(&upload.platform, "platform", "linux/amd64", "platform copied from multi-arch images")
+	f.BoolVar(&upload.allPlatforms, "all-platforms", false, "copy every platform of multi-arch images")
+	f.StringVar(&upload.cacheDir, "cache-dir", "", "directory for partially copied blobs (default: $TMPDIR/koreon-image-cache)")
+	f.StringVar(&upload.src.Username, "src-username", "", "source registry user")
+	f.StringVar(&upload.src.Password, "src-password", "", "source registry password")
+	f.StringVar(&upload.src.CAFile, "src-ca-file", "", "source registry CA certificate")
+	f.BoolVar(&upload.src.Insecure, "src-insecure", false, "skip TLS verification of the source registry")
+	f.SortFlags = false
+
+	return cmd
+}
+
+// images - Images given as arguments, in the image list file, in the source archive or of the kubernetes version
+func (c *strImagesCmd) images(args []string) ([]string, error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+	if c.imageList != "" {
+		return mirror.ReadImageList(c.imageList)
+	}
+
+	resolved, err := utils.ResolveSupportVersion(c.k8sVersion, "")
+	if err != nil {
+		return nil, err
+	}
+	images := mirror.SupportImages(resolved.Kubernetes, resolved.ImageVersion)
+	return append(images, mirror.ChartImages(resolved.HelmChartVersion)...), nil
+}
+
+func (c *strImagesCmd) copy(args []string) error {
+	if c.to == "" {
+		return fmt.Errorf("[ERROR]: %s", "To copy images a destination (--to) must be specified")
+	}
+
+	src, err := mirror.ParseEndpoint(c.from, c.src)
+	if err != nil {
+		return err
+	}
+	dest, err := mirror.ParseEndpoint(c.to, c.dest)
+	if err != nil {
+		return err
+	}
+	if dest.Host == "" && dest.Layout == nil {
+		return fmt.Errorf("[ERROR]: %s", "The destination must be a registry or an OCI archive")
+	}
+
+	return c.transfer(src, dest, args)
+}
+
+func (c *strImagesCmd) upload(args []string) error {
+	workDir, err := checkDirTree()
+	if err != nil {
+		return err
+	}
+
+	koreonToml, err := utils.GetKoreonTomlConfig(workDir + "/config/" + conf.KoreOnConfigFile)
+	if err != nil {
+		return err
+	}
+	if koreonToml.PrivateRegistry.External.URL == "" {
+		return fmt.Errorf("[ERROR]: %s", "private-registry.external > url is not set. Images of the installed registry are uploaded by 'koreonctl prepare-airgap' and 'koreonctl prepare-airgap import'")
+	}
+
+	src, err := mirror.ParseEndpoint(c.from, c.src)
+	if err != nil {
+		return err
+	}
+	if c.k8sVersion == "" && src.Layout == nil {
+		c.k8sVersion = koreonToml.Kubernetes.Version
+	}
+	dest, err := utils.ExternalRegistry(koreonToml, workDir+"/config")
+	if err != nil {
+		return fmt.Errorf("[ERROR]: private-registry.external > %s", err.Error())
+	}
+
+	return c.transfer(src, dest, args)
+}
+
+// transfer - Copy the images from src to dest and close both endpoints
+func (c *strImagesCmd) transfer(src *mirror.Endpoint, dest *mirror.Endpoint, args []string) error {
+	var err error
+	images := args
+	if len(images) == 0 && c.imageList == "" && src.Layout != nil && c.k8sVersion == "" {
+		// every image of the source archive
+		images = src.Layout.Images()
+	}
+	if len(images) == 0 {
+		if images, err = c.images(nil); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	progress := &imagesProgress{total: len(images)}
+	copier := &mirror.Copier{
+		Source:       src,
+		Destination:  dest,
+		CacheDir:     c.cacheDir,
+		Parallel:     c.parallel,
+		Retries:      c.retries,
+		Platform:     c.platform,
+		AllPlatforms: c.allPlatforms,
+		Progress:     progress.print,
+	}
+
+	logger.Infof("Copy %d images from %s to %s", len(images), src.String(), dest.String())
+	summary, err := copier.Copy(ctx, images)
+
+	// the archive is written even after a failure so that the copied images are kept
+	for _, e := range []*mirror.Endpoint{src, dest} {
+		if cerr := e.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	fmt.Printf("Images: %d copied, %d failed / Blobs: %d copied, %d already present / %s transferred\n",
+		summary.Images-summary.Failed, summary.Failed, summary.Blobs, summary.Skipped, byteSize(summary.Bytes))
+	if err != nil && ctx.Err() != nil {
+		logger.Warn("Copy interrupted. Run the same command again to resume.")
+	}
+	return err
+}
+
+// imagesProgress - Prints copy events of the parallel workers one line at a time
+type imagesProgress struct {
+	mu       sync.Mutex
+	total    int
+	finished int
+}
+
+func (p *imagesProgress) print(e mirror.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch e.Kind {
+	case mirror.EventDone:
+		p.finished++
+		fmt.Printf("[%d/%d] %s copied\n", p.finished, p.total, e.Image)
+	case mirror.EventFailed:
+		p.finished++
+		fmt.Printf("[%d/%d] %s failed: %s\n", p.finished, p.total, e.Image, e.Err.Error())
+	case mirror.EventBlob:
+		fmt.Printf("  %s %s %s\n", e.Image, shortDigest(e.Digest), byteSize(e.Size))
+	case mirror.EventExists:
+		fmt.Printf("  %s %s already present\n", e.Image, shortDigest(e.Digest))
+	case mirror.EventProgress:
+		if e.Size > 0 {
+			fmt.Printf("  %s %s %s / %s (%d%%)\n", e.Image, shortDigest(e.Digest), byteSize(e.Transferred), byteSize(e.Size), e.Transferred*100/e.Size)
+		}
+	}
+}
+
+func shortDigest(digest string) string {
+	if len(digest) > 19 {
+		return digest[:19]
+	}
+	return digest
+}
+
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"kore-on/cmd/koreonctl/conf"
+	"kore-on/pkg/logger"
+	"kore-on/pkg/oidc"
+	"kore-on/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+type strOidcCmd struct {
+	standIn bool
+	token   string
+	timeout time.Duration
+}
+
+func oidcCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "oidc [flags]",
+		Short:        "Check the OIDC authentication of the apiserver",
+		Long:         "This command checks [kubernetes.oidc] of koreon.toml, the OpenID Connect issuer trusted by the apiserver.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	// SubCommand add
+	cmd.AddCommand(
+		oidcCheckCmd(),
+	)
+
+	// SubCommand validation
+	utils.CheckCommand(cmd)
+
+	return cmd
+}
+
+func oidcCheckCmd() *cobra.Command {
+	check := &strOidcCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "check [flags]",
+		Short: "Check the OIDC issuer and an id token",
+		Long: `This command checks [kubernetes.oidc] of koreon.toml the way the apiserver authenticates the id tokens.
+The discovery document and the signing keys of the issuer are fetched with the ca-file, and the id token
+of --token (e.g. 'kubectl oidc-login get-token ...' output or a raw JWT) is verified and mapped to the
+username and groups of the cluster.
+
+With --stand-in a local issuer on 127.0.0.1 answers for the issuer-url with a generated CA and signs a
+sample id token, so the configuration is checked without network.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return check.check()
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&check.standIn, "stand-in", false, "check against a local stand-in of the issuer (no network)")
+	f.StringVar(&check.token, "token", "", "file with an id token of the issuer")
+	f.DurationVar(&check.timeout, "timeout", 30*time.Second, "timeout of the issuer requests")
+
+	return cmd
+}
+
+func (c *strOidcCmd) check() error {
+	workDir, err := checkDirTree()
+	if err != nil {
+		return err
+	}
+
+	koreonToml, err := utils.GetKoreonTomlConfig(workDir + "/config/" + conf.KoreOnConfigFile)
+	if err != nil {
+		return err
+	}
+	if !oidc.Enabled(&koreonToml) {
+		return fmt.Errorf("[ERROR]: %s", "kubernetes.oidc > issuer-url is not set")
+	}
+	errs := oidc.Validate(&koreonToml, workDir+"/config")
+	for _, err := range errs {
+		logger.Errorf("kubernetes.oidc > %s", err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("[ERROR]: %d errors in kubernetes.oidc", len(errs))
+	}
+	cfg := koreonToml.Kubernetes.Oidc
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if c.standIn {
+		if c.token != "" {
+			return fmt.Errorf("[ERROR]: %s", "--token is not used with --stand-in, the stand-in signs a sample token")
+		}
+		user, err := oidc.CheckStandIn(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("[ERROR]: stand-in issuer check failed: %s", err.Error())
+		}
+		fmt.Printf("Issuer:   %s (stand-in)\n", cfg.IssuerURL)
+		c.printUser(user)
+		return nil
+	}
+
+	caFile := ""
+	if cfg.CAFile != "" {
+		caFile = filepath.Join(workDir, "config", filepath.Base(cfg.CAFile))
+	}
+	client, err := oidc.HTTPClient(caFile)
+	if err != nil {
+		return err
+	}
+	provider, err := oidc.Discover(ctx, client, cfg.IssuerURL)
+	if err != nil {
+		return fmt.Errorf("[ERROR]: issuer check failed: %s", err.Error())
+	}
+	fmt.Printf("Issuer:   %s\n", provider.Issuer)
+	fmt.Printf("Keys:     %d %s signing keys (%s)\n", provider.Keys(), oidc.SigningAlg, provider.JwksURI)
+	if c.token == "" {
+		fmt.Printf("Kubeconfig of the developers: %s/config/%s (after create or update)\n", workDir, conf.KoreOnOidcKubeConfig)
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(c.token)
+	if err != nil {
+		return err
+	}
+	claims, err := provider.Verify(idToken(string(b)), cfg.ClientID, time.Now())
+	if err != nil {
+		return fmt.Errorf("[ERROR]: id token is not accepted: %s", err.Error())
+	}
+	user, err := oidc.MapUser(cfg, claims)
+	if err != nil {
+		return fmt.Errorf("[ERROR]: id token is not accepted: %s", err.Error())
+	}
+	c.printUser(user)
+	return nil
+}
+
+func (c *strOidcCmd) printUser(user *oidc.User) {
+	fmt.Printf("Username: %s\n", user.Name)
+	fmt.Printf("Groups:   %s\n", strings.Join(user.Groups, ", "))
+	fmt.Println("Bind RBAC roles to the username or the groups to give access to the developers.")
+}
+
+// idToken - Raw JWT or the token of an ExecCredential ('kubectl oidc-login get-token' output)
+func idToken(s string) string {
+	credential := struct {
+		Status struct {
+			Token string `json:"token"`
+		} `json:"status"`
+	}{}
+	if err := json.Unmarshal([]byte(s), &credential); err == nil && credential.Status.Token != "" {
+		return credential.Status.Token
+	}
+	return strings.TrimSpace(s)
+}
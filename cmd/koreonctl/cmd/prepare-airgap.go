@@ -24,6 +24,9 @@ type strAirGapCmd struct {
 	imagesDir      string
 	user           string
 	command        string
+	since          string
+	publicKey      string
+	skipVerify     bool
 	osRelease      string
 	osArchitecture string
 	osCurrentUser  string
@@ -46,6 +49,7 @@ func airGapCmd() *cobra.Command {
 	cmd.AddCommand(
 		downLoadArchiveCmd(),
 		imageUploadCmd(),
+		airGapImportCmd(),
 	)
 
 	// SubCommand validation
@@ -55,11 +59,37 @@ func airGapCmd() *cobra.Command {
 	f.StringVarP(&prepareAirgap.privateKey, "private-key", "p", "", "Specify ssh key path")
 	f.StringVarP(&prepareAirgap.user, "user", "u", "", "login user")
 	f.BoolVarP(&prepareAirgap.dryRun, "dry-run", "d", false, "dryRun")
+	f.StringVar(&prepareAirgap.since, "since", "", "manifest of the previous bundle. Only new packages, images and charts are bundled")
 	f.SortFlags = false
 
 	return cmd
 }
 
+func airGapImportCmd() *cobra.Command {
+	airgapImport := &strAirGapCmd{}
+
+	cmd := &cobra.Command{
+		Use:          "import [flags]",
+		Short:        "Import a delta bundle into the local repository and private registry",
+		Long:         "This command merges the packages, images and helm charts of a delta bundle (prepare-airgap --since) into the existing local repository and Harbor of the closed network.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return airgapImport.run()
+		},
+	}
+
+	airgapImport.command = "import"
+
+	f := cmd.Flags()
+	f.BoolVarP(&airgapImport.dryRun, "dry-run", "d", false, "dryRun")
+	f.StringVarP(&airgapImport.privateKey, "private-key", "p", "", "Specify ssh key path")
+	f.StringVarP(&airgapImport.user, "user", "u", "", "login user")
+	f.StringVar(&airgapImport.publicKey, "bundle-public-key", "", "public key to check the air gap bundle signature (required for a signed bundle)")
+	f.BoolVar(&airgapImport.skipVerify, "skip-verify", false, "import the air gap bundle without checking its manifest and signature")
+
+	return cmd
+}
+
 func downLoadArchiveCmd() *cobra.Command {
 	downLoadArchive := &strAirGapCmd{}
 
@@ -167,6 +197,15 @@ func (c *strAirGapCmd) airgap(workDir string) error {
 		commandArgs = append(commandArgs, "sudo")
 	}
 
+	if c.command == "import" {
+		if c.skipVerify {
+			logger.Warn("Air gap bundle > verification skipped")
+		} else if err := verifyBundle(workDir, c.publicKey); err != nil {
+			logger.Fatal(err)
+		}
+		podmanLoad(workDir+"/archive/koreon/"+conf.KoreOnImageArchive, commandArgs)
+	}
+
 	commandArgs = append(commandArgs, cmdDefault...)
 
 	if !koreonToml.KoreOn.ClosedNetwork {
@@ -193,6 +232,19 @@ func (c *strAirGapCmd) airgap(workDir string) error {
 		commandArgsVol = append(commandArgsVol, fmt.Sprintf("type=bind,source=%s,target=/home/%s,readonly", keyPath, key))
 	}
 
+	if c.since != "" {
+		since := filepath.Base(c.since)
+		sincePath, err := filepath.Abs(c.since)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if _, err := os.Stat(sincePath); err != nil {
+			logger.Fatal(err)
+		}
+		commandArgsVol = append(commandArgsVol, "--mount")
+		commandArgsVol = append(commandArgsVol, fmt.Sprintf("type=bind,source=%s,target=/home/%s,readonly", sincePath, since))
+	}
+
 	commandArgsKoreonctl := []string{
 		koreOnImage,
 		"./" + koreonImageName,
@@ -208,6 +260,15 @@ func (c *strAirGapCmd) airgap(workDir string) error {
 		commandArgsKoreonctl = append(commandArgsKoreonctl, "download-archive")
 	}
 
+	if c.command == "import" {
+		commandArgsKoreonctl = append(commandArgsKoreonctl, "import")
+	}
+
+	if c.since != "" {
+		commandArgsKoreonctl = append(commandArgsKoreonctl, "--since")
+		commandArgsKoreonctl = append(commandArgsKoreonctl, "/home/"+filepath.Base(c.since))
+	}
+
 	if c.verbose {
 		commandArgsKoreonctl = append(commandArgsKoreonctl, "--verbose")
 	}
@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"fmt"
+	"kore-on/pkg/logger"
+	"kore-on/pkg/utils"
+	"log"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"kore-on/cmd/koreonctl/conf"
+
+	"github.com/elastic/go-sysinfo"
+	"github.com/spf13/cobra"
+)
+
+type strRegistryManagerCmd struct {
+	dryRun         bool
+	verbose        bool
+	command        string
+	username       string
+	caFile         string
+	insecure       bool
+	backupFile     string
+	privateKey     string
+	user           string
+	osRelease      string
+	osArchitecture string
+	osCurrentUser  string
+}
+
+func registryManagerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "registry-manager [flags]",
+		Short: "Manage projects, robot accounts and retention of the private registry",
+		Long: "This command manages the installed or external private registry(Harbor) through the Harbor API.\n" +
+			"* The desired state is declared in [private-registry.projects] of koreon.toml.\n" +
+			"* backup, restore and upgrade are available for the registry installed by kore-on.\n" +
+			"* The harbor admin password is read from " + conf.HarborPasswordEnv + " or prompted.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	// SubCommand add
+	cmd.AddCommand(
+		registryManagerHarborCmd("project", "Create harbor projects and update their settings"),
+		registryManagerHarborCmd("robot", "Create and update project robot accounts"),
+		registryManagerHarborCmd("retention", "Apply tag retention policies of projects"),
+		registryManagerHarborCmd("proxy-cache", "Create proxy-cache projects and their upstream registry endpoints"),
+		registryManagerOperationCmd("backup", "Backup the harbor database and configuration into registry/backup", ""),
+		registryManagerOperationCmd("restore", "Restore the harbor database and configuration from registry/backup", ""),
+		registryManagerOperationCmd("upgrade", "Upgrade harbor to the next supported version with a pre-upgrade backup",
+			"* In the closed network copy harbor-offline-installer-<version>.tgz of the next version to the registry directory."),
+	)
+
+	// SubCommand validation
+	utils.CheckCommand(cmd)
+
+	return cmd
+}
+
+func registryManagerHarborCmd(command string, short string) *cobra.Command {
+	registryManager := &strRegistryManagerCmd{}
+
+	cmd := &cobra.Command{
+		Use:          command + " [flags]",
+		Short:        short,
+		Long:         short + " as declared in [private-registry.projects] of koreon.toml.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return registryManager.run()
+		},
+	}
+
+	registryManager.command = command
+
+	f := cmd.Flags()
+	f.BoolVarP(&registryManager.dryRun, "dry-run", "d", false, "show the changes without applying them")
+	f.StringVar(&registryManager.username, "username", "", "harbor admin user (default: admin)")
+	f.StringVar(&registryManager.caFile, "ca-file", "", "harbor CA certificate (default: ca-file of the external registry or "+conf.KoreOnRegistryCAFile+" of the installation)")
+	f.BoolVar(&registryManager.insecure, "insecure-skip-tls-verify", false, "skip TLS verification of the harbor certificate")
+
+	return cmd
+}
+
+func registryManagerOperationCmd(command string, short string, long string) *cobra.Command {
+	registryManager := &strRegistryManagerCmd{}
+
+	cmd := &cobra.Command{
+		Use:          command + " [flags]",
+		Short:        short,
+		Long:         strings.TrimSpace(short + ".\n* Only the registry installed by kore-on ([private-registry] install = true) is supported.\n" + long),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return registryManager.run()
+		},
+	}
+
+	registryManager.command = command
+
+	f := cmd.Flags()
+	f.BoolVarP(&registryManager.verbose, "verbose", "v", false, "verbose")
+	f.BoolVarP(&registryManager.dryRun, "dry-run", "d", false, "dryRun")
+	f.StringVarP(&registryManager.privateKey, "private-key", "p", "", "Specify ssh key path")
+	f.StringVarP(&registryManager.user, "user", "u", "", "login user")
+	f.StringVar(&registryManager.username, "username", "", "harbor admin user (default: admin)")
+	f.StringVar(&registryManager.caFile, "ca-file", "", "harbor CA certificate (default: "+conf.KoreOnRegistryCAFile+" of the installation)")
+	f.BoolVar(&registryManager.insecure, "insecure-skip-tls-verify", false, "skip TLS verification of the harbor certificate")
+	if command == "restore" {
+		f.StringVar(&registryManager.backupFile, "backup-file", "", "backup file name in registry/backup (default: latest backup)")
+	}
+
+	return cmd
+}
+
+func (c *strRegistryManagerCmd) run() error {
+	// 설치 directory tree check
+	workDir, err := checkDirTree()
+	if err != nil {
+		logger.Error(err)
+		os.Exit(1)
+	}
+
+	// Check installed Podman
+	if err := installPodman(workDir); err != nil {
+		logger.Fatal(err)
+	}
+
+	// system info
+	host, err := sysinfo.Host()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	currentUser, err := user.Current()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	c.osCurrentUser = currentUser.Username
+	c.osArchitecture = host.Info().Architecture
+	c.osRelease = host.Info().OS.Platform
+
+	if err := c.registryManager(workDir); err != nil {
+		return err
+	}
+	return nil
+}
+
+// isHarborCommand - project, robot, retention and proxy-cache only call the Harbor API
+func (c *strRegistryManagerCmd) isHarborCommand() bool {
+	return c.command != "backup" && c.command != "restore" && c.command != "upgrade"
+}
+
+func (c *strRegistryManagerCmd) registryManager(workDir string) error {
+
+	koreonImageName := conf.KoreOnImageName
+	koreOnImage := conf.KoreOnImage
+	koreOnConfigFileName := conf.KoreOnConfigFile
+
+	koreonToml, err := utils.GetKoreonTomlConfig(workDir + "/config/" + koreOnConfigFileName)
+	if err != nil {
+		logger.Fatal(err)
+		os.Exit(1)
+	}
+
+	commandArgs := []string{}
+
+	if c.osRelease == "ubuntu" && c.osCurrentUser != "root" {
+		commandArgs = append(commandArgs, "sudo")
+	}
+
+	if koreonToml.KoreOn.ClosedNetwork {
+		podmanLoad(workDir+"/archive/koreon/"+conf.KoreOnImageArchive, commandArgs)
+	}
+
+	if len(commandArgs) > 0 {
+		// sudo resets the environment
+		commandArgs = append(commandArgs, "--preserve-env="+conf.HarborPasswordEnv)
+	}
+
+	cmdDefault := []string{
+		"podman",
+		"run",
+		"--rm",
+		"--privileged",
+		"-it",
+	}
+
+	commandArgs = append(commandArgs, cmdDefault...)
+
+	if !koreonToml.KoreOn.ClosedNetwork {
+		commandArgs = append(commandArgs, "--pull")
+		commandArgs = append(commandArgs, "always")
+	}
+
+	// backups and the offline installer of an upgrade, outside of the air gap bundle directories
+	if err := os.MkdirAll(workDir+"/registry/backup", 0755); err != nil {
+		return err
+	}
+
+	commandArgsVol := []string{
+		"-v",
+		fmt.Sprintf("%s:%s", workDir+"/archive", "/"+conf.KoreOnArchiveFileDir),
+		"-v",
+		fmt.Sprintf("%s:%s", workDir+"/config", "/"+conf.KoreOnConfigDir),
+		"-v",
+		fmt.Sprintf("%s:%s", workDir+"/extends", "/"+conf.KoreOnExtendsFileDir),
+		"-v",
+		fmt.Sprintf("%s:%s", workDir+"/logs", "/"+conf.KoreOnLogsDir),
+		"-v",
+		fmt.Sprintf("%s:%s", workDir+"/registry", "/"+conf.KoreOnRegistryDir),
+	}
+
+	// harbor admin password: environment or prompt, passed with --env to keep it off the command line.
+	// The external registry uses the credential of [private-registry.external] unless --username is given.
+	env := os.Environ()
+	external := koreonToml.PrivateRegistry.External
+	useExternal := external.URL != "" && external.Password != "" && c.username == ""
+	if os.Getenv(conf.HarborPasswordEnv) == "" && !(useExternal && c.isHarborCommand()) {
+		env = append(env, conf.HarborPasswordEnv+"="+utils.SensitivePrompt("harbor admin password:"))
+	}
+	commandArgsVol = append(commandArgsVol, "--env", conf.HarborPasswordEnv)
+
+	// podman commands
+	if c.caFile != "" {
+		caFile := filepath.Base(c.caFile)
+		caFilePath, _ := filepath.Abs(c.caFile)
+		if _, err := os.Stat(caFilePath); err != nil {
+			logger.Fatal(err)
+		}
+		commandArgsVol = append(commandArgsVol, "--mount")
+		commandArgsVol = append(commandArgsVol, fmt.Sprintf("type=bind,source=%s,target=/home/%s,readonly", caFilePath, caFile))
+	}
+
+	if c.privateKey != "" {
+		key := filepath.Base(c.privateKey)
+		keyPath, _ := filepath.Abs(c.privateKey)
+		commandArgsVol = append(commandArgsVol, "--mount")
+		commandArgsVol = append(commandArgsVol, fmt.Sprintf("type=bind,source=%s,target=/home/%s,readonly", keyPath, key))
+	}
+
+	commandArgsKoreonctl := []string{
+		koreOnImage,
+		"./" + koreonImageName,
+		"registry-manager",
+		c.command,
+	}
+
+	//- koreonctl commands
+	if c.dryRun {
+		commandArgsKoreonctl = append(commandArgsKoreonctl, "--dry-run")
+	}
+
+	if c.username != "" {
+		commandArgsKoreonctl = append(commandArgsKoreonctl, "--username")
+		commandArgsKoreonctl = append(commandArgsKoreonctl, c.username)
+	}
+
+	if c.caFile != "" {
+		commandArgsKoreonctl = append(commandArgsKoreonctl, "--ca-file")
+		commandArgsKoreonctl = append(commandArgsKoreonctl, "/home/"+filepath.Base(c.caFile))
+	}
+
+	if c.insecure {
+		commandArgsKoreonctl = append(commandArgsKoreonctl, "--insecure-skip-tls-verify")
+	}
+
+	// backup, restore and upgrade run ansible-playbook on the registry node
+	if !c.isHarborCommand() {
+		if c.verbose {
+			commandArgsKoreonctl = append(commandArgsKoreonctl, "--verbose")
+		}
+
+		if c.backupFile != "" {
+			commandArgsKoreonctl = append(commandArgsKoreonctl, "--backup-file")
+			commandArgsKoreonctl = append(commandArgsKoreonctl, filepath.Base(c.backupFile))
+		}
+
+		if c.privateKey != "" {
+			commandArgsKoreonctl = append(commandArgsKoreonctl, "--private-key")
+			commandArgsKoreonctl = append(commandArgsKoreonctl, "/home/"+filepath.Base(c.privateKey))
+		} else {
+			logger.Fatal(fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an privateKey must be specified"))
+		}
+
+		if c.user != "" {
+			commandArgsKoreonctl = append(commandArgsKoreonctl, "--user")
+			commandArgsKoreonctl = append(commandArgsKoreonctl, c.user)
+		} else {
+			logger.Fatal(fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an ssh login user must be specified"))
+		}
+	}
+	//-end koreonctl commands
+
+	commandArgs = append(commandArgs, commandArgsVol...)
+	commandArgs = append(commandArgs, commandArgsKoreonctl...)
+
+	binary := ""
+	if c.osRelease == "ubuntu" && c.osCurrentUser != "root" {
+		binary, err = exec.LookPath("sudo")
+		if err != nil {
+			logger.Fatal(err)
+		}
+	} else {
+		binary, err = exec.LookPath("podman")
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	err = syscall.Exec(binary, commandArgs, env)
+	if err != nil {
+		log.Printf("Command finished with error: %v", err)
+	}
+
+	return nil
+}
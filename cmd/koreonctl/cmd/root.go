@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"kore-on/cmd/koreonctl/conf"
+	"kore-on/pkg/config"
 	"kore-on/pkg/logger"
 	"kore-on/pkg/utils"
 
@@ -64,6 +65,11 @@ func init() {
 		airGapCmd(),
 		bastionCmd(),
 		addonCmd(),
+		versionsCmd(),
+		airgapBundleCmd(),
+		imagesCmd(),
+		registryManagerCmd(),
+		oidcCmd(),
 	)
 
 	// SubCommand validation
@@ -77,10 +83,10 @@ func initConfig() {
 		logger.Fatalf("Could not instantiate log %ss", err.Error())
 	}
 
-	// // load config file
-	// err = config.Load()
-	// if err != nil {
-	// 	logger.Fatalf("Could not load configuration: %s", err.Error())
-	// 	os.Exit(1)
-	// }
+	// load support version catalog (conf/config.yaml is embedded in the binary)
+	err = config.LoadEmbedded()
+	if err != nil {
+		logger.Fatalf("Could not load configuration: %s", err.Error())
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/template"
+
+	"kore-on/cmd/koreonctl/conf"
+	"kore-on/cmd/koreonctl/conf/templates"
+	"kore-on/pkg/model"
+	"kore-on/pkg/utils"
+	semver "kore-on/pkg/version"
+
+	"github.com/spf13/cobra"
+)
+
+type strVersionsCmd struct {
+	output        string
+	k8sVersion    string
+	harborVersion string
+}
+
+func versionsCmd() *cobra.Command {
+	versions := &strVersionsCmd{}
+
+	cmd := &cobra.Command{
+		Use:          "versions [flags]",
+		Short:        "Show supported component versions",
+		Long:         "This command lists every component version supported by this release of koreonctl.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return versions.run()
+		},
+	}
+
+	// SubCommand add
+	cmd.AddCommand(
+		versionsResolveCmd(),
+	)
+
+	// SubCommand validation
+	utils.CheckCommand(cmd)
+
+	f := cmd.Flags()
+	f.StringVarP(&versions.output, "output", "o", "text", "output format (text, json)")
+
+	return cmd
+}
+
+func versionsResolveCmd() *cobra.Command {
+	resolve := &strVersionsCmd{}
+
+	cmd := &cobra.Command{
+		Use:          "resolve [flags]",
+		Short:        "Show the component versions chosen for a kubernetes version",
+		Long:         "This command shows the containerd, calico, coredns, etcd, crictl, helm chart and harbor versions that would be installed with the given kubernetes version.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return resolve.resolve()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&resolve.k8sVersion, "k8s", "", "kubernetes version or constraint (e.g. v1.24.10, ~1.24, \">=1.23 <1.26\", default: latest)")
+	f.StringVar(&resolve.harborVersion, "harbor", "", "harbor version or constraint (default: latest)")
+	f.StringVarP(&resolve.output, "output", "o", "text", "output format (text, json)")
+
+	return cmd
+}
+
+func (c *strVersionsCmd) run() error {
+	components := []model.SupportComponent{}
+	for _, v := range conf.SupportVersionConf {
+		supportVersion := utils.ListSupportVersion(v[1])
+
+		minors := []string{}
+		for minor := range supportVersion {
+			minors = append(minors, minor)
+		}
+		minors, err := semver.Sort(minors)
+		if err != nil {
+			return fmt.Errorf("%s: %w", v[1], err)
+		}
+
+		latest, err := utils.ResolveVersion("latest", v[1])
+		if err != nil {
+			return err
+		}
+
+		component := model.SupportComponent{
+			Name:    v[0],
+			ConfKey: v[1],
+			Latest:  latest,
+		}
+		for _, minor := range minors {
+			component.Series = append(component.Series, model.SupportSeries{
+				Minor:    minor,
+				Versions: supportVersion[minor],
+			})
+		}
+		components = append(components, component)
+	}
+
+	return c.print(components, templates.ShowSupportVersionText)
+}
+
+func (c *strVersionsCmd) resolve() error {
+	resolved, err := utils.ResolveSupportVersion(c.k8sVersion, c.harborVersion)
+	if err != nil {
+		return err
+	}
+
+	return c.print(resolved, templates.ResolveSupportVersionText)
+}
+
+func (c *strVersionsCmd) print(data interface{}, text string) error {
+	switch c.output {
+	case "json":
+		b, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case "text", "":
+		temp, err := template.New("SupportVersionText").Parse(text)
+		if err != nil {
+			return err
+		}
+		if err := temp.Execute(os.Stdout, data); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown output format %q (text, json)", c.output)
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ var (
 	KoreOnImage            = KoreOnRegistry + "/" + KoreOnImageName + ":" + KoreOnVersion
 	KoreOnImageArchive     = KoreOnImageName + "_" + KoreOnVersion + ".tar.gz"
 	KoreOnKubeConfig       = "acloud-client-kubeconfig"
+	KoreOnOidcKubeConfig   = "oidc-kubeconfig"
 	KoreOnConfigFile       = "koreon.toml"
 	AddOnConfigFile        = "addon.toml"
 	KoreOnConfigFileSubDir = "internal/playbooks/koreon-playbook/download"
@@ -14,8 +15,16 @@ var (
 	KoreOnConfigDir        = "internal/playbooks/koreon-playbook/download/config"
 	KoreOnExtendsFileDir   = "internal/playbooks/koreon-playbook/download/extends"
 	KoreOnLogsDir          = "internal/playbooks/koreon-playbook/download/logs"
+	KoreOnRegistryDir      = "internal/playbooks/koreon-playbook/download/registry"
 	HelmCubeRepoUrl        = "https://hcapital-harbor.acloud.run/chartrepo/cube"
 	HelmChartProject       = "helm-charts"
+	// Harbor admin account of the private registry (registry_id of the registry role)
+	HarborAdminID = "admin"
+	// HarborPasswordEnv - Harbor admin password given to registry-manager. It is passed to the container
+	// as an environment variable, never on the command line.
+	HarborPasswordEnv = "KOREON_HARBOR_PASSWORD"
+	// KoreOnRegistryCAFile - CA of the registry certificate generated by kore-on, fetched into the config directory
+	KoreOnRegistryCAFile = "registry-ca.crt"
 )
 
 var Addon = map[string]string{
@@ -1 +1,30 @@
 package conf
+
+// SupportVersionConf - Support version maps in conf/config.yaml shown by 'koreonctl versions' (component name: config key)
+var SupportVersionConf = [][]string{
+	{"kubernetes", "SupportK8sVersion"},
+	{"calico", "SupportCalicoVersion"},
+	{"calicoctl", "SupportCalicoCtlVersion"},
+	{"cilium", "SupportCiliumVersion"},
+	{"flannel", "SupportFlannelVersion"},
+	{"coredns", "SupportCorednsVersion"},
+	{"metrics-server", "SupportMetricsServerVersion"},
+	{"nginx", "SupportNginxVersion"},
+	{"pause", "SupportPauseVersion"},
+	{"containerd", "SupportContainerdVersion"},
+	{"cri-o", "SupportCrioVersion"},
+	{"crictl", "SupportCrictlVersion"},
+	{"etcd", "SupportEtcdVersion"},
+	{"dns-utils", "SupportDnsUtilsVersion"},
+	{"clusterctl", "SupportClusterCtlVersion"},
+	{"harbor", "SupportHarborVersion"},
+	{"docker-compose", "SupportDockerComposeVersion"},
+	{"helm", "SupportHelmVersion"},
+	{"csi-driver-nfs (chart)", "ChartCsiDriverNfsVersion"},
+	{"koreboard (chart)", "ChartKoreboardVersion"},
+	{"ingress-nginx (chart)", "ChartIngressNginxVersion"},
+	{"metallb (chart)", "ChartMetallbVersion"},
+	{"cert-manager (chart)", "ChartCertManagerVersion"},
+	{"kube-prometheus-stack (chart)", "ChartKubePrometheusStackVersion"},
+	{"longhorn (chart)", "ChartLonghornVersion"},
+}
@@ -3,36 +3,63 @@ package templates
 const AddonText = `
 {{- $Master := .AddonTemp.Addon.K8sMasterIP }}
 {{- $Apps := .AddonTemp.Apps }}
-{{- $check := false }}
 {{- $task := "Installation" }}
 {{- if eq "delete" .Command }}
 {{- $task = "DELETE" }}
+{{- else if eq "diff" .Command }}
+{{- $task = "DIFF" }}
+{{- else if eq "upgrade" .Command }}
+{{- $task = "UPGRADE" }}
+{{- else if eq "rollback" .Command }}
+{{- $task = "ROLLBACK" }}
 {{- end}}
 ## Inventory for {{ $task }} task.
 ===========================================================================
 Node Name                      IP Address              Private IP Adderss
 ===========================================================================
-{{- if ne "" $Master }}
+{{- if .AddonTemp.Addon.Local }}
+localhost                    (kubeconfig: {{ .AddonTemp.Addon.KubeConfig }})
+{{ else if ne "" $Master }}
 k8s-master-1                 {{$Master}}                    
 {{ end -}}
 ===========================================================================
 
  {{ $task }} Application List
 -------------------------------
-{{- range $k, $v := $Apps -}}
-{{-   range $i, $j := $v -}}
-{{-     if eq "Install" $i -}}
-{{-        if eq true $j}}
-{{-       $check = $j -}}
-{{-        else }}
-{{-        $check = false -}}
-{{-        end }}
-{{-     end -}}
-{{-   end }}
-{{- if eq true $check }}
-{{ $k }}
+{{- if eq "rollback" .Command }}
+{{- if gt .Revision 0.0 }}
+(to revision {{ .Revision }})
+{{- else }}
+(to the previous revision)
 {{- end }}
 {{- end }}
+{{- range $k := .AppList }}
+{{- $v := index $Apps $k }}
+{{ $k }}{{ if $v.DependsOn }} (after {{ range $j, $d := $v.DependsOn }}{{ if $j }}, {{ end }}{{ $d }}{{ end }}){{ end }}
+{{- end }}
 
 
 > Is this ok [y/n]: `
+
+const AddonListText = `
+===========================================================================
+{{ "Name" | printf "%-*s" 25 }} {{ "Install" | printf "%-*s" 8 }} {{ "Chart" | printf "%-*s" 30 }} {{ "Version" | printf "%-*s" 12 }} Namespace
+===========================================================================
+{{- range . }}
+{{ .Name | printf "%-*s" 25 }} {{ .Install | printf "%-*v" 8 }} {{ .Chart | printf "%-*s" 30 }} {{ or .DesiredVersion "latest" | printf "%-*s" 12 }} {{ or .Namespace "-" }}
+{{- end }}
+===========================================================================
+`
+
+const AddonStatusText = `
+===========================================================================
+{{ "Name" | printf "%-*s" 25 }} {{ "Namespace" | printf "%-*s" 15 }} {{ "Desired" | printf "%-*s" 12 }} {{ "Installed" | printf "%-*s" 12 }} {{ "Revision" | printf "%-*s" 9 }} Status
+===========================================================================
+{{- range . }}
+{{ .Name | printf "%-*s" 25 }} {{ or .Namespace "-" | printf "%-*s" 15 }} {{ or .DesiredVersion "latest" | printf "%-*s" 12 }} {{ or .InstalledVersion "-" | printf "%-*s" 12 }} {{ or .Revision "-" | printf "%-*s" 9 }} {{ or .Status "-" }}
+{{- if .Drift }}
+{{ drift (join .Drift ", ") | printf "  > drift: %s" }}
+{{- end }}
+{{- end }}
+===========================================================================
+`
@@ -22,6 +22,99 @@ Cluster Nodes
 {{$data.Name|printf "%-*s" $cluster_len.Name}}{{$data.Status|printf "%-*s" $cluster_len.Status}}{{$data.Role|printf "%-*s" $cluster_len.Role}}{{$data.Age|printf "%-*s" $cluster_len.Age}}{{$data.Version|printf "%-*s" $cluster_len.Version}}{{$data.InternalIP|printf "%-*s" $cluster_len.InternalIP}}{{$data.ExternalIP|printf "%-*s" $cluster_len.ExternalIP}}{{$data.OSImage|printf "%-*s" $cluster_len.OSImage}}{{$data.KernelVersion|printf "%-*s" $cluster_len.KernelVersion}}{{$data.ContainerRuntime|printf "%-*s" $cluster_len.ContainerRuntime}}
 {{- end}}
 
+{{- if eq $command "CONFIG" }}
+{{- $Kubernetes := .KoreOnTemp.Kubernetes }}
+{{- if or (eq $Kubernetes.Cni "") (eq $Kubernetes.Cni "calico") }}
+{{- $Calico := $Kubernetes.Calico }}
+
+
+Calico Network (no node changes)
+----------------------
+=====================================================================================
+encapsulation: {{ $Calico.Encapsulation }}{{ if $Calico.CrossSubnet }} (CrossSubnet){{ end }}
+mtu: {{ if eq $Calico.Mtu 0 }}auto{{ else }}{{ $Calico.Mtu }}{{ end }}
+{{- if $Calico.IPAutodetectionMethod }}
+ip-autodetection-method: {{ $Calico.IPAutodetectionMethod }}
+{{- end }}
+{{- range $Calico.IPPools }}
+ip-pool: {{ .Name | printf "%-*s" 20 }}{{ .Cidr | printf "%-*s" 20 }}{{ .Encapsulation | printf "%-*s" 7 }}{{ .NodeSelector }}
+{{- end }}
+{{- if ne $Calico.Encapsulation "vxlan" }}
+bgp: as-number {{ $Calico.Bgp.AsNumber }}, node-to-node mesh {{ if $Calico.Bgp.DisableNodeMesh }}disabled{{ else }}enabled{{ end }}
+{{-   range $Calico.Bgp.Peers }}
+bgp-peer: {{ .Name | printf "%-*s" 20 }}{{ .PeerIP | printf "%-*s" 20 }}AS {{ .AsNumber }}{{ if .NodeSelector }} ({{ .NodeSelector }}){{ end }}
+{{-   end }}
+{{- end }}
+=====================================================================================
+{{- end }}
+
+
+Kubernetes Components (no node changes, one node at a time)
+----------------------
+=====================================================================================
+{{- with $Kubernetes.Apiserver }}
+{{- range $name, $value := .ExtraArgs }}
+kube-apiserver: --{{ $name }}={{ $value }}
+{{- end }}
+{{- range $name, $enabled := .FeatureGates }}
+kube-apiserver: feature-gate {{ $name }}={{ $enabled }}
+{{- end }}
+{{- if .EnableAdmissionPlugins }}
+kube-apiserver: enable-admission-plugins {{ join .EnableAdmissionPlugins "," }}
+{{- end }}
+{{- if .DisableAdmissionPlugins }}
+kube-apiserver: disable-admission-plugins {{ join .DisableAdmissionPlugins "," }}
+{{- end }}
+{{- end }}
+{{- range $name, $value := $Kubernetes.ControllerManager.ExtraArgs }}
+kube-controller-manager: --{{ $name }}={{ $value }}
+{{- end }}
+{{- range $name, $enabled := $Kubernetes.ControllerManager.FeatureGates }}
+kube-controller-manager: feature-gate {{ $name }}={{ $enabled }}
+{{- end }}
+{{- range $name, $value := $Kubernetes.Scheduler.ExtraArgs }}
+kube-scheduler: --{{ $name }}={{ $value }}
+{{- end }}
+{{- range $name, $enabled := $Kubernetes.Scheduler.FeatureGates }}
+kube-scheduler: feature-gate {{ $name }}={{ $enabled }}
+{{- end }}
+{{- with $Kubernetes.Kubelet }}
+{{- range $name, $value := .ExtraArgs }}
+kubelet: --{{ $name }}={{ $value }}
+{{- end }}
+{{- range $name, $enabled := .FeatureGates }}
+kubelet: feature-gate {{ $name }}={{ $enabled }}
+{{- end }}
+{{- if .MaxPods }}
+kubelet: max-pods {{ .MaxPods }}
+{{- end }}
+{{- range $name, $value := .KubeReserved }}
+kubelet: kube-reserved {{ $name }}={{ $value }}
+{{- end }}
+{{- range $name, $value := .SystemReserved }}
+kubelet: system-reserved {{ $name }}={{ $value }}
+{{- end }}
+{{- range $name, $value := .EvictionHard }}
+kubelet: eviction-hard {{ $name }}={{ $value }}
+{{- end }}
+{{- range $name, $value := .EvictionSoft }}
+kubelet: eviction-soft {{ $name }}={{ $value }} (grace period {{ index $.KoreOnTemp.Kubernetes.Kubelet.EvictionSoftGracePeriod $name }})
+{{- end }}
+{{- end }}
+{{- with $Kubernetes.Oidc }}
+{{- if .IssuerURL }}
+kube-apiserver: oidc issuer {{ .IssuerURL }} (client-id {{ .ClientID }})
+{{- if .UsernameClaim }}
+kube-apiserver: oidc username-claim {{ .UsernameClaim }}
+{{- end }}
+{{- if .GroupsClaim }}
+kube-apiserver: oidc groups-claim {{ .GroupsClaim }}
+{{- end }}
+{{- end }}
+{{- end }}
+=====================================================================================
+{{- else }}
+
 
 Update Nodes ({{ $command }})
 ----------------------
@@ -40,4 +133,5 @@ node-{{$index|printf "%-*v" 10 }}{{$data|printf "%-*s" $update_node_len.IP}}{{(i
 {{-   end }}
 {{- end}}
 {{ printf "%.*s" $total "======================================================================================================================================================" }}
+{{- end }}
 Is this ok [y/n]: `
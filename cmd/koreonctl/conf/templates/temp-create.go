@@ -18,7 +18,7 @@ master-{{ $index |printf "%-*v" 22 }}{{ $data | printf "%-*s" 28 }}{{if ne (len
 node-{{ $index |printf "%-*v" 24 }}{{ $data | printf "%-*s" 28 }}{{if ne (len $Node.PrivateIP) 0}}{{index $Node.PrivateIP $index}}{{end -}} 
 {{  end}}
 {{  if eq true $PrivateRegistry.Install -}}
-{{    if eq true $SharedStorage.Install -}}
+{{    if eq true $SharedStorage.Server -}}
 {{      if eq $PrivateRegistry.RegistryIP $SharedStorage.StorageIP}}
 {{ "node-regi-storage" | printf "%-*s" 29 }}{{ $PrivateRegistry.RegistryIP | printf "%-*s" 28 }}{{if ne "" $PrivateRegistry.PrivateIP}}{{$PrivateRegistry.PrivateIP}}{{end -}}
 {{      else}}
@@ -28,7 +28,7 @@ node-{{ $index |printf "%-*v" 24 }}{{ $data | printf "%-*s" 28 }}{{if ne (len $N
 {{    else}}
 {{ "node-regi" | printf "%-*s" 29 }}{{ $PrivateRegistry.RegistryIP | printf "%-*s" 28 }}{{if ne "" $PrivateRegistry.PrivateIP}}{{$PrivateRegistry.PrivateIP}}{{end -}}
 {{    end -}}
-{{  else if eq true $SharedStorage.Install}}
+{{  else if eq true $SharedStorage.Server}}
 {{ "node-storage" | printf "%-*s" 29 }}{{ $SharedStorage.StorageIP | printf "%-*s" 28 }}{{if ne "" $SharedStorage.PrivateIP}}{{$SharedStorage.PrivateIP}}{{end -}}
 {{ else if and (ne "" $PrivateRegistry.RegistryIP)  (eq true $PrivateRegistry.PublicCert) }}
 ## Private repositories are not installed 
@@ -37,5 +37,13 @@ node-{{ $index |printf "%-*v" 24 }}{{ $data | printf "%-*s" 28 }}{{if ne (len $N
 ## Private repositories are not installed. used domain name
 {{ "node-regi" | printf "%-*s" 29 }}{{ $PrivateRegistry.RegistryDomain }}
 {{  end}}
+## Container runtime: {{ or .KoreOnTemp.Kubernetes.ContainerRuntime "containerd" }} {{ if eq "cri-o" .KoreOnTemp.Kubernetes.ContainerRuntime }}{{ .KoreOnTemp.SupportVersion.PackageVersion.Crio }}{{ else }}{{ .KoreOnTemp.SupportVersion.PackageVersion.Containerd }}{{ end }}
+## Network plugin: {{ .KoreOnTemp.Kubernetes.Cni }}{{ if ne 0 (len .KoreOnTemp.NodePool.Master.PrivateIPv6) }} (IPv4/IPv6 dual-stack){{ end }}
+{{- if eq true $SharedStorage.Install }}
+## Shared storage: {{ $SharedStorage.StorageType }} (default StorageClass)
+{{-   range $SharedStorage.Exports }}
+##   {{ .StorageClass | printf "%-*s" 24 }} {{ $SharedStorage.StorageIP }}:{{ .Path }} ({{ .ReclaimPolicy }}{{ if .Default }}, default{{ end }})
+{{-   end }}
+{{- end }}
 ===========================================================================
 Is this ok [y/n]: `
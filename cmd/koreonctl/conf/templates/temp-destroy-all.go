@@ -17,7 +17,7 @@ master-{{ $index |printf "%-*v" 22 }}{{ $data | printf "%-*s" 28 }}{{if ne (len
 node-{{ $index |printf "%-*v" 24 }}{{ $data | printf "%-*s" 28 }}{{if ne (len $Node.PrivateIP) 0}}{{index $Node.PrivateIP $index}}{{end -}} 
 {{  end}}
 {{  if eq true $PrivateRegistry.Install -}}
-{{    if eq true $SharedStorage.Install -}}
+{{    if eq true $SharedStorage.Server -}}
 {{      if eq $PrivateRegistry.RegistryIP $SharedStorage.StorageIP}}
 {{ "node-regi-storage" | printf "%-*s" 29 }}{{ $PrivateRegistry.RegistryIP | printf "%-*s" 28 }}{{if ne "" $PrivateRegistry.PrivateIP}}{{$PrivateRegistry.PrivateIP}}{{end -}}
 {{      else}}
@@ -27,7 +27,7 @@ node-{{ $index |printf "%-*v" 24 }}{{ $data | printf "%-*s" 28 }}{{if ne (len $N
 {{    else}}
 {{ "node-regi" | printf "%-*s" 29 }}{{ $PrivateRegistry.RegistryIP | printf "%-*s" 28 }}{{if ne "" $PrivateRegistry.PrivateIP}}{{$PrivateRegistry.PrivateIP}}{{end -}}
 {{    end -}}
-{{  else if eq true $SharedStorage.Install}}
+{{  else if eq true $SharedStorage.Server}}
 {{ "node-storage" | printf "%-*s" 29 }}{{ $SharedStorage.StorageIP | printf "%-*s" 28 }}{{if ne "" $SharedStorage.PrivateIP}}{{$SharedStorage.PrivateIP}}{{end -}}
 {{  end}}
 ===========================================================================
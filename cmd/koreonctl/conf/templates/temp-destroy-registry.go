@@ -11,7 +11,7 @@ const DestroyRegistryText = `
 Node Name                      IP Address              Private IP Adderss
 ===========================================================================
 {{-  if eq true $PrivateRegistry.Install -}}
-{{    if eq true $SharedStorage.Install -}}
+{{    if eq true $SharedStorage.Server -}}
 {{      if eq $PrivateRegistry.RegistryIP $SharedStorage.StorageIP}}
 node-regi-storage            {{$PrivateRegistry.RegistryIP}}
 {{      else}}
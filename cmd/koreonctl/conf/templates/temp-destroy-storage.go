@@ -11,15 +11,18 @@ const DestroyStorageText = `
 Node Name                      IP Address              Private IP Adderss
 ===========================================================================
 {{-  if eq true $PrivateRegistry.Install -}}
-{{    if eq true $SharedStorage.Install -}}
+{{    if eq true $SharedStorage.Server -}}
 {{      if eq $PrivateRegistry.RegistryIP $SharedStorage.StorageIP}}
 node-regi-storage            {{$PrivateRegistry.RegistryIP}}
 {{      else}}
 node-storage                   {{$SharedStorage.StorageIP}}                    {{if ne "" $SharedStorage.PrivateIP}}{{$SharedStorage.PrivateIP}}{{end -}}
 {{      end -}}
 {{    end -}}
-{{  else if eq true $SharedStorage.Install}}
+{{  else if eq true $SharedStorage.Server}}
 node-storage                   {{$SharedStorage.StorageIP}}                    {{if ne "" $SharedStorage.PrivateIP}}{{$SharedStorage.PrivateIP}}{{end -}}
 {{  end }}
+{{- if eq true $SharedStorage.Install }}
+## Shared storage: {{ $SharedStorage.StorageType }} (default StorageClass and volumes are deleted)
+{{- end }}
 ===========================================================================
 Is this ok [y/n]: `
@@ -0,0 +1,16 @@
+package templates
+
+const ImportAirgapText = `
+{{- $PrivateRegistry := .KoreOnTemp.PrivateRegistry}}
+## Inventory for {{.Command}} task.
+===========================================================================
+Node Name                      IP Address              Private IP Adderss
+===========================================================================
+{{ "node-regi" | printf "%-*s" 31 }}{{ $PrivateRegistry.RegistryIP | printf "%-*s" 24 }}{{ if ne "" $PrivateRegistry.PrivateIP }}{{ $PrivateRegistry.PrivateIP }}{{ end }}
+===========================================================================
+Delta bundle since {{ .Delta.Since }}
+  packages : {{ len .Delta.Packages }} archive(s)
+  images   : {{ len .Delta.Images }}
+  charts   : {{ len .Delta.Charts }}
+===========================================================================
+Is this ok [y/n]: `
@@ -0,0 +1,35 @@
+package templates
+
+const RegistryManagerText = `
+## Harbor {{.Command}} changes on {{.Registry}} (Harbor {{.Version}})
+===========================================================================
+Action     Kind       Name                            Detail
+===========================================================================
+{{- range .Changes}}
+{{ .Action | printf "%-*s" 11 }}{{ .Kind | printf "%-*s" 11 }}{{ .Name | printf "%-*s" 32 }}{{ .Detail }}
+{{- else}}
+(no projects)
+{{- end}}
+===========================================================================
+{{- if .Confirm}}
+Is this ok [y/n]: {{ end }}`
+
+const RegistryOperationText = `
+## Harbor {{.Command}} on {{.Registry}}
+===========================================================================
+Installed version : {{.Version}}
+{{- if .Target}}
+Upgrade version   : {{.Target}}
+{{- end}}
+{{- if eq .Command "restore"}}
+Restore from      : registry/backup/{{.BackupFile}}
+{{- else}}
+Backup to         : registry/backup/{{.BackupFile}}
+{{- end}}
+===========================================================================
+{{- if eq .Command "restore"}}
+Harbor is stopped and its database is replaced by the backup.
+{{- else if eq .Command "upgrade"}}
+Harbor is stopped during the upgrade. It is restored from the backup when the upgrade fails.
+{{- end}}
+Is this ok [y/n]: `
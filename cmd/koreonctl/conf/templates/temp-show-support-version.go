@@ -1,5 +1,52 @@
 package templates
 
 const ShowSupportVersionText = `
+===========================================================================
+Component                 Supported Versions
+===========================================================================
+{{- range . }}
+{{- $Name := .Name }}
+{{- range .Series }}
+{{ $Name | printf "%-*s" 30 }} {{ range $i, $v := .Versions }}{{ if $i }}, {{ end }}{{ $v }}{{ end }}
+{{- end }}
+{{- end }}
+===========================================================================
+`
 
+const ResolveSupportVersionText = `
+## Versions resolved for kubernetes {{ .Kubernetes }}
+===========================================================================
+Component                 Version
+===========================================================================
+{{ "kubernetes" | printf "%-*s" 30 }} {{ .Kubernetes }}
+{{ "containerd" | printf "%-*s" 30 }} {{ .PackageVersion.Containerd }}
+{{- if .PackageVersion.Crio }}
+{{ "cri-o" | printf "%-*s" 30 }} {{ .PackageVersion.Crio }}
+{{- end }}
+{{ "crictl" | printf "%-*s" 30 }} {{ .PackageVersion.Crictl }}
+{{ "etcd" | printf "%-*s" 30 }} {{ .PackageVersion.Etcd }}
+{{ "calico" | printf "%-*s" 30 }} {{ .ImageVersion.Calico }}
+{{ "calicoctl" | printf "%-*s" 30 }} {{ .PackageVersion.CalicoCtl }}
+{{ "cilium" | printf "%-*s" 30 }} {{ .ImageVersion.Cilium }}
+{{ "flannel" | printf "%-*s" 30 }} {{ .ImageVersion.Flannel }}
+{{ "coredns" | printf "%-*s" 30 }} {{ .ImageVersion.Coredns }}
+{{ "metrics-server" | printf "%-*s" 30 }} {{ .ImageVersion.MetricsServer }}
+{{ "pause" | printf "%-*s" 30 }} {{ .ImageVersion.Pause }}
+{{ "dns-utils" | printf "%-*s" 30 }} {{ .ImageVersion.DnsUtils }}
+{{ "helm" | printf "%-*s" 30 }} {{ .PackageVersion.Helm }}
+{{ "docker-compose" | printf "%-*s" 30 }} {{ .PackageVersion.DockerCompose }}
+{{- if .PackageVersion.ClusterCtl }}
+{{ "clusterctl" | printf "%-*s" 30 }} {{ .PackageVersion.ClusterCtl }}
+{{- end }}
+{{ "harbor" | printf "%-*s" 30 }} {{ .Harbor }}
+{{ "csi-driver-nfs (chart)" | printf "%-*s" 30 }} {{ .HelmChartVersion.CsiDriverNfs }}
+{{ "koreboard (chart)" | printf "%-*s" 30 }} {{ .HelmChartVersion.Koreboard }}
+{{ "ingress-nginx (chart)" | printf "%-*s" 30 }} {{ .HelmChartVersion.IngressNginx }}
+{{ "metallb (chart)" | printf "%-*s" 30 }} {{ .HelmChartVersion.Metallb }}
+{{ "cert-manager (chart)" | printf "%-*s" 30 }} {{ .HelmChartVersion.CertManager }}
+{{ "kube-prometheus-stack (chart)" | printf "%-*s" 30 }} {{ .HelmChartVersion.KubePrometheusStack }}
+{{- if .HelmChartVersion.Longhorn }}
+{{ "longhorn (chart)" | printf "%-*s" 30 }} {{ .HelmChartVersion.Longhorn }}
+{{- end }}
+===========================================================================
 `
@@ -6,6 +6,7 @@ package main
 import (
 	"fmt"
 	"kore-on/cmd/koreonctl/cmd"
+	"os"
 	"time"
 )
 
@@ -13,7 +14,8 @@ func timeElapsed() func() {
 	start := time.Now()
 	return func() {
 		timeElapsed := time.Since(start)
-		fmt.Println("Duration", timeElapsed, "time")
+		// stderr, so that machine readable output (e.g. versions -o json) stays clean
+		fmt.Fprintln(os.Stderr, "Duration", timeElapsed, "time")
 	}
 }
 
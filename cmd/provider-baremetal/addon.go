@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"bytes"
-	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -12,16 +11,12 @@ import (
 	"kore-on/cmd/koreonctl/conf/templates"
 	"kore-on/pkg/logger"
 	"kore-on/pkg/model"
+	"kore-on/pkg/runner"
 	"kore-on/pkg/utils"
 
-	"os"
 	"strings"
 	"text/template"
 
-	"github.com/apenella/go-ansible/pkg/execute"
-	"github.com/apenella/go-ansible/pkg/execute/measure"
-	"github.com/apenella/go-ansible/pkg/options"
-	"github.com/apenella/go-ansible/pkg/playbook"
 	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
@@ -42,6 +37,9 @@ type strAddonCmd struct {
 	addonExtravars map[string]interface{}
 	result         map[string]interface{}
 	command        string
+	app            string
+	revision       int
+	kubeconfig     string
 }
 
 func AddonCmd() *cobra.Command {
@@ -59,7 +57,12 @@ func AddonCmd() *cobra.Command {
 	}
 
 	// SubCommand add
-	cmd.AddCommand(AddonDeleteCmd())
+	cmd.AddCommand(
+		AddonDeleteCmd(),
+		AddonDiffCmd(),
+		AddonUpgradeCmd(),
+		AddonRollbackCmd(),
+	)
 
 	// SubCommand validation
 	utils.CheckCommand(cmd)
@@ -79,6 +82,7 @@ func AddonCmd() *cobra.Command {
 	f.StringVar(&addon.tags, "tags", addon.tags, "Ansible options tags")
 	f.StringVarP(&addon.privateKey, "private-key", "p", "", "Specify ssh key path")
 	f.StringVarP(&addon.user, "user", "u", "", "login user")
+	f.StringVar(&addon.kubeconfig, "kubeconfig", "", "kubeconfig file path to run helm in this host instead of k8s-master-ip")
 
 	return cmd
 }
@@ -112,6 +116,103 @@ func AddonDeleteCmd() *cobra.Command {
 	f.StringVar(&addonDelete.tags, "tags", addonDelete.tags, "Ansible options tags")
 	f.StringVarP(&addonDelete.privateKey, "private-key", "p", "", "Specify ssh key path")
 	f.StringVarP(&addonDelete.user, "user", "u", "", "login user")
+	f.StringVar(&addonDelete.kubeconfig, "kubeconfig", "", "kubeconfig file path to run helm in this host instead of k8s-master-ip")
+
+	return cmd
+}
+
+func AddonDiffCmd() *cobra.Command {
+	addonDiff := &strAddonCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "diff [app] [flags]",
+		Short: "Show diff of Applications against the live helm releases",
+		Long: "This command renders the manifest of the application in addon.toml (all applications without [app])\n" +
+			"and shows the diff against the manifest of the live helm release.",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				addonDiff.app = args[0]
+			}
+			return addonDiff.run()
+		},
+	}
+
+	addonDiff.command = "diff"
+	addonDiff.inventory = "./internal/playbooks/koreon-playbook/inventory/inventory.ini"
+	addonDiff.playbookFiles = []string{
+		"./internal/playbooks/koreon-playbook/addon-operation.yaml",
+	}
+	f := cmd.Flags()
+	f.BoolVar(&addonDiff.verbose, "verbose", false, "verbose")
+	f.BoolVarP(&addonDiff.dryRun, "dry-run", "d", false, "dryRun")
+	f.StringVarP(&addonDiff.privateKey, "private-key", "p", "", "Specify ssh key path")
+	f.StringVarP(&addonDiff.user, "user", "u", "", "login user")
+	f.StringVar(&addonDiff.kubeconfig, "kubeconfig", "", "kubeconfig file path to run helm in this host instead of k8s-master-ip")
+
+	return cmd
+}
+
+func AddonUpgradeCmd() *cobra.Command {
+	addonUpgrade := &strAddonCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "upgrade <app> [flags]",
+		Short: "Upgrade an Application in kubernetes cluster",
+		Long: "This command upgrades the helm release of the application\n" +
+			"to the chart_version and values in addon.toml.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addonUpgrade.app = args[0]
+			return addonUpgrade.run()
+		},
+	}
+
+	addonUpgrade.command = "upgrade"
+	addonUpgrade.inventory = "./internal/playbooks/koreon-playbook/inventory/inventory.ini"
+	addonUpgrade.playbookFiles = []string{
+		"./internal/playbooks/koreon-playbook/add-on.yaml",
+	}
+	f := cmd.Flags()
+	f.BoolVar(&addonUpgrade.verbose, "verbose", false, "verbose")
+	f.BoolVarP(&addonUpgrade.dryRun, "dry-run", "d", false, "dryRun")
+	f.StringVarP(&addonUpgrade.privateKey, "private-key", "p", "", "Specify ssh key path")
+	f.StringVarP(&addonUpgrade.user, "user", "u", "", "login user")
+	f.StringVar(&addonUpgrade.kubeconfig, "kubeconfig", "", "kubeconfig file path to run helm in this host instead of k8s-master-ip")
+
+	return cmd
+}
+
+func AddonRollbackCmd() *cobra.Command {
+	addonRollback := &strAddonCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "rollback <app> [flags]",
+		Short: "Rollback an Application in kubernetes cluster",
+		Long: "This command rolls back the helm release of the application\n" +
+			"to the previous revision or to --revision.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addonRollback.app = args[0]
+			return addonRollback.run()
+		},
+	}
+
+	addonRollback.command = "rollback"
+	addonRollback.inventory = "./internal/playbooks/koreon-playbook/inventory/inventory.ini"
+	addonRollback.playbookFiles = []string{
+		"./internal/playbooks/koreon-playbook/addon-operation.yaml",
+	}
+	f := cmd.Flags()
+	f.BoolVar(&addonRollback.verbose, "verbose", false, "verbose")
+	f.BoolVarP(&addonRollback.dryRun, "dry-run", "d", false, "dryRun")
+	f.IntVar(&addonRollback.revision, "revision", 0, "helm release revision to roll back to (default: previous revision)")
+	f.StringVarP(&addonRollback.privateKey, "private-key", "p", "", "Specify ssh key path")
+	f.StringVarP(&addonRollback.user, "user", "u", "", "login user")
+	f.StringVar(&addonRollback.kubeconfig, "kubeconfig", "", "kubeconfig file path to run helm in this host instead of k8s-master-ip")
 
 	return cmd
 }
@@ -121,216 +222,256 @@ func (c *strAddonCmd) run() error {
 	addonPath := utils.IskoreOnConfigFilePath(addonConfigFileName)
 	addonToml, err := utils.GetAddonTomlConfig(addonPath)
 	if err != nil {
-		logger.Fatal(err)
-	} else {
+		return err
+	}
 
-		// current pocessing directory
-		dir, err := utils.Dirname("../..")
-		if err != nil {
-			logger.Fatal(err)
+	// Local mode runs helm in this host with the kubeconfig, k8s-master-ip only without --kubeconfig
+	if c.kubeconfig != "" {
+		if !utils.FileExists(c.kubeconfig) {
+			return fmt.Errorf("kubeconfig %s is not found", c.kubeconfig)
+		}
+		if err := utils.CheckKubeconfig(c.kubeconfig); err != nil {
+			return err
+		}
+		if addonToml.Addon.K8sMasterIP != "" {
+			logger.Warnf("k8s-master-ip(%s) is ignored with --kubeconfig", addonToml.Addon.K8sMasterIP)
+			addonToml.Addon.K8sMasterIP = ""
 		}
-		if dir == "/build" {
-			dir = ""
+		if c.installHelm || c.helmBinaryFile != "" {
+			logger.Warn("helm installation is ignored with --kubeconfig. helm in this host is used")
+			c.installHelm = false
+			c.helmBinaryFile = ""
 		}
-		addonToml.Addon.WorkDir = dir + "/" + conf.KoreOnConfigFileSubDir
+		addonToml.Addon.Local = true
+		addonToml.Addon.KubeConfig = c.kubeconfig
+	} else if addonToml.Addon.K8sMasterIP == "" {
+		return fmt.Errorf("k8s-master-ip in %s or --kubeconfig is required", addonConfigFileName)
+	}
 
-		// Make provision data
-		data := model.AddonText{}
-		data.AddonTemp = addonToml
-		data.Command = c.command
+	// Deployment order of the apps. Delete in the reverse order of the deployment
+	appList, err := utils.AddonAppList(addonToml.Apps)
+	if err != nil {
+		return err
+	}
+	if c.command == "delete" {
+		for i, j := 0, len(appList)-1; i < j; i, j = i+1, j-1 {
+			appList[i], appList[j] = appList[j], appList[i]
+		}
+	}
+	if c.app != "" {
+		found := false
+		for _, name := range appList {
+			if name == c.app {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("apps.%s is not an app to install in %s", c.app, addonConfigFileName)
+		}
+		appList = []string{c.app}
+	}
+	if c.revision < 0 {
+		return fmt.Errorf("revision %d is not a helm release revision", c.revision)
+	}
 
-		addon_temp, err := utils.StrucToJson(data)
+	// Cluster of koreon.toml: chart version of the catalog apps and context of the values templates
+	var valuesContext *model.AddonValuesContext
+	k8sVersion := ""
+	koreOnConfigFilePath := utils.IskoreOnConfigFilePath(conf.KoreOnConfigFile)
+	if utils.FileExists(koreOnConfigFilePath) {
+		koreonToml, err := utils.GetKoreonTomlConfig(koreOnConfigFilePath)
 		if err != nil {
-			logger.Fatal(err)
+			return err
 		}
+		valuesContext = utils.AddonValuesContextOf(koreonToml)
+		k8sVersion = koreonToml.Kubernetes.Version
+	}
+	resolved, err := utils.SetAddonChartVersion(addonToml.Apps, appList, k8sVersion)
+	if err != nil {
+		return err
+	}
+	for _, name := range resolved {
+		logger.Infof("apps.%s > chart_version %s (helm_chart_package)", name, addonToml.Apps[name].ChartVersion)
+	}
 
-		// Processing template
-		koreonctlText := template.New("AddonText")
-		temp, err := koreonctlText.Parse(templates.AddonText)
+	// Values and manifests of the apps, rendered before the deployment to fail early
+	resultYaml := make(map[string]interface{})
+	for _, name := range appList {
+		dataYaml, err := utils.SetValuesFile(name, addonToml.Apps[name], valuesContext)
 		if err != nil {
-			logger.Errorf("Template has errors. cause(%s)", err.Error())
 			return err
 		}
-		// TODO: 진행상황을 어떻게 클라이언트에 보여줄 것인가?
-		var buff bytes.Buffer
-		err = temp.Execute(&buff, addon_temp)
+		for k, v := range dataYaml {
+			resultYaml[k] = v
+		}
+		manifests, err := utils.SetManifests(name, addonToml.Apps[name], valuesContext)
 		if err != nil {
-			logger.Errorf("Template execution failed. cause(%s)", err.Error())
 			return err
 		}
+		// the rendered values are passed by addon_apps, the template is not an ansible variable
+		app := addonToml.Apps[name]
+		app.Values = ""
+		app.Manifests = manifests
+		addonToml.Apps[name] = app
+	}
 
-		if !utils.CheckUserInput(buff.String(), "y") {
-			fmt.Println("nothing to changed. exit")
-			os.Exit(1)
-		}
+	// current pocessing directory
+	dir, err := utils.Dirname("../..")
+	if err != nil {
+		return err
+	}
+	if dir == "/build" {
+		dir = ""
+	}
+	addonToml.Addon.WorkDir = dir + "/" + conf.KoreOnConfigFileSubDir
 
-		// Install Helm
-		if c.installHelm {
-			addonToml.Addon.HelmInstall = c.installHelm
-		}
-		if c.helmBinaryFile != "" {
-			addonToml.Addon.HelmBinaryFile = c.helmBinaryFile
-		}
+	// Make provision data
+	data := model.AddonText{}
+	data.AddonTemp = addonToml
+	data.Command = c.command
+	data.AppList = appList
+	data.Revision = c.revision
 
-		// Apps configuration for csi-driver-nfs
-		if c.command != "delete" && !addonToml.Addon.ClosedNetwork && addonToml.Apps.CsiDriverNfs.Install {
-
-			id := utils.InputPrompt("\n## To deploy csi-driver-nfs, you need to login as a private repository (Helm Chart) user.\nusername:")
-			pw := utils.SensitivePrompt("password:")
-
-			// commandArgs := "helm search repo " + addonToml.Apps.CsiDriverNfs.ChartRefName + "/" + addonToml.Apps.CsiDriverNfs.ChartName +
-			// 	" --output json"
-
-			// rs, err := c.checkHelmRepoLogin(id, pw, commandArgs)
-			// if err != nil {
-			// 	logger.Fatal(err)
-			// }
-
-			commandArgs := "helm registry login " + addonToml.Apps.CsiDriverNfs.ChartRef +
-				" --username " + id +
-				" --password " + pw
-
-			err := checkHelmRepoLogin(id, pw, commandArgs)
-			if err != nil {
-				str := fmt.Sprintf("%s", err)
-				fi := strings.Index(str, "Error")
-				li := strings.LastIndex(str, "\"")
-				err = fmt.Errorf(str[fi : li+1])
-				logger.Fatal(err)
-			} else {
-				fmt.Println("Login Succeeded!!")
-			}
+	addon_temp, err := utils.StrucToJson(data)
+	if err != nil {
+		return err
+	}
 
-			addonToml.Apps.CsiDriverNfs.ChartRefID = base64.StdEncoding.EncodeToString([]byte(id))
-			addonToml.Apps.CsiDriverNfs.ChartRefPW = base64.StdEncoding.EncodeToString([]byte(pw))
-		}
+	// Processing template
+	koreonctlText := template.New("AddonText")
+	temp, err := koreonctlText.Parse(templates.AddonText)
+	if err != nil {
+		logger.Errorf("Template has errors. cause(%s)", err.Error())
+		return err
+	}
+	// TODO: 진행상황을 어떻게 클라이언트에 보여줄 것인가?
+	var buff bytes.Buffer
+	err = temp.Execute(&buff, addon_temp)
+	if err != nil {
+		logger.Errorf("Template execution failed. cause(%s)", err.Error())
+		return err
+	}
 
-		addonToml.Addon.HelmVersion = utils.IsSupportVersion("", "SupportHelmVersion")
-		if addonToml.Addon.AddonDataDir == "" {
-			addonToml.Addon.AddonDataDir = "/data/addon"
-		}
+	if !utils.CheckUserInput(buff.String(), "y") {
+		return errCanceled
+	}
 
-		// addonToml.Addon.KubeConfig = viper.GetString("Addon.KubeConfigDir") + "/" + viper.GetString("KoreOn.KoreOnKubeConfig")
-		addonToml.Addon.KubeConfig = conf.Addon["KubeConfigDir"] + "/" + conf.KoreOnKubeConfig
+	// Install Helm
+	if c.installHelm {
+		addonToml.Addon.HelmInstall = c.installHelm
+	}
+	if c.helmBinaryFile != "" {
+		addonToml.Addon.HelmBinaryFile = c.helmBinaryFile
+	}
 
-		b, err := json.Marshal(addonToml)
-		if err != nil {
-			logger.Fatal(err)
+	// Apps in the private repository (Helm Chart) need a user login
+	for _, name := range appList {
+		app := addonToml.Apps[name]
+		if c.command == "delete" || c.command == "rollback" || addonToml.Addon.ClosedNetwork || !app.Login {
+			continue
 		}
-		if err := json.Unmarshal(b, &c.addonExtravars); err != nil {
-			logger.Fatal(err.Error())
+
+		id := utils.InputPrompt(fmt.Sprintf("\n## To deploy %s, you need to login as a private repository (Helm Chart) user.\nusername:", name))
+		pw := utils.SensitivePrompt("password:")
+
+		// oci://<registry>/<project> is logged in with the registry host
+		registry := app.ChartRef
+		if strings.HasPrefix(registry, "oci://") {
+			registry = strings.SplitN(strings.TrimPrefix(registry, "oci://"), "/", 2)[0]
 		}
+		commandArgs := "helm registry login " + registry +
+			" --username " + id +
+			" --password " + pw
 
-		result := make(map[string]interface{})
-		// for k, v := range c.extravars {
-		// 	if _, ok := c.extravars[k]; ok {
-		// 		result[k] = v
-		// 	}
-		// }
-		for k, v := range c.addonExtravars {
-			if _, ok := c.addonExtravars[k]; ok {
-				result[k] = v
-			}
+		if err := checkHelmRepoLogin(id, pw, commandArgs); err != nil {
+			return err
 		}
-		c.result = result
+		fmt.Println("Login Succeeded!!")
 
+		app.ChartRefID = base64.StdEncoding.EncodeToString([]byte(id))
+		app.ChartRefPW = base64.StdEncoding.EncodeToString([]byte(pw))
+		addonToml.Apps[name] = app
 	}
 
-	if len(c.playbookFiles) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook playbook file path must be specified")
+	if addonToml.Addon.HelmVersion, err = utils.IsSupportVersion("", "SupportHelmVersion"); err != nil {
+		return err
 	}
-
-	if len(c.inventory) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an inventory must be specified")
+	if addonToml.Addon.AddonDataDir == "" {
+		addonToml.Addon.AddonDataDir = "/data/addon"
 	}
 
-	if len(c.privateKey) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an privateKey must be specified")
+	// addonToml.Addon.KubeConfig = viper.GetString("Addon.KubeConfigDir") + "/" + viper.GetString("KoreOn.KoreOnKubeConfig")
+	if !addonToml.Addon.Local {
+		addonToml.Addon.KubeConfig = conf.Addon["KubeConfigDir"] + "/" + conf.KoreOnKubeConfig
 	}
 
-	if len(c.user) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an ssh login user must be specified")
+	b, err := json.Marshal(addonToml)
+	if err != nil {
+		return err
 	}
-
-	ansiblePlaybookConnectionOptions := &options.AnsibleConnectionOptions{
-		PrivateKey: c.privateKey,
-		User:       c.user,
+	if err := json.Unmarshal(b, &c.addonExtravars); err != nil {
+		return err
 	}
 
-	ansiblePlaybookOptions := &playbook.AnsiblePlaybookOptions{
-		Inventory: c.inventory,
-		Verbose:   c.verbose,
-		Tags:      c.tags,
-		ExtraVars: c.result,
-		// ExtraVarsFile: []string{"@internal/playbooks/koreon-playbook/download/test-values.yaml"},
+	result := make(map[string]interface{})
+	// for k, v := range c.extravars {
+	// 	if _, ok := c.extravars[k]; ok {
+	// 		result[k] = v
+	// 	}
+	// }
+	for k, v := range c.addonExtravars {
+		if _, ok := c.addonExtravars[k]; ok {
+			result[k] = v
+		}
 	}
+	c.result = result
 
 	// Set values file and ExtraVarsFile
-	rApps := make(map[string]interface{})
-	apps, err := json.Marshal(addonToml.Apps)
-	if err != nil {
-		logger.Fatal(err)
-	}
-	if err := json.Unmarshal(apps, &rApps); err != nil {
-		logger.Fatal(err.Error())
+	appItems := []map[string]string{}
+	for _, name := range appList {
+		timeout, _ := utils.AddonWaitTimeout(addonToml.Apps[name].Wait)
+		appItems = append(appItems, map[string]string{
+			"name":       name,
+			"values_key": utils.AddonValuesKey(name),
+			"timeout":    fmt.Sprintf("%ds", int(timeout.Seconds())),
+		})
 	}
 
-	resultYaml := make(map[string]interface{})
-
-	for k, v := range rApps {
-		for i, j := range v.(map[string]interface{}) {
-			if i == "Install" && j == true {
-				dataYaml, _ := utils.SetValuesFile(k, v.(map[string]interface{}))
-
-				for k, v := range dataYaml {
-					if _, ok := dataYaml[k]; ok {
-						resultYaml[k] = v
-					}
-				}
-			}
-		}
-	}
 	c.extravarsFile = map[string]interface{}{
-		"addon_apps": resultYaml,
+		"addon_apps":     resultYaml,
+		"addon_app_list": appItems,
+		// diff, rollback in addon-operation.yaml
+		"addon_operation":         c.command,
+		"addon_rollback_revision": c.revision,
 	}
 	bytes, err := yaml.Marshal(c.extravarsFile)
 	if err != nil {
-		logger.Fatal(err)
+		return err
 	}
 	subPath := conf.KoreOnConfigFileSubDir
 
-	ioutil.WriteFile(subPath+"/extravars-file.yaml", []byte(bytes), 0600)
-
-	err = ansiblePlaybookOptions.AddExtraVarsFile("@" + subPath + "/extravars-file.yaml")
-	if err != nil {
-		logger.Fatal(err)
-	}
-
-	executorTimeMeasurement := measure.NewExecutorTimeMeasurement(
-		execute.NewDefaultExecute(
-			execute.WithEnvVar("ANSIBLE_FORCE_COLOR", "true"),
-			execute.WithTransformers(
-				utils.OutputColored(),
-				results.Prepend("Addon deployment in cluster"),
-				// results.LogFormat(results.DefaultLogFormatLayout, results.Now),
-			),
-		),
-		// measure.WithShowDuration(),
-	)
-
-	playbook := &playbook.AnsiblePlaybookCmd{
-		Playbooks:         c.playbookFiles,
-		ConnectionOptions: ansiblePlaybookConnectionOptions,
-		Options:           ansiblePlaybookOptions,
-		Exec:              executorTimeMeasurement,
+	if err := ioutil.WriteFile(subPath+"/extravars-file.yaml", []byte(bytes), 0600); err != nil {
+		return err
 	}
 
-	options.AnsibleForceColor()
+	connection := ""
+	if addonToml.Addon.Local {
+		connection = "local"
+	}
 
-	err = playbook.Run(context.TODO())
-	if err != nil {
-		return err
+	task := &runner.Task{
+		Name:          "Addon deployment in cluster",
+		Playbooks:     c.playbookFiles,
+		Inventory:     c.inventory,
+		Tags:          c.tags,
+		Verbose:       c.verbose,
+		PrivateKey:    c.privateKey,
+		User:          c.user,
+		Connection:    connection,
+		ExtraVars:     c.result,
+		ExtraVarsFile: []string{"@" + subPath + "/extravars-file.yaml"},
+		Transformers:  []results.TransformerFunc{utils.OutputColored()},
 	}
 
-	return nil
+	return runPlaybook(task, c.dryRun)
 }
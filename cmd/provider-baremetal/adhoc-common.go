@@ -2,97 +2,55 @@ package cmd
 
 import (
 	"bytes"
-	"context"
 	"fmt"
-	"io"
+	"kore-on/pkg/runner"
 	"strings"
 
-	"github.com/apenella/go-ansible/pkg/adhoc"
-	"github.com/apenella/go-ansible/pkg/execute"
-	"github.com/apenella/go-ansible/pkg/execute/measure"
-	"github.com/apenella/go-ansible/pkg/options"
+	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
 )
 
 // Check Helm Repo Login - ansible ad-hoc used
 func checkHelmRepoLogin(id string, pw string, commandArgs string) error {
-	var err error
-
 	buff := new(bytes.Buffer)
 
-	ansibleConnectionOptions := &options.AnsibleConnectionOptions{
-		Connection: "local",
-	}
-
-	executorTimeMeasurement := measure.NewExecutorTimeMeasurement(
-		execute.NewDefaultExecute(
-			execute.WithWrite(io.Writer(buff)),
-		),
-	)
-
-	ansibleAdhocOptions := &adhoc.AnsibleAdhocOptions{
-		Inventory:  " 127.0.0.1,",
-		ModuleName: "command",
-		Args:       commandArgs,
-	}
-
-	adhoc := &adhoc.AnsibleAdhocCmd{
-		Pattern:           "all",
-		Exec:              executorTimeMeasurement,
-		Options:           ansibleAdhocOptions,
-		ConnectionOptions: ansibleConnectionOptions,
-		StdoutCallback:    "json",
+	task := &runner.Task{
+		Name:           "Helm Repo Login",
+		Pattern:        "all",
+		Inventory:      " 127.0.0.1,",
+		Connection:     "local",
+		Module:         "command",
+		ModuleArgs:     commandArgs,
+		StdoutCallback: "json",
+		Output:         buff,
 	}
 
-	err = adhoc.Run(context.TODO())
-	if err != nil {
-		firstIndex := strings.Index(buff.String(), "stderr")
-		lastIndex := strings.LastIndex(buff.String(), "stderr_lines")
-		tempStr := buff.String()[firstIndex+9 : lastIndex-1]
-		tmpLastIndex := strings.LastIndex(tempStr, ",")
-		return fmt.Errorf(tempStr[0:tmpLastIndex])
+	if err := runAdhoc(task); err != nil {
+		return fmt.Errorf("%s", adhocStderr(buff.Bytes(), err))
 	}
 
 	return nil
 }
 
-// Check Helm Repo Login - ansible ad-hoc used
-func downloadInstaller(commandArgs string) error {
-	var err error
-
-	buff := new(bytes.Buffer)
-
-	ansibleConnectionOptions := &options.AnsibleConnectionOptions{
-		Connection: "local",
-	}
-
-	executorTimeMeasurement := measure.NewExecutorTimeMeasurement(
-		execute.NewDefaultExecute(
-			execute.WithWrite(io.Writer(buff)),
-		),
-	)
-
-	ansibleAdhocOptions := &adhoc.AnsibleAdhocOptions{
-		Inventory:  " 127.0.0.1,",
-		ModuleName: "command",
-		Args:       commandArgs,
-	}
-
-	adhoc := &adhoc.AnsibleAdhocCmd{
-		Pattern:           "all",
-		Exec:              executorTimeMeasurement,
-		Options:           ansibleAdhocOptions,
-		ConnectionOptions: ansibleConnectionOptions,
-		StdoutCallback:    "json",
-	}
-
-	err = adhoc.Run(context.TODO())
-	if err != nil {
-		firstIndex := strings.Index(buff.String(), "stderr")
-		lastIndex := strings.LastIndex(buff.String(), "stderr_lines")
-		tempStr := buff.String()[firstIndex+9 : lastIndex-1]
-		tmpLastIndex := strings.LastIndex(tempStr, ",")
-		return fmt.Errorf(tempStr[0:tmpLastIndex])
-	}
-
-	return nil
+// adhocStderr - stderr of the failed host in the json output of an ad-hoc command
+func adhocStderr(output []byte, err error) string {
+	res, perr := results.JSONParse(output)
+	if perr != nil {
+		return err.Error()
+	}
+	for _, play := range res.Plays {
+		for _, task := range play.Tasks {
+			for _, host := range task.Hosts {
+				if !host.Failed {
+					continue
+				}
+				if stderr := strings.TrimSpace(fmt.Sprint(host.Stderr)); host.Stderr != nil && stderr != "" {
+					return stderr
+				}
+				if host.Msg != nil {
+					return fmt.Sprint(host.Msg)
+				}
+			}
+		}
+	}
+	return err.Error()
 }
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
 	"kore-on/cmd/koreonctl/conf"
@@ -11,6 +10,7 @@ import (
 	"kore-on/pkg/logger"
 	"kore-on/pkg/model"
 	"kore-on/pkg/model/k8s"
+	"kore-on/pkg/runner"
 	"kore-on/pkg/utils"
 
 	"os"
@@ -20,10 +20,6 @@ import (
 	"text/template"
 	"time"
 
-	"github.com/apenella/go-ansible/pkg/execute"
-	"github.com/apenella/go-ansible/pkg/options"
-	"github.com/apenella/go-ansible/pkg/playbook"
-	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -50,7 +46,7 @@ func ClusterUpdateCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:          "update [flags]",
 		Short:        "Update kubernetes cluster(node scale in/out)",
-		Long:         "This command update the Kubernetes cluster nodes (node scale in/out)",
+		Long:         "This command update the Kubernetes cluster nodes (node scale in/out).\nWithout node changes, the calico network configuration and the kubernetes component flags\n([kubernetes.apiserver], [kubernetes.controller-manager], [kubernetes.scheduler], [kubernetes.kubelet])\nof koreon.toml are applied one node at a time.",
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return clusterUpdate.run()
@@ -149,10 +145,9 @@ func UpdateInitCmd() *cobra.Command {
 func (c *strClusterUpdateCmd) run() error {
 	koreOnConfigFileName := conf.KoreOnConfigFile
 	koreOnConfigFilePath := utils.IskoreOnConfigFilePath(koreOnConfigFileName)
-	koreonToml, errBool := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "cluster-update")
-	if !errBool {
-		message := "Settings are incorrect. Please check the 'korean.toml' file!!"
-		logger.Fatal(fmt.Errorf("%s", message))
+	koreonToml, err := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "cluster-update")
+	if err != nil {
+		return err
 	}
 
 	// koreonToml Default value
@@ -161,7 +156,7 @@ func (c *strClusterUpdateCmd) run() error {
 	// current pocessing directory
 	dir, err := utils.Dirname("../..")
 	if err != nil {
-		logger.Fatal(err)
+		return err
 	}
 	if dir == "/build" {
 		dir = ""
@@ -173,20 +168,14 @@ func (c *strClusterUpdateCmd) run() error {
 		koreonToml.Kubernetes.GetKubeConfig = true
 	}
 
-	if len(c.playbookFiles) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook playbook file path must be specified")
-	}
-
-	if len(c.inventory) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an inventory must be specified")
-	}
-
-	if len(c.privateKey) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an privateKey must be specified")
+	preCheck := &runner.Task{
+		Playbooks:  c.playbookFiles,
+		Inventory:  c.inventory,
+		PrivateKey: c.privateKey,
+		User:       c.user,
 	}
-
-	if len(c.user) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an ssh login user must be specified")
+	if err := preCheck.Validate(); err != nil {
+		return err
 	}
 
 	if c.command != "update-init" && c.command != "get-kubeconfig" && len(c.kubeconfig) < 1 {
@@ -209,12 +198,12 @@ func (c *strClusterUpdateCmd) run() error {
 		kubeconfigPath, _ := filepath.Abs(c.kubeconfig)
 		config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
 		if err != nil {
-			logger.Fatal(err)
+			return err
 		}
 		var lbIP []string
 
 		if koreonToml.NodePool.Master.LbIP != "" {
-			lbIP[0] = koreonToml.NodePool.Master.LbIP
+			lbIP = []string{koreonToml.NodePool.Master.LbIP}
 		} else {
 			lbIP = koreonToml.NodePool.Master.IP
 		}
@@ -222,18 +211,18 @@ func (c *strClusterUpdateCmd) run() error {
 		chekServerAddress := checkKubeconfig(lbIP, config.Host)
 
 		if !chekServerAddress {
-			logger.Fatal("The cluster is unreachable. Check the kubeconfig server address.")
+			return fmt.Errorf("[ERROR]: %s", "The cluster is unreachable. Check the kubeconfig server address.")
 		}
 
 		client, err := kubemethod.CreateK8sClient(config)
 		if err != nil {
-			logger.Fatal(err)
+			return err
 		}
 
 		// Get K8s Cluster Nodes
 		kubeNodes, err := kubemethod.GetNodeList(client)
 		if err != nil {
-			logger.Fatal(err)
+			return err
 		}
 
 		for _, v := range kubeNodes {
@@ -258,7 +247,12 @@ func (c *strClusterUpdateCmd) run() error {
 				}
 			}
 			if cnt == len(node) {
-				logger.Fatal("Same as the current cluster node list. There are no node entries to update. Please check node pool input.")
+				// 노드 변경 없음: calico network 설정(ip pools, bgp, mtu)과 kubernetes component 설정을 적용
+				// (kubelet 설정을 위해 전체 node 가 inventory 에 포함된다)
+				updateType = "CONFIG"
+				c.playbookFiles = []string{
+					"./internal/playbooks/koreon-playbook/cluster-update-config.yaml",
+				}
 			}
 		}
 		if len(koreonToml.NodePool.Node.PrivateIP) > 0 {
@@ -328,8 +322,8 @@ func (c *strClusterUpdateCmd) run() error {
 				}
 			}
 		}
-		if len(updateNodeIP) == 0 {
-			logger.Fatal("There are no node entries to update. Please check node pool input.")
+		if len(updateNodeIP) == 0 && updateType != "CONFIG" {
+			return fmt.Errorf("[ERROR]: %s", "There are no node entries to update. Please check node pool input.")
 		}
 	}
 
@@ -342,10 +336,15 @@ func (c *strClusterUpdateCmd) run() error {
 		data.UpdateNode.IP = append(data.UpdateNode.IP, v)
 		data.UpdateNode.PrivateIP = append(data.UpdateNode.PrivateIP, updateNodePrivateIP[k])
 		data.UpdateNode.Name = append(data.UpdateNode.Name, updateNodeName[k])
+		// dual-stack: IPv6 address of the added (or updated) node (the index of koreon.toml)
+		if (updateType == "ADD" || updateType == "CONFIG") && k < len(koreonToml.NodePool.Node.PrivateIPv6) {
+			data.UpdateNode.PrivateIPv6 = append(data.UpdateNode.PrivateIPv6, koreonToml.NodePool.Node.PrivateIPv6[k])
+		}
 	}
 	koreonToml.NodePool.Node.Name = data.UpdateNode.Name
 	koreonToml.NodePool.Node.IP = data.UpdateNode.IP
 	koreonToml.NodePool.Node.PrivateIP = data.UpdateNode.PrivateIP
+	koreonToml.NodePool.Node.PrivateIPv6 = data.UpdateNode.PrivateIPv6
 	koreonToml.KoreOn.Update = true
 
 	// Processing template
@@ -427,6 +426,7 @@ func (c *strClusterUpdateCmd) run() error {
 			}
 			return total
 		},
+		"join": strings.Join,
 	}))
 	var tempText = ""
 	if c.command == "update-init" {
@@ -455,63 +455,42 @@ func (c *strClusterUpdateCmd) run() error {
 		return err
 	}
 	if !utils.CheckUserInput(buff.String(), "y") {
-		fmt.Println("nothing to changed. exit")
-		os.Exit(1)
+		return errCanceled
 	}
 
 	b, err := json.Marshal(koreonToml)
 	if err != nil {
-		logger.Fatal(err)
-		os.Exit(1)
+		return err
 	}
 	if err := json.Unmarshal(b, &c.extravars); err != nil {
-		logger.Fatal(err.Error())
-		os.Exit(1)
+		return err
 	}
 
 	if c.command == "update-init" {
 		currTime := time.Now()
 
-		if err == nil {
-			fmt.Println("Previous " + koreOnConfigFileName + " file exist and it will be backup")
-			e := os.Rename(koreOnConfigFilePath, koreOnConfigFilePath+"_"+currTime.Format("20060102150405"))
-			if e != nil {
-				logger.Fatal(e)
-			}
+		fmt.Println("Previous " + koreOnConfigFileName + " file exist and it will be backup")
+		if err := os.Rename(koreOnConfigFilePath, koreOnConfigFilePath+"_"+currTime.Format("20060102150405")); err != nil {
+			return err
 		}
 	}
 
-	ansiblePlaybookConnectionOptions := &options.AnsibleConnectionOptions{
-		PrivateKey: c.privateKey,
-		User:       c.user,
-	}
-
-	ansiblePlaybookOptions := &playbook.AnsiblePlaybookOptions{
-		Inventory: c.inventory,
-		Verbose:   c.verbose,
-		Tags:      c.tags,
-		ExtraVars: c.extravars,
-	}
-
-	playbook := &playbook.AnsiblePlaybookCmd{
-		Playbooks:         c.playbookFiles,
-		ConnectionOptions: ansiblePlaybookConnectionOptions,
-		Options:           ansiblePlaybookOptions,
-		Exec: execute.NewDefaultExecute(
-			execute.WithTransformers(
-				results.Prepend("Update Cluster"),
-			),
-		),
+	if err := checkExternalRegistry(koreonToml, c.dryRun); err != nil {
+		return err
 	}
 
-	options.AnsibleForceColor()
-
-	err = playbook.Run(context.TODO())
-	if err != nil {
-		return err
+	task := &runner.Task{
+		Name:       "Update Cluster",
+		Playbooks:  c.playbookFiles,
+		Inventory:  c.inventory,
+		Tags:       c.tags,
+		Verbose:    c.verbose,
+		PrivateKey: c.privateKey,
+		User:       c.user,
+		ExtraVars:  c.extravars,
 	}
 
-	return nil
+	return runPlaybook(task, c.dryRun)
 }
 
 func checkKubeconfig(ip []string, host string) bool {
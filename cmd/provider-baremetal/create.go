@@ -2,22 +2,16 @@ package cmd
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
-	"fmt"
 	"kore-on/cmd/koreonctl/conf"
 	"kore-on/cmd/koreonctl/conf/templates"
 	"kore-on/pkg/logger"
 	"kore-on/pkg/model"
+	"kore-on/pkg/runner"
 	"kore-on/pkg/utils"
-	"os"
 	"strings"
 	"text/template"
 
-	"github.com/apenella/go-ansible/pkg/execute"
-	"github.com/apenella/go-ansible/pkg/options"
-	"github.com/apenella/go-ansible/pkg/playbook"
-	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
 	"github.com/spf13/cobra"
 )
 
@@ -90,7 +84,10 @@ func emptyCmd() *cobra.Command {
 func (c *strCreateCmd) run() error {
 	koreOnConfigFileName := conf.KoreOnConfigFile
 	koreOnConfigFilePath := utils.IskoreOnConfigFilePath(koreOnConfigFileName)
-	koreonToml, value := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "create")
+	koreonToml, err := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "create")
+	if err != nil {
+		return err
+	}
 
 	// koreonToml Default value
 	koreonToml.KoreOn.FileName = koreOnConfigFileName
@@ -98,23 +95,19 @@ func (c *strCreateCmd) run() error {
 	// current pocessing directory
 	dir, err := utils.Dirname("../..")
 	if err != nil {
-		logger.Fatal(err)
+		return err
 	}
 	if dir == "/build" {
 		dir = ""
 	}
 	koreonToml.KoreOn.WorkDir = dir + "/" + conf.KoreOnConfigFileSubDir
 
-	if value {
-		b, err := json.Marshal(koreonToml)
-		if err != nil {
-			logger.Fatal(err)
-			os.Exit(1)
-		}
-		if err := json.Unmarshal(b, &c.extravars); err != nil {
-			logger.Fatal(err.Error())
-			os.Exit(1)
-		}
+	b, err := json.Marshal(koreonToml)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &c.extravars); err != nil {
+		return err
 	}
 
 	// Make provision data
@@ -146,55 +139,23 @@ func (c *strCreateCmd) run() error {
 	}
 
 	if !utils.CheckUserInput(buff.String(), "y") {
-		fmt.Println("nothing to changed. exit")
-		os.Exit(1)
-	}
-
-	if len(c.playbookFiles) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook playbook file path must be specified")
-	}
-
-	if len(c.inventory) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an inventory must be specified")
+		return errCanceled
 	}
 
-	if len(c.privateKey) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an privateKey must be specified")
-	}
-
-	if len(c.user) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an ssh login user must be specified")
+	if err := checkExternalRegistry(koreonToml, c.dryRun); err != nil {
+		return err
 	}
 
-	ansiblePlaybookConnectionOptions := &options.AnsibleConnectionOptions{
+	task := &runner.Task{
+		Name:       "Create Cluster",
+		Playbooks:  c.playbookFiles,
+		Inventory:  c.inventory,
+		Tags:       c.tags,
+		Verbose:    c.verbose,
 		PrivateKey: c.privateKey,
 		User:       c.user,
+		ExtraVars:  c.extravars,
 	}
 
-	ansiblePlaybookOptions := &playbook.AnsiblePlaybookOptions{
-		Inventory: c.inventory,
-		Verbose:   c.verbose,
-		Tags:      c.tags,
-		ExtraVars: c.extravars,
-	}
-
-	playbook := &playbook.AnsiblePlaybookCmd{
-		Playbooks:         c.playbookFiles,
-		ConnectionOptions: ansiblePlaybookConnectionOptions,
-		Options:           ansiblePlaybookOptions,
-		Exec: execute.NewDefaultExecute(
-			execute.WithTransformers(
-				results.Prepend("Create Cluster"),
-			),
-		),
-	}
-
-	options.AnsibleForceColor()
-
-	err = playbook.Run(context.TODO())
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return runPlaybook(task, c.dryRun)
 }
@@ -2,21 +2,15 @@ package cmd
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
-	"fmt"
 	"kore-on/cmd/koreonctl/conf"
 	"kore-on/cmd/koreonctl/conf/templates"
 	"kore-on/pkg/logger"
 	"kore-on/pkg/model"
+	"kore-on/pkg/runner"
 	"kore-on/pkg/utils"
-	"os"
 	"text/template"
 
-	"github.com/apenella/go-ansible/pkg/execute"
-	"github.com/apenella/go-ansible/pkg/options"
-	"github.com/apenella/go-ansible/pkg/playbook"
-	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
 	"github.com/spf13/cobra"
 )
 
@@ -170,7 +164,7 @@ func destroyStorageCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:          "storage [flags]",
 		Short:        "Destroy storage",
-		Long:         "This command deletes the installed storage(NFS) and deletes related services and directories.",
+		Long:         "This command deletes the default StorageClass of the storage-type, the installed storage(NFS) and deletes related services and directories.",
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return destroyStorageCmd.run()
@@ -197,18 +191,17 @@ func destroyStorageCmd() *cobra.Command {
 func (c *strDestroyCmd) run() error {
 	koreOnConfigFileName := conf.KoreOnConfigFile
 	koreOnConfigFilePath := utils.IskoreOnConfigFilePath(koreOnConfigFileName)
-	koreonToml, value := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, c.tags)
+	koreonToml, err := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, c.tags)
+	if err != nil {
+		return err
+	}
 
-	if value {
-		b, err := json.Marshal(koreonToml)
-		if err != nil {
-			logger.Fatal(err)
-			os.Exit(1)
-		}
-		if err := json.Unmarshal(b, &c.extravars); err != nil {
-			logger.Fatal(err.Error())
-			os.Exit(1)
-		}
+	b, err := json.Marshal(koreonToml)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &c.extravars); err != nil {
+		return err
 	}
 
 	// Make provision data
@@ -225,6 +218,10 @@ func (c *strDestroyCmd) run() error {
 	case "reset-cluster":
 		textVar = templates.DestroyClusterText
 	case "reset-registry":
+		if koreonToml.PrivateRegistry.External.URL != "" {
+			logger.Warnf("external registry(%s) is not managed by kore-on. nothing to destroy", koreonToml.PrivateRegistry.External.URL)
+			return nil
+		}
 		textVar = templates.DestroyRegistryText
 	case "reset-storage":
 		textVar = templates.DestroyStorageText
@@ -249,60 +246,27 @@ func (c *strDestroyCmd) run() error {
 	}
 
 	if !utils.CheckUserInput(buff.String(), "y") {
-		fmt.Println("nothing to changed. exit")
-		os.Exit(1)
-	}
-
-	if len(c.playbookFiles) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook playbook file path must be specified")
-	}
-
-	if len(c.inventory) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an inventory must be specified")
-	}
-
-	if len(c.privateKey) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an privateKey must be specified")
-	}
-
-	if len(c.user) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an ssh login user must be specified")
+		return errCanceled
 	}
 
 	if len(c.tags) > 1 && c.tags == "all" {
 		c.tags = ""
 	}
 
-	utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, c.tags)
+	if _, err := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, c.tags); err != nil {
+		return err
+	}
 
-	ansiblePlaybookConnectionOptions := &options.AnsibleConnectionOptions{
+	task := &runner.Task{
+		Name:       "Destroy Cluster",
+		Playbooks:  c.playbookFiles,
+		Inventory:  c.inventory,
+		Tags:       c.tags,
+		Verbose:    c.verbose,
 		PrivateKey: c.privateKey,
 		User:       c.user,
-	}
-	ansiblePlaybookOptions := &playbook.AnsiblePlaybookOptions{
-		Inventory: c.inventory,
-		Verbose:   c.verbose,
-		Tags:      c.tags,
-		ExtraVars: c.extravars,
-	}
-
-	playbook := &playbook.AnsiblePlaybookCmd{
-		Playbooks:         c.playbookFiles,
-		ConnectionOptions: ansiblePlaybookConnectionOptions,
-		Options:           ansiblePlaybookOptions,
-		Exec: execute.NewDefaultExecute(
-			execute.WithTransformers(
-				results.Prepend("Destroy Cluster"),
-			),
-		),
-	}
-
-	options.AnsibleForceColor()
-
-	err = playbook.Run(context.TODO())
-	if err != nil {
-		return err
+		ExtraVars:  c.extravars,
 	}
 
-	return nil
+	return runPlaybook(task, c.dryRun)
 }
@@ -2,21 +2,18 @@ package cmd
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
 	"kore-on/cmd/koreonctl/conf"
 	"kore-on/cmd/koreonctl/conf/templates"
+	"kore-on/pkg/airgap"
 	"kore-on/pkg/logger"
 	"kore-on/pkg/model"
+	"kore-on/pkg/runner"
 	"kore-on/pkg/utils"
-	"os"
+	"strings"
 	"text/template"
 
-	"github.com/apenella/go-ansible/pkg/execute"
-	"github.com/apenella/go-ansible/pkg/options"
-	"github.com/apenella/go-ansible/pkg/playbook"
-	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
 	"github.com/spf13/cobra"
 )
 
@@ -30,6 +27,7 @@ type strAirGapCmd struct {
 	privateKey    string
 	user          string
 	command       string
+	since         string
 	extravars     map[string]interface{}
 }
 
@@ -50,6 +48,7 @@ func AirGapCmd() *cobra.Command {
 	cmd.AddCommand(
 		DownLoadArchiveCmd(),
 		ImageUploadCmd(),
+		AirGapImportCmd(),
 	)
 
 	// SubCommand validation
@@ -68,6 +67,38 @@ func AirGapCmd() *cobra.Command {
 	f.StringVar(&prepareAirgap.tags, "tags", prepareAirgap.tags, "Ansible options tags")
 	f.StringVarP(&prepareAirgap.privateKey, "private-key", "p", "", "Specify ssh key path")
 	f.StringVarP(&prepareAirgap.user, "user", "u", "", "login user")
+	f.StringVar(&prepareAirgap.since, "since", "", "manifest of the previous bundle. Only new packages, images and charts are bundled")
+
+	return cmd
+}
+
+func AirGapImportCmd() *cobra.Command {
+	airgapImport := &strAirGapCmd{}
+
+	cmd := &cobra.Command{
+		Use:          "import [flags]",
+		Short:        "Import a delta bundle into the local repository and private registry",
+		Long:         "",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return airgapImport.importDelta()
+		},
+	}
+
+	// Default value for command struct
+	airgapImport.tags = ""
+	airgapImport.command = "import"
+	airgapImport.inventory = "./internal/playbooks/koreon-playbook/inventory/inventory.ini"
+	airgapImport.playbookFiles = []string{
+		"./internal/playbooks/koreon-playbook/prepare-airgap-import.yaml",
+	}
+
+	f := cmd.Flags()
+	f.BoolVarP(&airgapImport.verbose, "verbose", "v", false, "verbose")
+	f.BoolVarP(&airgapImport.dryRun, "dry-run", "d", false, "dryRun")
+	f.StringVar(&airgapImport.tags, "tags", airgapImport.tags, "Ansible options tags")
+	f.StringVarP(&airgapImport.privateKey, "private-key", "p", "", "Specify ssh key path")
+	f.StringVarP(&airgapImport.user, "user", "u", "", "login user")
 
 	return cmd
 }
@@ -137,10 +168,9 @@ func ImageUploadCmd() *cobra.Command {
 func (c *strAirGapCmd) run() error {
 	koreOnConfigFileName := conf.KoreOnConfigFile
 	koreOnConfigFilePath := utils.IskoreOnConfigFilePath(koreOnConfigFileName)
-	koreonToml, errBool := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "prepare-airgap")
-	if !errBool {
-		message := "Settings are incorrect. Please check the 'korean.toml' file!!"
-		logger.Fatal(fmt.Errorf("%s", message))
+	koreonToml, err := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "prepare-airgap")
+	if err != nil {
+		return err
 	}
 
 	// koreonToml Default value
@@ -149,7 +179,7 @@ func (c *strAirGapCmd) run() error {
 	// current pocessing directory
 	dir, err := utils.Dirname("../..")
 	if err != nil {
-		logger.Fatal(err)
+		return err
 	}
 	if dir == "/build" {
 		dir = ""
@@ -178,89 +208,189 @@ func (c *strAirGapCmd) run() error {
 	}
 
 	if !utils.CheckUserInput(buff.String(), "y") {
-		fmt.Println("nothing to changed. exit")
-		os.Exit(1)
+		return errCanceled
+	}
+
+	b, err := json.Marshal(koreonToml)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &c.extravars); err != nil {
+		return err
+	}
+
+	// Charts of the apps in addon.toml are bundled with the cluster charts
+	addonCharts, err := prepareAddonCharts(koreonToml.Kubernetes.Version)
+	if err != nil {
+		return err
+	}
+	c.extravars["prepare_airgap_addon_charts"] = addonCharts
+
+	// Delta bundle
+	if c.since != "" {
+		since, err := airgap.LoadFile(c.since)
+		if err != nil {
+			return err
+		}
+		logger.Infof("Prepare Air Gap > delta bundle since %s (kubernetes %s)", since.CreatedAt, since.Versions.Kubernetes)
+		c.extravars["prepare_airgap_delta"] = true
+		c.extravars["prepare_airgap_since"] = map[string]interface{}{
+			"created_at": since.CreatedAt,
+			"kubernetes": since.Versions.Kubernetes,
+			"images":     since.Images,
+			"charts":     since.Charts,
+			"packages":   since.Packages,
+		}
+	}
+
+	if err := checkExternalRegistry(koreonToml, c.dryRun); err != nil {
+		return err
+	}
+
+	task := &runner.Task{
+		Name:       "Prepare AirGap",
+		Playbooks:  c.playbookFiles,
+		Inventory:  c.inventory,
+		Tags:       c.tags,
+		Verbose:    c.verbose,
+		PrivateKey: c.privateKey,
+		User:       c.user,
+		ExtraVars:  c.extravars,
+	}
+
+	return runPlaybook(task, c.dryRun)
+}
+
+// importDelta - Merge the delta bundle of the archive directory into the local repository and Harbor
+func (c *strAirGapCmd) importDelta() error {
+	koreOnConfigFileName := conf.KoreOnConfigFile
+	koreOnConfigFilePath := utils.IskoreOnConfigFilePath(koreOnConfigFileName)
+	koreonToml, err := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "create")
+	if err != nil {
+		return err
 	}
 
-	// if c.command == "" {
-	// 	// Prompt login
-	// 	id := utils.InputPrompt("\n## To helm chart pull csi-driver-nfs, you need to login as a private repository (Helm Chart) user.\nusername:")
-	// 	pw := utils.SensitivePrompt("password:")
-	// 	koreonToml.KoreOn.HelmChartProject = viper.GetString("KoreOn.HelmChartProject")
-	// 	koreonToml.KoreOn.HelmCubeRepoID = base64.StdEncoding.EncodeToString([]byte(id))
-	// 	koreonToml.KoreOn.HelmCubeRepoPW = base64.StdEncoding.EncodeToString([]byte(pw))
+	// koreonToml Default value
+	koreonToml.KoreOn.HelmChartProject = conf.HelmChartProject
 
-	// 	commandArgs := "helm registry login " + koreonToml.KoreOn.HelmCubeRepoUrl +
-	// 		" --username " + id +
-	// 		" --password " + pw
+	// current pocessing directory
+	dir, err := utils.Dirname("../..")
+	if err != nil {
+		return err
+	}
+	if dir == "/build" {
+		dir = ""
+	}
+	koreonToml.KoreOn.WorkDir = dir + "/" + conf.KoreOnConfigFileSubDir
 
-	// 	err = checkHelmRepoLogin(id, pw, commandArgs)
-	// 	if err != nil {
-	// 		str := fmt.Sprintf("%s", err)
-	// 		fi := strings.Index(str, "Error")
-	// 		li := strings.LastIndex(str, "\"")
-	// 		err = fmt.Errorf(str[fi : li+1])
-	// 		logger.Fatal(err)
-	// 	} else {
-	// 		fmt.Println("Login Succeeded!!")
-	// 	}
-	// }
+	if !koreonToml.KoreOn.ClosedNetwork || !koreonToml.PrivateRegistry.Install {
+		return fmt.Errorf("[ERROR]: %s", "A delta bundle can only be imported in a closed network with the private registry installed")
+	}
 
-	if len(c.playbookFiles) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook playbook file path must be specified")
+	m, err := airgap.Load(koreonToml.KoreOn.WorkDir)
+	if err != nil {
+		return err
+	}
+	if m.Delta == nil {
+		return fmt.Errorf("[ERROR]: %s", "The bundle is not a delta bundle. Use 'create' to install a full bundle")
 	}
 
-	if len(c.inventory) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an inventory must be specified")
+	// Make provision data
+	data := struct {
+		model.KoreonctlText
+		Delta *airgap.Delta
+	}{}
+	data.KoreOnTemp = koreonToml
+	data.Command = "prepare-airgap import"
+	data.Delta = m.Delta
+
+	// Processing template
+	koreonctlText := template.New("ImportAirgapText")
+	temp, err := koreonctlText.Parse(templates.ImportAirgapText)
+	if err != nil {
+		logger.Errorf("Template has errors. cause(%s)", err.Error())
+		return err
 	}
 
-	if len(c.privateKey) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an privateKey must be specified")
+	var buff bytes.Buffer
+	err = temp.Execute(&buff, data)
+	if err != nil {
+		logger.Errorf("Template execution failed. cause(%s)", err.Error())
+		return err
 	}
 
-	if len(c.user) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an ssh login user must be specified")
+	if !utils.CheckUserInput(buff.String(), "y") {
+		return errCanceled
 	}
 
 	b, err := json.Marshal(koreonToml)
 	if err != nil {
-		logger.Fatal(err)
-		os.Exit(1)
+		return err
 	}
 	if err := json.Unmarshal(b, &c.extravars); err != nil {
-		logger.Fatal(err.Error())
-		os.Exit(1)
+		return err
 	}
+	c.extravars["airgap_import"] = m.Delta
 
-	ansiblePlaybookConnectionOptions := &options.AnsibleConnectionOptions{
-		PrivateKey: c.privateKey,
-		User:       c.user,
+	if err := checkExternalRegistry(koreonToml, c.dryRun); err != nil {
+		return err
 	}
 
-	ansiblePlaybookOptions := &playbook.AnsiblePlaybookOptions{
-		Inventory: c.inventory,
-		Verbose:   c.verbose,
-		Tags:      c.tags,
-		ExtraVars: c.extravars,
+	task := &runner.Task{
+		Name:       "Import AirGap delta bundle",
+		Playbooks:  c.playbookFiles,
+		Inventory:  c.inventory,
+		Tags:       c.tags,
+		Verbose:    c.verbose,
+		PrivateKey: c.privateKey,
+		User:       c.user,
+		ExtraVars:  c.extravars,
 	}
 
-	playbook := &playbook.AnsiblePlaybookCmd{
-		Playbooks:         c.playbookFiles,
-		ConnectionOptions: ansiblePlaybookConnectionOptions,
-		Options:           ansiblePlaybookOptions,
-		Exec: execute.NewDefaultExecute(
-			execute.WithTransformers(
-				results.Prepend("Prepare AirGap"),
-			),
-		),
-	}
+	return runPlaybook(task, c.dryRun)
+}
 
-	options.AnsibleForceColor()
+// prepareAddonCharts - Charts of the apps to install in addon.toml that are pulled from a chart repository.
+// Chart archives (.tgz), OCI references and repositories with a login are not bundled.
+func prepareAddonCharts(k8sVersion string) ([]map[string]string, error) {
+	charts := []map[string]string{}
 
-	err = playbook.Run(context.TODO())
+	addonPath := utils.IskoreOnConfigFilePath(conf.Addon["AddonConfigFile"])
+	if !utils.FileExists(addonPath) {
+		return charts, nil
+	}
+	addonToml, err := utils.GetAddonTomlConfig(addonPath)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	appList, err := utils.AddonAppList(addonToml.Apps)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range appList {
+		app := addonToml.Apps[name]
+		if !strings.HasPrefix(app.ChartRef, "http://") && !strings.HasPrefix(app.ChartRef, "https://") ||
+			strings.HasSuffix(app.ChartRef, ".tgz") {
+			continue
+		}
+		if app.Login {
+			logger.Warnf("apps.%s > %s needs a login, the chart is not bundled", name, app.ChartRef)
+			continue
+		}
+		ver := app.ChartVersion
+		if ver == "" {
+			if ver, err = utils.AddonChartVersion(name, k8sVersion); err != nil {
+				return nil, fmt.Errorf("apps.%s: %w", name, err)
+			}
+		}
+		charts = append(charts, map[string]string{
+			"name":    name,
+			"repo":    app.ChartRef,
+			"chart":   app.ChartName,
+			"version": ver,
+		})
 	}
 
-	return nil
+	return charts, nil
 }
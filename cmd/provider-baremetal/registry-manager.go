@@ -2,21 +2,23 @@ package cmd
 
 import (
 	"bytes"
-	"context"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"kore-on/cmd/koreonctl/conf"
 	"kore-on/cmd/koreonctl/conf/templates"
+	"kore-on/pkg/harbor"
 	"kore-on/pkg/logger"
 	"kore-on/pkg/model"
+	"kore-on/pkg/runner"
 	"kore-on/pkg/utils"
 	"os"
+	"path/filepath"
+	"strings"
 	"text/template"
+	"time"
 
-	"github.com/apenella/go-ansible/pkg/execute"
-	"github.com/apenella/go-ansible/pkg/options"
-	"github.com/apenella/go-ansible/pkg/playbook"
-	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
 	"github.com/spf13/cobra"
 )
 
@@ -30,6 +32,11 @@ type strRegistryCmd struct {
 	privateKey    string
 	user          string
 	command       string
+	username      string
+	password      string
+	caFile        string
+	insecure      bool
+	backupFile    string
 	extravars     map[string]interface{}
 }
 
@@ -48,7 +55,28 @@ func RegistryCmd() *cobra.Command {
 
 	// SubCommand add
 	cmd.AddCommand(
-		emptyCmd(),
+		registryHarborCmd("project", "Create harbor projects and update their settings",
+			"This command creates the projects of [private-registry.projects] without proxy-cache and updates public and storage-limit.\n"+
+				"Projects are never deleted. Projects missing in koreon.toml are listed as unmanaged."),
+		registryHarborCmd("robot", "Create and update project robot accounts",
+			"This command creates and updates the robot accounts of [private-registry.projects.<name>.robots].\n"+
+				"The secret of a new robot account is printed once and cannot be read again."),
+		registryHarborCmd("retention", "Apply tag retention policies of projects",
+			"This command creates or replaces the tag retention policy of [private-registry.projects.<name>.retention]."),
+		registryHarborCmd("proxy-cache", "Create proxy-cache projects and their upstream registry endpoints",
+			"This command creates the upstream registry endpoint (<project>-upstream) and the proxy-cache project\n"+
+				"of [private-registry.projects.<name>.proxy-cache] and updates them."),
+		registryOperationCmd("backup", "Backup the harbor database and configuration",
+			"This command dumps the harbor database and configuration into registry/backup of the work directory.\n"+
+				"Image blobs and charts stay in the registry data directory and are not included."),
+		registryOperationCmd("restore", "Restore the harbor database and configuration",
+			"This command restores a backup of registry/backup into the installed harbor.\n"+
+				"A backup of an older harbor version is migrated to the installed version when harbor starts."),
+		registryOperationCmd("upgrade", "Upgrade harbor to the next supported version",
+			"This command upgrades the installed harbor one supported version at a time (e.g. v2.4.3 -> v2.5.4 -> v2.6.4).\n"+
+				"The database and configuration are backed up before the upgrade and restored when the upgrade fails.\n"+
+				"The database schema is migrated by harbor core when the new version starts.\n"+
+				"In the closed network copy harbor-offline-installer-<version>.tgz of the next version to the registry directory."),
 	)
 
 	// SubCommand validation
@@ -71,6 +99,66 @@ func RegistryCmd() *cobra.Command {
 	return cmd
 }
 
+func registryHarborCmd(command string, short string, long string) *cobra.Command {
+	registry := &strRegistryCmd{}
+
+	cmd := &cobra.Command{
+		Use:          command + " [flags]",
+		Short:        short,
+		Long:         long,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return registry.harbor()
+		},
+	}
+
+	registry.command = command
+
+	f := cmd.Flags()
+	f.BoolVarP(&registry.dryRun, "dry-run", "d", false, "show the changes without applying them")
+	f.StringVar(&registry.username, "username", "", "harbor admin user (default: admin or the user of the external registry)")
+	f.StringVar(&registry.caFile, "ca-file", "", "harbor CA certificate (default: ca-file of the external registry or "+conf.KoreOnRegistryCAFile+" of the installation)")
+	f.BoolVar(&registry.insecure, "insecure-skip-tls-verify", false, "skip TLS verification of the harbor certificate")
+
+	return cmd
+}
+
+func registryOperationCmd(command string, short string, long string) *cobra.Command {
+	registry := &strRegistryCmd{}
+
+	cmd := &cobra.Command{
+		Use:          command + " [flags]",
+		Short:        short,
+		Long:         long,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return registry.operation()
+		},
+	}
+
+	// Default value for command struct
+	registry.command = command
+	registry.tags = command + "-registry"
+	registry.inventory = "./internal/playbooks/koreon-playbook/inventory/inventory.ini"
+	registry.playbookFiles = []string{
+		"./internal/playbooks/koreon-playbook/registry-operation.yaml",
+	}
+
+	f := cmd.Flags()
+	f.BoolVarP(&registry.verbose, "verbose", "v", false, "verbose")
+	f.BoolVarP(&registry.dryRun, "dry-run", "d", false, "dryRun")
+	f.StringVarP(&registry.privateKey, "private-key", "p", "", "Specify ssh key path")
+	f.StringVarP(&registry.user, "user", "u", "", "login user")
+	f.StringVar(&registry.username, "username", conf.HarborAdminID, "harbor admin user")
+	f.StringVar(&registry.caFile, "ca-file", "", "harbor CA certificate (default: "+conf.KoreOnRegistryCAFile+" of the installation)")
+	f.BoolVar(&registry.insecure, "insecure-skip-tls-verify", false, "skip TLS verification of the harbor certificate")
+	if command == "restore" {
+		f.StringVar(&registry.backupFile, "backup-file", "", "backup file name in registry/backup (default: latest backup)")
+	}
+
+	return cmd
+}
+
 func RegistryUploadCmd() *cobra.Command {
 	imageUpload := &strAirGapCmd{}
 
@@ -105,10 +193,9 @@ func RegistryUploadCmd() *cobra.Command {
 func (c *strRegistryCmd) run() error {
 	koreOnConfigFileName := conf.KoreOnConfigFile
 	koreOnConfigFilePath := utils.IskoreOnConfigFilePath(koreOnConfigFileName)
-	koreonToml, errBool := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "prepare-airgap")
-	if !errBool {
-		message := "Settings are incorrect. Please check the 'korean.toml' file!!"
-		logger.Fatal(fmt.Errorf("%s", message))
+	koreonToml, err := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "prepare-airgap")
+	if err != nil {
+		return err
 	}
 
 	// koreonToml Default value
@@ -117,7 +204,7 @@ func (c *strRegistryCmd) run() error {
 	// current pocessing directory
 	dir, err := utils.Dirname("../..")
 	if err != nil {
-		logger.Fatal(err)
+		return err
 	}
 	if dir == "/build" {
 		dir = ""
@@ -146,89 +233,353 @@ func (c *strRegistryCmd) run() error {
 	}
 
 	if !utils.CheckUserInput(buff.String(), "y") {
-		fmt.Println("nothing to changed. exit")
-		os.Exit(1)
+		return errCanceled
 	}
 
-	// if c.command == "" {
-	// 	// Prompt login
-	// 	id := utils.InputPrompt("\n## To helm chart pull csi-driver-nfs, you need to login as a private repository (Helm Chart) user.\nusername:")
-	// 	pw := utils.SensitivePrompt("password:")
-	// 	koreonToml.KoreOn.HelmChartProject = viper.GetString("KoreOn.HelmChartProject")
-	// 	koreonToml.KoreOn.HelmCubeRepoID = base64.StdEncoding.EncodeToString([]byte(id))
-	// 	koreonToml.KoreOn.HelmCubeRepoPW = base64.StdEncoding.EncodeToString([]byte(pw))
+	b, err := json.Marshal(koreonToml)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &c.extravars); err != nil {
+		return err
+	}
 
-	// 	commandArgs := "helm registry login " + koreonToml.KoreOn.HelmCubeRepoUrl +
-	// 		" --username " + id +
-	// 		" --password " + pw
+	task := &runner.Task{
+		Name:       "Registry Manager",
+		Playbooks:  c.playbookFiles,
+		Inventory:  c.inventory,
+		Tags:       c.tags,
+		Verbose:    c.verbose,
+		PrivateKey: c.privateKey,
+		User:       c.user,
+		ExtraVars:  c.extravars,
+	}
 
-	// 	err = checkHelmRepoLogin(id, pw, commandArgs)
-	// 	if err != nil {
-	// 		str := fmt.Sprintf("%s", err)
-	// 		fi := strings.Index(str, "Error")
-	// 		li := strings.LastIndex(str, "\"")
-	// 		err = fmt.Errorf(str[fi : li+1])
-	// 		logger.Fatal(err)
-	// 	} else {
-	// 		fmt.Println("Login Succeeded!!")
-	// 	}
-	// }
+	return runPlaybook(task, c.dryRun)
+}
 
-	if len(c.playbookFiles) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook playbook file path must be specified")
+// harbor - Bring the harbor projects to the state of [private-registry.projects] through the Harbor API
+func (c *strRegistryCmd) harbor() error {
+	koreOnConfigFileName := conf.KoreOnConfigFile
+	koreOnConfigFilePath := utils.IskoreOnConfigFilePath(koreOnConfigFileName)
+	koreonToml, err := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "registry-manager")
+	if err != nil {
+		return err
 	}
 
-	if len(c.inventory) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an inventory must be specified")
+	address := koreonToml.PrivateRegistry.RegistryDomain
+	if address == "" {
+		address = koreonToml.PrivateRegistry.RegistryIP
+	}
+	username, password, caFile := c.username, c.password, c.caFile
+
+	// external harbor: url, credential and ca-file of [private-registry.external]
+	external := koreonToml.PrivateRegistry.External
+	if external.URL != "" {
+		address = external.URL
+		if username == "" {
+			username, password = external.Username, external.Password
+		}
+		if caFile == "" && external.CAFile != "" {
+			caFile = filepath.Join(conf.KoreOnConfigDir, filepath.Base(external.CAFile))
+		}
+	} else if caFile == "" {
+		caFile = registryCAFile()
+	}
+	if username == "" {
+		username = conf.HarborAdminID
+	}
+	if password == "" {
+		password = harborPassword()
 	}
 
-	if len(c.privateKey) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an privateKey must be specified")
+	client, err := harbor.NewClient(harbor.Options{
+		URL:      address,
+		Username: username,
+		Password: password,
+		CAFile:   caFile,
+		Insecure: c.insecure,
+	})
+	if err != nil {
+		return err
+	}
+	version, err := client.SystemInfo()
+	if err != nil {
+		return tlsHint(err)
 	}
 
-	if len(c.user) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an ssh login user must be specified")
+	projects := koreonToml.PrivateRegistry.Projects
+	var plan *harbor.Plan
+	switch c.command {
+	case "project":
+		plan, err = client.PlanProjects(projects)
+	case "robot":
+		plan, err = client.PlanRobots(projects)
+	case "retention":
+		plan, err = client.PlanRetention(projects)
+	case "proxy-cache":
+		plan, err = client.PlanProxyCache(projects)
+		if err == nil && !koreonToml.PrivateRegistry.MirrorUse {
+			logger.Warn("private-registry > mirror-use is false. Container runtimes do not pull through the proxy-cache projects.")
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Processing template
+	data := struct {
+		Command  string
+		Registry string
+		Version  string
+		Changes  []harbor.Change
+		Confirm  bool
+	}{c.command, client.URL, version, plan.Changes, plan.Pending() > 0 && !c.dryRun}
+
+	temp, err := template.New("RegistryManagerText").Parse(templates.RegistryManagerText)
+	if err != nil {
+		logger.Errorf("Template has errors. cause(%s)", err.Error())
+		return err
+	}
+	var buff bytes.Buffer
+	if err := temp.Execute(&buff, data); err != nil {
+		logger.Errorf("Template execution failed. cause(%s)", err.Error())
+		return err
+	}
+
+	if !data.Confirm {
+		fmt.Println(buff.String())
+		if c.dryRun && plan.Pending() > 0 {
+			fmt.Printf("dry-run: %d change(s) not applied\n", plan.Pending())
+		} else {
+			fmt.Println("nothing to changed. exit")
+		}
+		return nil
+	}
+	if !utils.CheckUserInput(buff.String(), "y") {
+		return errCanceled
+	}
+
+	return plan.Apply(func(ch harbor.Change, output string) {
+		logger.Infof("%s %s %s", ch.Action, ch.Kind, ch.Name)
+		if output != "" {
+			fmt.Println(output)
+		}
+	})
+}
+
+// operation - Backup, restore or upgrade the harbor installed by kore-on
+func (c *strRegistryCmd) operation() error {
+	koreOnConfigFileName := conf.KoreOnConfigFile
+	koreOnConfigFilePath := utils.IskoreOnConfigFilePath(koreOnConfigFileName)
+	koreonToml, err := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, c.tags)
+	if err != nil {
+		return err
+	}
+
+	address := koreonToml.PrivateRegistry.RegistryDomain
+	if address == "" {
+		address = koreonToml.PrivateRegistry.RegistryIP
+	}
+
+	c.password = harborPassword()
+
+	// running version of harbor. restore is also used when harbor does not start.
+	installed, err := c.harborVersion(address)
+	if err != nil {
+		if c.command != "restore" {
+			return err
+		}
+		installed = koreonToml.PrivateRegistry.RegistryVersion
+		logger.Warnf("harbor version can not be read (%s). registry-version %s of koreon.toml is used.", err.Error(), installed)
+	}
+	koreonToml.PrivateRegistry.InstalledVersion = installed
+
+	backupDir := filepath.Join(conf.KoreOnRegistryDir, "backup")
+	target := ""
+	switch c.command {
+	case "backup":
+		koreonToml.PrivateRegistry.BackupFile = registryBackupFile(installed)
+	case "restore":
+		if c.backupFile == "" {
+			if c.backupFile, err = latestRegistryBackup(backupDir); err != nil {
+				return err
+			}
+		}
+		c.backupFile = filepath.Base(c.backupFile)
+		if !utils.FileExists(filepath.Join(backupDir, c.backupFile)) {
+			return fmt.Errorf("[ERROR]: backup file %s is not found in registry/backup", c.backupFile)
+		}
+		koreonToml.PrivateRegistry.BackupFile = c.backupFile
+	case "upgrade":
+		if target, err = utils.NextSupportVersion(installed, "SupportHarborVersion"); err != nil {
+			return err
+		}
+		if target == "" {
+			fmt.Printf("harbor %s is the latest supported version. nothing to upgrade\n", installed)
+			return nil
+		}
+		installer := filepath.Join(conf.KoreOnRegistryDir, "harbor-offline-installer-"+target+".tgz")
+		if koreonToml.KoreOn.ClosedNetwork && !utils.FileExists(installer) {
+			return fmt.Errorf("[ERROR]: copy %s to the registry directory of the work directory", filepath.Base(installer))
+		}
+		koreonToml.PrivateRegistry.RegistryVersion = target
+		koreonToml.PrivateRegistry.BackupFile = registryBackupFile(installed)
+	}
+
+	data := struct {
+		Command    string
+		Registry   string
+		Version    string
+		Target     string
+		BackupFile string
+	}{c.command, address, installed, target, koreonToml.PrivateRegistry.BackupFile}
+
+	temp, err := template.New("RegistryOperationText").Parse(templates.RegistryOperationText)
+	if err != nil {
+		logger.Errorf("Template has errors. cause(%s)", err.Error())
+		return err
+	}
+	var buff bytes.Buffer
+	if err := temp.Execute(&buff, data); err != nil {
+		logger.Errorf("Template execution failed. cause(%s)", err.Error())
+		return err
+	}
+	if !utils.CheckUserInput(buff.String(), "y") {
+		return errCanceled
 	}
 
 	b, err := json.Marshal(koreonToml)
 	if err != nil {
-		logger.Fatal(err)
-		os.Exit(1)
+		return err
 	}
 	if err := json.Unmarshal(b, &c.extravars); err != nil {
-		logger.Fatal(err.Error())
-		os.Exit(1)
+		return err
 	}
 
-	ansiblePlaybookConnectionOptions := &options.AnsibleConnectionOptions{
+	task := &runner.Task{
+		Name:       "Registry Manager",
+		Playbooks:  c.playbookFiles,
+		Inventory:  c.inventory,
+		Tags:       c.tags,
+		Verbose:    c.verbose,
 		PrivateKey: c.privateKey,
 		User:       c.user,
+		ExtraVars:  c.extravars,
+	}
+	if err := runPlaybook(task, c.dryRun); err != nil {
+		return err
 	}
 
-	ansiblePlaybookOptions := &playbook.AnsiblePlaybookOptions{
-		Inventory: c.inventory,
-		Verbose:   c.verbose,
-		Tags:      c.tags,
-		ExtraVars: c.extravars,
+	if target != "" && !c.dryRun {
+		logger.Infof("harbor is upgraded to %s. Set registry-version = \"%s\" in [private-registry] of koreon.toml.", target, target)
 	}
+	return nil
+}
 
-	playbook := &playbook.AnsiblePlaybookCmd{
-		Playbooks:         c.playbookFiles,
-		ConnectionOptions: ansiblePlaybookConnectionOptions,
-		Options:           ansiblePlaybookOptions,
-		Exec: execute.NewDefaultExecute(
-			execute.WithTransformers(
-				results.Prepend("Prepare AirGap"),
-			),
-		),
+// harborVersion - Running harbor version without the build suffix (v2.6.0-4ba9d7ee -> v2.6.0)
+func (c *strRegistryCmd) harborVersion(address string) (string, error) {
+	caFile := c.caFile
+	if caFile == "" {
+		caFile = registryCAFile()
+	}
+	client, err := harbor.NewClient(harbor.Options{
+		URL:      address,
+		Username: c.username,
+		Password: c.password,
+		CAFile:   caFile,
+		Insecure: c.insecure,
+	})
+	if err != nil {
+		return "", err
 	}
+	version, err := client.SystemInfo()
+	if err != nil {
+		return "", tlsHint(err)
+	}
+	return strings.SplitN(version, "-", 2)[0], nil
+}
 
-	options.AnsibleForceColor()
+// registryCAFile - CA of the registry certificate fetched by the installation. Without it (public certificate)
+// the system roots are used.
+func registryCAFile() string {
+	caFile := filepath.Join(conf.KoreOnConfigDir, conf.KoreOnRegistryCAFile)
+	if !utils.FileExists(caFile) {
+		return ""
+	}
+	return caFile
+}
 
-	err = playbook.Run(context.TODO())
-	if err != nil {
-		return err
+// checkExternalRegistry - Connection and credentials of [private-registry.external] through the Harbor API.
+// It runs before the playbook and is skipped with --dry-run.
+func checkExternalRegistry(koreonToml model.KoreOnToml, dryRun bool) error {
+	external := koreonToml.PrivateRegistry.External
+	if external.URL == "" || dryRun {
+		return nil
 	}
 
+	caFile := ""
+	if external.CAFile != "" {
+		caFile = filepath.Join(conf.KoreOnConfigDir, filepath.Base(external.CAFile))
+	}
+	client, err := harbor.NewClient(harbor.Options{
+		URL:      external.URL,
+		Username: external.Username,
+		Password: external.Password,
+		CAFile:   caFile,
+	})
+	if err != nil {
+		return fmt.Errorf("private-registry.external > %w", err)
+	}
+	if err := client.Ping(); err != nil {
+		return fmt.Errorf("private-registry.external > registry check failed: %w", err)
+	}
 	return nil
 }
+
+// tlsHint - Add the options to an error of the certificate verification
+func tlsHint(err error) error {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid) {
+		return fmt.Errorf("%w\nGive the registry CA with --ca-file or skip the verification with --insecure-skip-tls-verify", err)
+	}
+	return err
+}
+
+// harborPassword - Harbor admin password of the environment (KOREON_HARBOR_PASSWORD) or the prompt
+func harborPassword() string {
+	if password := os.Getenv(conf.HarborPasswordEnv); password != "" {
+		return password
+	}
+	return utils.SensitivePrompt("harbor admin password:")
+}
+
+// registryBackupFile - harbor-<version>-<time>.tgz
+func registryBackupFile(version string) string {
+	return fmt.Sprintf("harbor-%s-%s.tgz", version, time.Now().Format("20060102-150405"))
+}
+
+// latestRegistryBackup - Most recent backup file name in dir
+func latestRegistryBackup(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "harbor-*.tgz"))
+	if err != nil {
+		return "", err
+	}
+	latest := ""
+	var latestTime time.Time
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		if latest == "" || info.ModTime().After(latestTime) {
+			latest, latestTime = f, info.ModTime()
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("[ERROR]: %s", "there is no backup in registry/backup. Run 'registry-manager backup' first")
+	}
+	return filepath.Base(latest), nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kore-on/pkg/logger"
+	"kore-on/pkg/runner"
+	"strings"
+	"sync"
+)
+
+// errCanceled - The confirmation was declined. The command exits with a non-zero status.
+var errCanceled = errors.New("nothing to changed. exit")
+
+var (
+	commandRunner     runner.Runner
+	commandRunnerErr  error
+	commandRunnerOnce sync.Once
+)
+
+// getRunner - Runner selected by KOREON_RUNNER, shared by every task of the command
+// so that a replay checks the tasks in the recorded order.
+func getRunner() (runner.Runner, error) {
+	commandRunnerOnce.Do(func() {
+		commandRunner, commandRunnerErr = runner.Default()
+		if commandRunnerErr == nil {
+			commandRunner.OnEvent(logRunnerEvent)
+		}
+	})
+	return commandRunner, commandRunnerErr
+}
+
+// runPlaybook - Run the task with the engine selected by KOREON_RUNNER.
+// With dryRun the planned command line is printed instead.
+// The playbook is the last task of a command, a replay fails when recorded tasks remain.
+func runPlaybook(task *runner.Task, dryRun bool) error {
+	r, err := getRunner()
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		plan, err := r.Plan(task)
+		if err != nil {
+			return err
+		}
+		fmt.Println(strings.Join(plan.Command, " "))
+		return nil
+	}
+
+	if err := r.Run(context.TODO(), task); err != nil {
+		return err
+	}
+	if replay, ok := r.(*runner.ReplayRunner); ok {
+		return replay.Done()
+	}
+	return nil
+}
+
+// runAdhoc - Run an ad-hoc task (ansible module) before the playbook of the command
+func runAdhoc(task *runner.Task) error {
+	r, err := getRunner()
+	if err != nil {
+		return err
+	}
+	return r.Run(context.TODO(), task)
+}
+
+// logRunnerEvent - Write runner events to the koreonctl log
+func logRunnerEvent(event runner.Event) {
+	name := ""
+	if event.Plan != nil && event.Plan.Task != nil {
+		name = event.Plan.Task.Name
+	}
+
+	switch event.Type {
+	case runner.EventPlanned:
+		if event.Plan.Task.Module != "" {
+			logger.Debugf("%s > planned: %s", name, event.Plan.Task.Module)
+		} else {
+			logger.Debugf("%s > planned: %s", name, strings.Join(event.Plan.Task.Playbooks, ", "))
+		}
+	case runner.EventStarted:
+		logger.Debugf("%s > started", name)
+	case runner.EventFinished:
+		logger.Debugf("%s > finished", name)
+	case runner.EventFailed:
+		logger.Errorf("%s > failed: %v", name, event.Err)
+	}
+}
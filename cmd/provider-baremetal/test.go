@@ -2,21 +2,15 @@ package cmd
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
-	"fmt"
 	"kore-on/cmd/koreonctl/conf"
 	"kore-on/cmd/koreonctl/conf/templates"
 	"kore-on/pkg/logger"
 	"kore-on/pkg/model"
+	"kore-on/pkg/runner"
 	"kore-on/pkg/utils"
-	"os"
 	"text/template"
 
-	"github.com/apenella/go-ansible/pkg/execute"
-	"github.com/apenella/go-ansible/pkg/execute/measure"
-	"github.com/apenella/go-ansible/pkg/options"
-	"github.com/apenella/go-ansible/pkg/playbook"
 	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
 	"github.com/spf13/cobra"
 )
@@ -76,18 +70,17 @@ func TestCmd() *cobra.Command {
 func (c *strTestCmd) run() error {
 	koreOnConfigFileName := conf.KoreOnConfigFile
 	koreOnConfigFilePath := utils.IskoreOnConfigFilePath(koreOnConfigFileName)
-	koreonToml, value := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "create")
+	koreonToml, err := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "create")
+	if err != nil {
+		return err
+	}
 	koreonToml.KoreOn.FileName = koreOnConfigFileName
-	if value {
-		b, err := json.Marshal(koreonToml)
-		if err != nil {
-			logger.Fatal(err)
-			os.Exit(1)
-		}
-		if err := json.Unmarshal(b, &c.extravars); err != nil {
-			logger.Fatal(err.Error())
-			os.Exit(1)
-		}
+	b, err := json.Marshal(koreonToml)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &c.extravars); err != nil {
+		return err
 	}
 
 	// Make provision data
@@ -112,64 +105,20 @@ func (c *strTestCmd) run() error {
 	}
 
 	if !utils.CheckUserInput(buff.String(), "y") {
-		fmt.Println("nothing to changed. exit")
-		os.Exit(1)
-	}
-
-	if len(c.playbookFiles) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook playbook file path must be specified")
-	}
-
-	if len(c.inventory) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an inventory must be specified")
-	}
-
-	if len(c.privateKey) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an privateKey must be specified")
-	}
-
-	if len(c.user) < 1 {
-		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an ssh login user must be specified")
-	}
-
-	ansiblePlaybookConnectionOptions := &options.AnsibleConnectionOptions{
-		PrivateKey: c.privateKey,
-		User:       c.user,
-	}
-
-	ansiblePlaybookOptions := &playbook.AnsiblePlaybookOptions{
-		Inventory: c.inventory,
-		Verbose:   c.verbose,
-		Tags:      c.tags,
-		ExtraVars: c.extravars,
-		// ExtraVars: c.result,
-	}
-
-	executorTimeMeasurement := measure.NewExecutorTimeMeasurement(
-		execute.NewDefaultExecute(
-			execute.WithEnvVar("ANSIBLE_FORCE_COLOR", "true"),
-			execute.WithTransformers(
-				utils.OutputColored(),
-				results.Prepend("cobra-cmd-ansibleplaybook example"),
-				// results.LogFormat(results.DefaultLogFormatLayout, results.Now),
-			),
-		),
-		measure.WithShowDuration(),
-	)
-
-	playbook := &playbook.AnsiblePlaybookCmd{
-		Playbooks:         c.playbookFiles,
-		ConnectionOptions: ansiblePlaybookConnectionOptions,
-		Options:           ansiblePlaybookOptions,
-		Exec:              executorTimeMeasurement,
+		return errCanceled
 	}
 
-	options.AnsibleForceColor()
-
-	err = playbook.Run(context.TODO())
-	if err != nil {
-		return err
+	task := &runner.Task{
+		Name:         "cobra-cmd-ansibleplaybook example",
+		Playbooks:    c.playbookFiles,
+		Inventory:    c.inventory,
+		Tags:         c.tags,
+		Verbose:      c.verbose,
+		PrivateKey:   c.privateKey,
+		User:         c.user,
+		ExtraVars:    c.extravars,
+		Transformers: []results.TransformerFunc{utils.OutputColored()},
 	}
 
-	return nil
+	return runPlaybook(task, c.dryRun)
 }
@@ -52,7 +52,9 @@ func (c *strInitCmd) run() error {
 	// if use check flag then validation for configfile
 	// var data map[string]interface{}
 	if c.check {
-		utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "init")
+		if _, err := utils.ValidateKoreonTomlConfig(koreOnConfigFilePath, "init"); err != nil {
+			return err
+		}
 		os.Exit(0)
 	}
 
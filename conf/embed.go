@@ -0,0 +1,14 @@
+// Package conf - Support version catalog shipped inside the binaries
+package conf
+
+import (
+	_ "embed"
+)
+
+// ===== [ Constants and Variables ] =====
+
+// ConfigYaml - Contents of conf/config.yaml at build time.
+// koreonctl runs on the bastion without the conf directory, so it reads the support maps from here.
+//
+//go:embed config.yaml
+var ConfigYaml []byte
@@ -0,0 +1,273 @@
+// Package airgap - Air gap bundle manifest (checksums and signature of the installer bundle)
+package airgap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"kore-on/pkg/model"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// ManifestFile is the manifest path relative to the bundle directory
+	ManifestFile = "archive/manifest.json"
+	// SignatureFile is the detached signature of the manifest
+	SignatureFile = ManifestFile + ".sig"
+	// FormatVersion is the manifest format written by this release
+	FormatVersion = 1
+)
+
+// BundleDirs - Bundle sub directories covered by the manifest.
+// config and logs are excluded because they are changed by the user.
+var BundleDirs = []string{"archive", "bin", "extends"}
+
+// ===== [ Types ] =====
+
+// Manifest - Contents of an air gap bundle
+type Manifest struct {
+	FormatVersion int                   `json:"format_version"`
+	KoreOnVersion string                `json:"koreon_version"`
+	CreatedAt     string                `json:"created_at"`
+	Versions      model.ResolvedVersion `json:"versions"`
+	Images        []string              `json:"images"`
+	Charts        []string              `json:"charts"`
+	Packages      []string              `json:"packages,omitempty"`
+	Delta         *Delta                `json:"delta,omitempty"`
+	Files         []File                `json:"files"`
+}
+
+// Delta - Contents added since a previous bundle (prepare-airgap --since).
+// Images, Charts and Packages of the manifest still list the full set so that the
+// next delta can be made from this manifest.
+type Delta struct {
+	Since    string       `json:"since"`
+	Images   []DeltaImage `json:"images"`
+	Charts   []string     `json:"charts"`
+	Packages []string     `json:"packages"`
+}
+
+// DeltaImage - Image archive of a delta bundle
+type DeltaImage struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// File - One file of the bundle
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Problem - Difference found between the manifest and the bundle
+type Problem struct {
+	Path   string
+	Reason string
+}
+
+// ===== [ Implementations ] =====
+
+func (p Problem) String() string {
+	return p.Path + ": " + p.Reason
+}
+
+// Save - Write the manifest to the bundle directory
+func (m *Manifest) Save(bundleDir string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(bundleDir, ManifestFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// Verify - Compare every file of the manifest with the bundle directory.
+// Files that exist in the bundle but not in the manifest are reported as well.
+func (m *Manifest) Verify(bundleDir string) ([]Problem, error) {
+	problems := []Problem{}
+	listed := map[string]bool{}
+
+	for _, f := range m.Files {
+		listed[f.Path] = true
+
+		path := filepath.Join(bundleDir, filepath.FromSlash(f.Path))
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			problems = append(problems, Problem{f.Path, "missing"})
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if info.Size() != f.Size {
+			problems = append(problems, Problem{f.Path, fmt.Sprintf("size mismatch (expected %d, got %d)", f.Size, info.Size())})
+			continue
+		}
+
+		sum, err := Checksum(path)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(sum, f.SHA256) {
+			problems = append(problems, Problem{f.Path, "sha256 mismatch"})
+		}
+	}
+
+	files, err := listFiles(bundleDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if !listed[f] {
+			problems = append(problems, Problem{f, "not listed in the manifest"})
+		}
+	}
+
+	return problems, nil
+}
+
+// ===== [ Private Functions ] =====
+
+// listFiles - Bundle files relative to the bundle directory (slash separated), without the manifest itself
+func listFiles(bundleDir string) ([]string, error) {
+	files := []string{}
+	for _, dir := range BundleDirs {
+		root := filepath.Join(bundleDir, dir)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(bundleDir, path)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if rel == ManifestFile || rel == SignatureFile {
+				return nil
+			}
+			files = append(files, rel)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ===== [ Public Functions ] =====
+
+// Checksum - SHA-256 of a file as lower case hex
+func Checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Build - Create a manifest for the bundle directory.
+// Versions, images and charts of base are kept (e.g. from the manifest written by prepare-airgap).
+func Build(bundleDir string, base *Manifest) (*Manifest, error) {
+	m := &Manifest{}
+	if base != nil {
+		*m = *base
+	}
+	m.FormatVersion = FormatVersion
+	m.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	m.Files = []File{}
+
+	files, err := listFiles(bundleDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range files {
+		path := filepath.Join(bundleDir, filepath.FromSlash(rel))
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := Checksum(path)
+		if err != nil {
+			return nil, err
+		}
+		m.Files = append(m.Files, File{Path: rel, Size: info.Size(), SHA256: sum})
+	}
+
+	return m, nil
+}
+
+// Load - Read the manifest of the bundle directory
+func Load(bundleDir string) (*Manifest, error) {
+	return LoadFile(filepath.Join(bundleDir, ManifestFile))
+}
+
+// VerifyBundle - Manifest signature and files of the bundle. A bundle without a manifest and a signed
+// bundle without the public key are errors. The signature is checked only when the bundle is signed
+// or a public key is given, the files always.
+func VerifyBundle(bundleDir string, publicKeyPath string) (*Manifest, []Problem, error) {
+	if _, err := os.Stat(filepath.Join(bundleDir, ManifestFile)); os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("air gap bundle has no manifest (%s)", ManifestFile)
+	}
+	m, err := Load(bundleDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if publicKeyPath != "" {
+		if err := VerifySignature(bundleDir, publicKeyPath); err != nil {
+			return nil, nil, err
+		}
+	} else if IsSigned(bundleDir) {
+		return nil, nil, ErrNoPublicKey
+	}
+
+	problems, err := m.Verify(bundleDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, problems, nil
+}
+
+// LoadFile - Read a manifest file (e.g. the manifest of a previous bundle)
+func LoadFile(path string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	if m.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("%s: format version %d is newer than supported version %d", path, m.FormatVersion, FormatVersion)
+	}
+	return m, nil
+}
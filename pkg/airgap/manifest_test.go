@@ -0,0 +1,227 @@
+package airgap
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"kore-on/pkg/model"
+)
+
+// testBundle - Bundle directory with a few files of archive, bin and extends, and its manifest
+func testBundle(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"archive/koreon/koreon.tar":  "koreon image",
+		"archive/charts/metallb.tgz": "metallb chart",
+		"bin/helm":                   "helm binary",
+		"extends/images/calico.tar":  "calico image",
+		"config/koreon.toml":         "not in the manifest",
+		"logs/koreonctl.log":         "not in the manifest",
+	}
+	for path, content := range files {
+		writeFile(t, filepath.Join(dir, path), content)
+	}
+
+	m, err := Build(dir, &Manifest{Versions: model.ResolvedVersion{Kubernetes: "v1.24.10"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testKey - Key pair in a temporary directory
+func testKey(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	priv, pub := filepath.Join(dir, "bundle-sign.key"), filepath.Join(dir, "bundle-sign.pub")
+	if err := GenerateKey(priv, pub); err != nil {
+		t.Fatal(err)
+	}
+	return priv, pub
+}
+
+func TestBuildListsBundleFiles(t *testing.T) {
+	dir := testBundle(t)
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{}
+	for _, f := range m.Files {
+		paths = append(paths, f.Path)
+	}
+	want := []string{"archive/charts/metallb.tgz", "archive/koreon/koreon.tar", "bin/helm", "extends/images/calico.tar"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("files = %v, want %v", paths, want)
+	}
+	if m.Versions.Kubernetes != "v1.24.10" || m.FormatVersion != FormatVersion {
+		t.Errorf("manifest = %+v, want the versions of the base", m)
+	}
+}
+
+func TestVerifyProblems(t *testing.T) {
+	cases := []struct {
+		name    string
+		change  func(dir string)
+		problem string
+	}{
+		{"unchanged", func(dir string) {}, ""},
+		// same size, other content
+		{"hash mismatch", func(dir string) { writeFile(t, filepath.Join(dir, "bin/helm"), "HELM BINARY") }, "bin/helm: sha256 mismatch"},
+		{"size mismatch", func(dir string) { writeFile(t, filepath.Join(dir, "bin/helm"), "helm") }, "bin/helm: size mismatch (expected 11, got 4)"},
+		{"missing", func(dir string) { os.Remove(filepath.Join(dir, "extends/images/calico.tar")) }, "extends/images/calico.tar: missing"},
+		{"not listed", func(dir string) { writeFile(t, filepath.Join(dir, "archive/extra.tar"), "extra") }, "archive/extra.tar: not listed in the manifest"},
+		{"user files", func(dir string) { writeFile(t, filepath.Join(dir, "config/addon.toml"), "changed") }, ""},
+	}
+	for _, c := range cases {
+		dir := testBundle(t)
+		c.change(dir)
+
+		_, problems, err := VerifyBundle(dir, "")
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		got := []string{}
+		for _, p := range problems {
+			got = append(got, p.String())
+		}
+		switch {
+		case c.problem == "" && len(got) > 0:
+			t.Errorf("%s: problems = %v, want none", c.name, got)
+		case c.problem != "" && (len(got) != 1 || got[0] != c.problem):
+			t.Errorf("%s: problems = %v, want [%s]", c.name, got, c.problem)
+		}
+	}
+}
+
+func TestVerifyBundleSignature(t *testing.T) {
+	priv, pub := testKey(t)
+	_, otherPub := testKey(t)
+
+	cases := []struct {
+		name      string
+		sign      bool
+		change    func(dir string)
+		publicKey string
+		err       string
+	}{
+		{name: "signed", sign: true, publicKey: pub},
+		{name: "unsigned without key"},
+		{name: "no manifest", change: func(dir string) { os.Remove(filepath.Join(dir, ManifestFile)) }, err: "has no manifest"},
+		{name: "signed without key", sign: true, err: ErrNoPublicKey.Error()},
+		{name: "key without signature", publicKey: pub, err: ErrNotSigned.Error()},
+		{name: "wrong key", sign: true, publicKey: otherPub, err: "signature does not match the manifest"},
+		{
+			name: "tampered manifest",
+			sign: true,
+			change: func(dir string) {
+				// the file and its sum in the manifest are both replaced
+				writeFile(t, filepath.Join(dir, "bin/helm"), "HELM BINARY")
+				m, err := Build(dir, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := m.Save(dir); err != nil {
+					t.Fatal(err)
+				}
+			},
+			publicKey: pub,
+			err:       "signature does not match the manifest",
+		},
+	}
+	for _, c := range cases {
+		dir := testBundle(t)
+		if c.sign {
+			if err := Sign(dir, priv); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if c.change != nil {
+			c.change(dir)
+		}
+
+		_, problems, err := VerifyBundle(dir, c.publicKey)
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%s: VerifyBundle() = %v, want nil", c.name, err)
+		case c.err == "" && len(problems) > 0:
+			t.Errorf("%s: problems = %v, want none", c.name, problems)
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			t.Errorf("%s: VerifyBundle() = %v, want an error with %q", c.name, err, c.err)
+		}
+	}
+}
+
+func TestDeltaManifest(t *testing.T) {
+	dir := testBundle(t)
+	since, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the delta bundle keeps the full image list and adds the new images
+	writeFile(t, filepath.Join(dir, "extends/images/cilium.tar"), "cilium image")
+	base := *since
+	base.Images = []string{"docker.io/calico/node:v3.25.1", "quay.io/cilium/cilium:v1.13.2"}
+	base.Delta = &Delta{
+		Since:    since.CreatedAt,
+		Images:   []DeltaImage{{Name: "quay.io/cilium/cilium:v1.13.2", Path: "extends/images/cilium.tar"}},
+		Charts:   []string{},
+		Packages: []string{},
+	}
+	m, err := Build(dir, &base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, problems, err := VerifyBundle(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("problems = %v, want none", problems)
+	}
+	if loaded.Delta == nil || !reflect.DeepEqual(loaded.Delta.Images, base.Delta.Images) {
+		t.Errorf("delta = %+v, want %+v", loaded.Delta, base.Delta)
+	}
+	if !reflect.DeepEqual(loaded.Images, base.Images) {
+		t.Errorf("images = %v, want the full list %v", loaded.Images, base.Images)
+	}
+	if len(loaded.Files) != len(since.Files)+1 {
+		t.Errorf("files = %d, want %d", len(loaded.Files), len(since.Files)+1)
+	}
+}
+
+func TestLoadNewerFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	writeFile(t, path, `{"format_version": 2}`)
+	if _, err := LoadFile(path); err == nil || !strings.Contains(err.Error(), "newer than supported") {
+		t.Errorf("LoadFile() = %v, want a format version error", err)
+	}
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "none.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadFile() of a missing file = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,154 @@
+package airgap
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ===== [ Constants and Variables ] =====
+
+var (
+	// ErrNotSigned is returned when a public key is given but the bundle has no signature
+	ErrNotSigned = errors.New("bundle manifest is not signed")
+	// ErrNoPublicKey is returned when the bundle is signed but no public key is given to check it
+	ErrNoPublicKey = errors.New("bundle manifest is signed, a public key is required to check the signature")
+)
+
+// ===== [ Private Functions ] =====
+
+func readPEM(path string, blockType string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s: %s PEM block not found", path, blockType)
+	}
+	return block.Bytes, nil
+}
+
+func loadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 private key", path)
+	}
+	return priv, nil
+}
+
+func loadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 public key", path)
+	}
+	return pub, nil
+}
+
+// ===== [ Public Functions ] =====
+
+// GenerateKey - Create an ed25519 key pair in PEM files (private key 0600)
+func GenerateKey(privateKeyPath string, publicKeyPath string) error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	privDer, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return err
+	}
+	pubDer, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range []string{privateKeyPath, publicKeyPath} {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+	}
+
+	if err := ioutil.WriteFile(privateKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDer}), 0600); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer}), 0644)
+}
+
+// Sign - Write the detached signature of the bundle manifest
+func Sign(bundleDir string, privateKeyPath string) error {
+	priv, err := loadPrivateKey(privateKeyPath)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := ioutil.ReadFile(filepath.Join(bundleDir, ManifestFile))
+	if err != nil {
+		return err
+	}
+
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, manifest))
+	return ioutil.WriteFile(filepath.Join(bundleDir, SignatureFile), []byte(sig+"\n"), 0644)
+}
+
+// VerifySignature - Check the manifest signature with the public key
+func VerifySignature(bundleDir string, publicKeyPath string) error {
+	pub, err := loadPublicKey(publicKeyPath)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := ioutil.ReadFile(filepath.Join(bundleDir, ManifestFile))
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(bundleDir, SignatureFile))
+	if os.IsNotExist(err) {
+		return ErrNotSigned
+	} else if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return fmt.Errorf("%s: %s", SignatureFile, err.Error())
+	}
+
+	if !ed25519.Verify(pub, manifest, sig) {
+		return fmt.Errorf("%s: signature does not match the manifest", SignatureFile)
+	}
+	return nil
+}
+
+// IsSigned - Whether the bundle has a manifest signature
+func IsSigned(bundleDir string) bool {
+	_, err := os.Stat(filepath.Join(bundleDir, SignatureFile))
+	return err == nil
+}
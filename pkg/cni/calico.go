@@ -0,0 +1,279 @@
+package cni
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"strings"
+
+	"kore-on/pkg/model"
+	"kore-on/pkg/network"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// calicoDefaultPool, calicoDefaultPoolIPv6 - IP pools of pod-cidr created by calico-node
+	calicoDefaultPool     = "default-ipv4-ippool"
+	calicoDefaultPoolIPv6 = "default-ipv6-ippool"
+
+	calicoDefaultBlockSize     = 26
+	calicoDefaultBlockSizeIPv6 = 122
+	// calicoIPv6VxlanVersion - VXLAN of the IPv6 pools
+	calicoIPv6VxlanVersion = "v3.23"
+	calicoDefaultAsNumber  = 64512
+	calicoMinMtu           = 1280
+	calicoMaxMtu           = 9000
+)
+
+var (
+	calicoEncapsulations = []string{"ipip", "vxlan", "none"}
+	calicoNameRegex      = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// ===== [ Implements ] =====
+
+// Validate - [kubernetes.calico]. The network resources (ip-pools, bgp) are applied by the
+// ansible role cni/calico on create and on cluster update.
+func (calico) Validate(k *model.KoreOnToml) []error {
+	errs := checkOtherSections(k)
+	c := &k.Kubernetes.Calico
+
+	// vxlan-mode is the encapsulation of the earlier versions
+	if c.VxlanMode {
+		if c.Encapsulation == "" {
+			c.Encapsulation = "vxlan"
+		} else if c.Encapsulation != "vxlan" {
+			errs = append(errs, fmt.Errorf("vxlan-mode can not be used with encapsulation %q", c.Encapsulation))
+		}
+	}
+	if c.Encapsulation == "" {
+		c.Encapsulation = "ipip"
+	}
+	if !contains(calicoEncapsulations, c.Encapsulation) {
+		errs = append(errs, fmt.Errorf("encapsulation %q is not supported (%s)", c.Encapsulation, strings.Join(calicoEncapsulations, ", ")))
+	}
+	if c.CrossSubnet && c.Encapsulation == "none" {
+		errs = append(errs, fmt.Errorf("cross-subnet needs the encapsulation ipip or vxlan"))
+	}
+	// calico_backend of the manifests (vxlan: no BGP)
+	c.VxlanMode = c.Encapsulation == "vxlan"
+
+	if c.Mtu != 0 && (c.Mtu < calicoMinMtu || c.Mtu > calicoMaxMtu) {
+		errs = append(errs, fmt.Errorf("mtu %d must be between %d and %d (0: auto-detect)", c.Mtu, calicoMinMtu, calicoMaxMtu))
+	}
+
+	if err := checkAutodetectionMethod("ip-autodetection-method", c.IPAutodetectionMethod); err != nil {
+		errs = append(errs, err)
+	}
+
+	// the errors of pod-cidr are of [kubernetes] (network.Validate)
+	pod, _ := network.PodCidrs(k)
+	if pod.DualStack() {
+		if err := checkAutodetectionMethod("ip6-autodetection-method", c.IP6AutodetectionMethod); err != nil {
+			errs = append(errs, err)
+		}
+		if c.Encapsulation == "vxlan" && !versionAtLeast(k.SupportVersion.ImageVersion.Calico, calicoIPv6VxlanVersion) {
+			errs = append(errs, fmt.Errorf("the vxlan encapsulation of dual-stack needs calico %s or later (%s)", calicoIPv6VxlanVersion, k.SupportVersion.ImageVersion.Calico))
+		}
+	} else if c.IP6AutodetectionMethod != "" {
+		errs = append(errs, fmt.Errorf("ip6-autodetection-method needs the dual-stack pod-cidr"))
+	}
+	errs = append(errs, checkIPPools(c.IPPools, pod, c.Encapsulation)...)
+	errs = append(errs, checkBgp(&c.Bgp, c.Encapsulation)...)
+	return errs
+}
+
+// ===== [ Private Functions ] =====
+
+// calicoSet - [kubernetes.calico] has a value
+func calicoSet(k *model.KoreOnToml) bool {
+	c := k.Kubernetes.Calico
+	return c.VxlanMode || c.Encapsulation != "" || c.CrossSubnet || c.Mtu != 0 || c.IPAutodetectionMethod != "" ||
+		c.IP6AutodetectionMethod != "" || len(c.IPPools) > 0 || c.Bgp.AsNumber != 0 || c.Bgp.DisableNodeMesh || len(c.Bgp.Peers) > 0
+}
+
+// checkAutodetectionMethod - IP_AUTODETECTION_METHOD, IP6_AUTODETECTION_METHOD of calico-node ("": default of the manifest)
+func checkAutodetectionMethod(name string, method string) error {
+	switch method {
+	case "", "first-found", "kubernetes-internal-ip":
+		return nil
+	}
+
+	key, value, ok := strings.Cut(method, "=")
+	if !ok || value == "" {
+		return fmt.Errorf("%s %q is not supported (first-found, kubernetes-internal-ip, can-reach=, interface=, skip-interface=, cidr=)", name, method)
+	}
+	switch key {
+	case "can-reach":
+		return nil
+	case "interface", "skip-interface":
+		for _, r := range strings.Split(value, ",") {
+			if _, err := regexp.Compile(r); err != nil {
+				return fmt.Errorf("%s %q: %s", name, method, err)
+			}
+		}
+		return nil
+	case "cidr":
+		for _, cidr := range strings.Split(value, ",") {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("%s %q: %q is not a cidr", name, method, cidr)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%s %q is not supported (first-found, kubernetes-internal-ip, can-reach=, interface=, skip-interface=, cidr=)", name, method)
+}
+
+// checkIPPools - [[kubernetes.calico.ip-pools]] are in the pod-cidr of the address family and do not overlap.
+// Dual-stack needs the pools of both families. The defaults of the pools are set.
+func checkIPPools(pools []model.CalicoIPPool, pod network.Cidrs, encapsulation string) []error {
+	errs := []error{}
+	if len(pools) == 0 || pod.IPv4 == nil {
+		return errs
+	}
+
+	names := map[string]bool{}
+	families := map[string]bool{}
+	nets := []*net.IPNet{}
+	for i := range pools {
+		p := &pools[i]
+		name := fmt.Sprintf("ip-pools[%d]", i)
+
+		switch {
+		case p.Name == "":
+			errs = append(errs, fmt.Errorf("%s: name is required", name))
+		case !calicoNameRegex.MatchString(p.Name):
+			errs = append(errs, fmt.Errorf("%s: name %q must be a lowercase RFC 1123 subdomain", name, p.Name))
+		case p.Name == calicoDefaultPool || p.Name == calicoDefaultPoolIPv6:
+			errs = append(errs, fmt.Errorf("%s: name %q is the pool of pod-cidr", name, p.Name))
+		case names[p.Name]:
+			errs = append(errs, fmt.Errorf("%s: name %q is duplicated", name, p.Name))
+		}
+		names[p.Name] = true
+
+		_, ipNet, err := net.ParseCIDR(p.Cidr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: cidr %q is not a cidr", name, p.Cidr))
+			nets = append(nets, nil)
+			continue
+		}
+		family := network.Family(ipNet.IP)
+		families[family] = true
+
+		podNet := pod.Get(family)
+		ones, _ := ipNet.Mask.Size()
+		if podNet == nil {
+			errs = append(errs, fmt.Errorf("%s: cidr %s is %s, but the pod-cidr has no %s cidr", name, p.Cidr, family, family))
+		} else if podOnes, _ := podNet.Mask.Size(); !podNet.Contains(ipNet.IP) || ones < podOnes {
+			errs = append(errs, fmt.Errorf("%s: cidr %s is not in the pod-cidr %s", name, p.Cidr, podNet))
+		}
+		for j, n := range nets {
+			if n != nil && (n.Contains(ipNet.IP) || ipNet.Contains(n.IP)) {
+				errs = append(errs, fmt.Errorf("%s: cidr %s overlaps ip-pools[%d] %s", name, p.Cidr, j, n))
+			}
+		}
+		p.Cidr = ipNet.String()
+		nets = append(nets, ipNet)
+
+		minBlock, maxBlock, defaultBlock := 20, 32, calicoDefaultBlockSize
+		if family == network.IPv6 {
+			minBlock, maxBlock, defaultBlock = 116, 128, calicoDefaultBlockSizeIPv6
+		}
+		if p.BlockSize == 0 {
+			p.BlockSize = defaultBlock
+		}
+		if p.BlockSize < minBlock || p.BlockSize > maxBlock {
+			errs = append(errs, fmt.Errorf("%s: block-size %d of the %s pool must be between %d and %d", name, p.BlockSize, family, minBlock, maxBlock))
+		}
+		if p.BlockSize < ones {
+			errs = append(errs, fmt.Errorf("%s: block-size %d is larger than the cidr %s", name, p.BlockSize, p.Cidr))
+		}
+
+		if p.NodeSelector == "" {
+			p.NodeSelector = "all()"
+		}
+
+		if p.Encapsulation == "" {
+			p.Encapsulation = encapsulation
+			// IPIP is IPv4 only, the IPv6 pools are routed by BGP
+			if family == network.IPv6 && encapsulation == "ipip" {
+				p.Encapsulation = "none"
+			}
+		}
+		switch {
+		case !contains(calicoEncapsulations, p.Encapsulation):
+			errs = append(errs, fmt.Errorf("%s: encapsulation %q is not supported (%s)", name, p.Encapsulation, strings.Join(calicoEncapsulations, ", ")))
+		case family == network.IPv6 && p.Encapsulation == "ipip":
+			errs = append(errs, fmt.Errorf("%s: encapsulation ipip is not supported on the IPv6 pool (vxlan, none)", name))
+		case encapsulation == "vxlan" && p.Encapsulation != "vxlan":
+			// without BGP (calico_backend vxlan) the routes of ipip and unencapsulated pools are not distributed
+			errs = append(errs, fmt.Errorf("%s: encapsulation %q needs BGP, but the encapsulation of [kubernetes.calico] is vxlan", name, p.Encapsulation))
+		}
+	}
+
+	if pod.DualStack() && (!families[network.IPv4] || !families[network.IPv6]) {
+		errs = append(errs, fmt.Errorf("ip-pools of dual-stack need the IPv4 and IPv6 pools"))
+	}
+	return errs
+}
+
+// checkBgp - [kubernetes.calico.bgp]. BGP is served by bird, not used with the vxlan encapsulation.
+func checkBgp(b *model.CalicoBgp, encapsulation string) []error {
+	errs := []error{}
+	if encapsulation == "vxlan" && (b.AsNumber != 0 || b.DisableNodeMesh || len(b.Peers) > 0) {
+		return []error{fmt.Errorf("bgp needs the encapsulation ipip or none (vxlan does not run BGP)")}
+	}
+
+	if b.AsNumber == 0 {
+		b.AsNumber = calicoDefaultAsNumber
+	}
+	if !validAsNumber(b.AsNumber) {
+		errs = append(errs, fmt.Errorf("bgp > as-number %d must be between 1 and 4294967295", b.AsNumber))
+	}
+	if b.DisableNodeMesh && len(b.Peers) == 0 {
+		errs = append(errs, fmt.Errorf("bgp > disable-node-mesh needs peers, the nodes would not have routes to the pods"))
+	}
+
+	names := map[string]bool{}
+	for i := range b.Peers {
+		p := &b.Peers[i]
+		name := fmt.Sprintf("bgp > peers[%d]", i)
+
+		ip := net.ParseIP(p.PeerIP)
+		if ip == nil {
+			errs = append(errs, fmt.Errorf("%s: peer-ip %q is not an ip address", name, p.PeerIP))
+		}
+		if !validAsNumber(p.AsNumber) {
+			errs = append(errs, fmt.Errorf("%s: as-number %d must be between 1 and 4294967295", name, p.AsNumber))
+		}
+
+		if p.Name == "" && ip != nil {
+			p.Name = "peer-" + strings.NewReplacer(".", "-", ":", "-").Replace(ip.String())
+		}
+		switch {
+		case p.Name == "":
+		case !calicoNameRegex.MatchString(p.Name):
+			errs = append(errs, fmt.Errorf("%s: name %q must be a lowercase RFC 1123 subdomain", name, p.Name))
+		case names[p.Name]:
+			errs = append(errs, fmt.Errorf("%s: name %q is duplicated (set the name of the peers with the same peer-ip)", name, p.Name))
+		}
+		names[p.Name] = true
+	}
+	return errs
+}
+
+// validAsNumber - 4 byte AS number
+func validAsNumber(as int) bool {
+	return as >= 1 && int64(as) <= math.MaxUint32
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
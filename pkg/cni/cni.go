@@ -0,0 +1,141 @@
+// Package cni - Network plugins of [kubernetes] cni
+package cni
+
+import (
+	"fmt"
+
+	"kore-on/pkg/model"
+	"kore-on/pkg/network"
+	"kore-on/pkg/version"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// TypeCalico installs calico (default)
+	TypeCalico = "calico"
+	// TypeCilium installs cilium, optionally replacing kube-proxy with eBPF
+	TypeCilium = "cilium"
+	// TypeFlannel installs flannel for small clusters
+	TypeFlannel = "flannel"
+
+	// nodeCidrMaskSize - Pod cidr of a node allocated by kube-controller-manager (--node-cidr-mask-size)
+	nodeCidrMaskSize = 24
+)
+
+// ===== [ Types ] =====
+
+// Plugin - Network plugin. Install and upgrade are the ansible role cni/<type>
+// (calico: the manifests of the master role and the network resources of cni/calico).
+type Plugin interface {
+	// Validate - [kubernetes.<cni>] of the plugin. The defaults of the plugin are set.
+	Validate(k *model.KoreOnToml) []error
+}
+
+type calico struct{}
+
+type cilium struct{}
+
+type flannel struct{}
+
+// ===== [ Implements ] =====
+
+func (cilium) Validate(k *model.KoreOnToml) []error {
+	errs := checkOtherSections(k)
+	c := &k.Kubernetes.Cilium
+
+	switch c.Tunnel {
+	case "":
+		c.Tunnel = "vxlan"
+	case "vxlan", "geneve", "disabled":
+	default:
+		errs = append(errs, fmt.Errorf("tunnel %q is not supported (vxlan, geneve, disabled)", c.Tunnel))
+	}
+
+	// kube-proxy is not deployed with the kubeadm v1beta3 skipPhases (kubernetes v1.24 or later)
+	if c.KubeProxyReplacement && !versionAtLeast(k.Kubernetes.Version, "v1.24") {
+		errs = append(errs, fmt.Errorf("kube-proxy-replacement needs kubernetes v1.24 or later (%s)", k.Kubernetes.Version))
+	}
+
+	if k.SupportVersion.ImageVersion.Cilium == "" {
+		errs = append(errs, fmt.Errorf("cilium is not supported on kubernetes %s", k.Kubernetes.Version))
+	}
+	return append(errs, checkNodeCidr(k)...)
+}
+
+func (flannel) Validate(k *model.KoreOnToml) []error {
+	errs := checkOtherSections(k)
+	f := &k.Kubernetes.Flannel
+
+	switch f.Backend {
+	case "":
+		f.Backend = "vxlan"
+	case "vxlan", "host-gw", "wireguard":
+	default:
+		errs = append(errs, fmt.Errorf("backend %q is not supported (vxlan, host-gw, wireguard)", f.Backend))
+	}
+
+	if k.SupportVersion.ImageVersion.Flannel == "" {
+		errs = append(errs, fmt.Errorf("flannel is not supported on kubernetes %s", k.Kubernetes.Version))
+	}
+	return append(errs, checkNodeCidr(k)...)
+}
+
+// ===== [ Private Functions ] =====
+
+// checkOtherSections - [kubernetes.<cni>] of the plugins not selected are not applied
+func checkOtherSections(k *model.KoreOnToml) []error {
+	errs := []error{}
+	cni := k.Kubernetes.Cni
+	if cni != TypeCalico && calicoSet(k) {
+		errs = append(errs, fmt.Errorf("[kubernetes.calico] is set, but the cni is %s", cni))
+	}
+	if cni != TypeCilium && (k.Kubernetes.Cilium.KubeProxyReplacement || k.Kubernetes.Cilium.Tunnel != "") {
+		errs = append(errs, fmt.Errorf("[kubernetes.cilium] is set, but the cni is %s", cni))
+	}
+	if cni != TypeFlannel && k.Kubernetes.Flannel.Backend != "" {
+		errs = append(errs, fmt.Errorf("[kubernetes.flannel] is set, but the cni is %s", cni))
+	}
+	return errs
+}
+
+// checkNodeCidr - cilium (ipam kubernetes) and flannel use the pod cidr of the node,
+// the IPv4 pod cidr must hold more than one node cidr
+func checkNodeCidr(k *model.KoreOnToml) []error {
+	if k.Kubernetes.PodCidr == "" {
+		return nil
+	}
+	// the errors of pod-cidr are of [kubernetes] (network.Validate)
+	pod, errs := network.PodCidrs(k)
+	if len(errs) > 0 || pod.IPv4 == nil {
+		return nil
+	}
+	if ones, _ := pod.IPv4.Mask.Size(); ones >= nodeCidrMaskSize {
+		return []error{fmt.Errorf("pod-cidr %s must be larger than the /%d pod cidr of a node", pod.IPv4, nodeCidrMaskSize)}
+	}
+	return nil
+}
+
+func versionAtLeast(v string, min string) bool {
+	cur, err := version.Parse(v)
+	if err != nil {
+		return false
+	}
+	m, _ := version.Parse(min)
+	return cur.Compare(m) >= 0
+}
+
+// ===== [ Public Functions ] =====
+
+// Get - Plugin of the cni ("" is calico)
+func Get(cni string) (Plugin, error) {
+	switch cni {
+	case "", TypeCalico:
+		return calico{}, nil
+	case TypeCilium:
+		return cilium{}, nil
+	case TypeFlannel:
+		return flannel{}, nil
+	}
+	return nil, fmt.Errorf("cni %q is not supported (%s, %s, %s)", cni, TypeCalico, TypeCilium, TypeFlannel)
+}
@@ -0,0 +1,204 @@
+package cni
+
+import (
+	"strings"
+	"testing"
+
+	"kore-on/pkg/model"
+)
+
+func testToml(cni string) *model.KoreOnToml {
+	k := &model.KoreOnToml{}
+	k.Kubernetes.Version = "v1.24.10"
+	k.Kubernetes.Cni = cni
+	k.SupportVersion.ImageVersion.Calico = "v3.25.1"
+	k.SupportVersion.ImageVersion.Cilium = "v1.13.2"
+	k.SupportVersion.ImageVersion.Flannel = "v0.21.4"
+	return k
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		cni    string
+		change func(k *model.KoreOnToml)
+		err    string
+	}{
+		{name: "calico", cni: ""},
+		{name: "cilium", cni: TypeCilium, change: func(k *model.KoreOnToml) { k.Kubernetes.Cilium.KubeProxyReplacement = true }},
+		{name: "flannel", cni: TypeFlannel, change: func(k *model.KoreOnToml) { k.Kubernetes.PodCidr = "10.4.0.0/16" }},
+		{
+			name:   "calico section of cilium",
+			cni:    TypeCilium,
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Calico.Mtu = 1450 },
+			err:    "[kubernetes.calico] is set, but the cni is cilium",
+		},
+		{
+			name:   "flannel section of calico",
+			cni:    TypeCalico,
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Flannel.Backend = "host-gw" },
+			err:    "[kubernetes.flannel] is set, but the cni is calico",
+		},
+		{
+			name:   "cilium tunnel",
+			cni:    TypeCilium,
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Cilium.Tunnel = "ipip" },
+			err:    `tunnel "ipip" is not supported`,
+		},
+		{
+			name: "cilium kube-proxy-replacement",
+			cni:  TypeCilium,
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Version = "v1.23.17"
+				k.Kubernetes.Cilium.KubeProxyReplacement = true
+			},
+			err: "kube-proxy-replacement needs kubernetes v1.24 or later (v1.23.17)",
+		},
+		{
+			name:   "cilium not supported",
+			cni:    TypeCilium,
+			change: func(k *model.KoreOnToml) { k.SupportVersion.ImageVersion.Cilium = "" },
+			err:    "cilium is not supported on kubernetes v1.24.10",
+		},
+		{
+			name:   "flannel backend",
+			cni:    TypeFlannel,
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Flannel.Backend = "udp" },
+			err:    `backend "udp" is not supported`,
+		},
+		{
+			name:   "node cidr",
+			cni:    TypeFlannel,
+			change: func(k *model.KoreOnToml) { k.Kubernetes.PodCidr = "10.4.0.0/24" },
+			err:    "pod-cidr 10.4.0.0/24 must be larger than the /24 pod cidr of a node",
+		},
+		// the errors of pod-cidr are of [kubernetes] (network.Validate)
+		{name: "invalid pod-cidr", cni: TypeCilium, change: func(k *model.KoreOnToml) { k.Kubernetes.PodCidr = "10.4.0.0" }},
+		{name: "dual-stack pod-cidr", cni: TypeCilium, change: func(k *model.KoreOnToml) { k.Kubernetes.PodCidr = "10.4.0.0/16,fd00:10:4::/56" }},
+		{
+			name:   "calico encapsulation",
+			cni:    TypeCalico,
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Calico.Encapsulation = "gre" },
+			err:    `encapsulation "gre" is not supported (ipip, vxlan, none)`,
+		},
+		{
+			name: "calico vxlan-mode",
+			cni:  TypeCalico,
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Calico.VxlanMode = true
+				k.Kubernetes.Calico.Encapsulation = "ipip"
+			},
+			err: `vxlan-mode can not be used with encapsulation "ipip"`,
+		},
+		{
+			name:   "calico mtu",
+			cni:    TypeCalico,
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Calico.Mtu = 9500 },
+			err:    "mtu 9500 must be between 1280 and 9000",
+		},
+		{
+			name:   "calico ip-autodetection-method",
+			cni:    TypeCalico,
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Calico.IPAutodetectionMethod = "cidr=10.0.0.0" },
+			err:    `ip-autodetection-method "cidr=10.0.0.0": "10.0.0.0" is not a cidr`,
+		},
+		{
+			name:   "calico ip6-autodetection-method",
+			cni:    TypeCalico,
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Calico.IP6AutodetectionMethod = "first-found" },
+			err:    "ip6-autodetection-method needs the dual-stack pod-cidr",
+		},
+		{
+			name: "calico ip-pools",
+			cni:  TypeCalico,
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.PodCidr = "10.4.0.0/16"
+				k.Kubernetes.Calico.IPPools = []model.CalicoIPPool{{Name: "zone-a", Cidr: "10.5.0.0/24"}}
+			},
+			err: "ip-pools[0]: cidr 10.5.0.0/24 is not in the pod-cidr 10.4.0.0/16",
+		},
+		{
+			name: "calico bgp of vxlan",
+			cni:  TypeCalico,
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Calico.Encapsulation = "vxlan"
+				k.Kubernetes.Calico.Bgp.AsNumber = 64513
+			},
+			err: "bgp needs the encapsulation ipip or none",
+		},
+		{
+			name:   "calico disable-node-mesh",
+			cni:    TypeCalico,
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Calico.Bgp.DisableNodeMesh = true },
+			err:    "bgp > disable-node-mesh needs peers",
+		},
+	}
+	for _, c := range cases {
+		k := testToml(c.cni)
+		if c.change != nil {
+			c.change(k)
+		}
+		plugin, err := Get(c.cni)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		errs := []string{}
+		for _, err := range plugin.Validate(k) {
+			errs = append(errs, err.Error())
+		}
+		switch {
+		case c.err == "" && len(errs) > 0:
+			t.Errorf("%s: Validate() = %v, want no errors", c.name, errs)
+		case c.err != "" && (len(errs) != 1 || !strings.Contains(errs[0], c.err)):
+			t.Errorf("%s: Validate() = %v, want one error with %q", c.name, errs, c.err)
+		}
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	k := testToml(TypeCalico)
+	k.Kubernetes.PodCidr = "10.4.0.0/16,fd00:10:4::/56"
+	k.Kubernetes.Calico.IPPools = []model.CalicoIPPool{
+		{Name: "zone-a", Cidr: "10.4.0.0/24"},
+		{Name: "zone-a-v6", Cidr: "fd00:10:4::/64"},
+	}
+	k.Kubernetes.Calico.Bgp.Peers = []model.CalicoBgpPeer{{PeerIP: "192.168.77.1", AsNumber: 64513}}
+	plugin, _ := Get(TypeCalico)
+	if errs := plugin.Validate(k); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	c := k.Kubernetes.Calico
+	if c.Encapsulation != "ipip" || c.VxlanMode || c.Bgp.AsNumber != calicoDefaultAsNumber {
+		t.Errorf("calico = %+v, want ipip and the default as-number", c)
+	}
+	// IPIP is IPv4 only, the IPv6 pool is routed by BGP
+	if p := c.IPPools[0]; p.BlockSize != calicoDefaultBlockSize || p.Encapsulation != "ipip" || p.NodeSelector != "all()" {
+		t.Errorf("ip-pools[0] = %+v, want the defaults of the IPv4 pool", p)
+	}
+	if p := c.IPPools[1]; p.BlockSize != calicoDefaultBlockSizeIPv6 || p.Encapsulation != "none" {
+		t.Errorf("ip-pools[1] = %+v, want the defaults of the IPv6 pool", p)
+	}
+	if name := c.Bgp.Peers[0].Name; name != "peer-192-168-77-1" {
+		t.Errorf("peers[0] name = %s, want peer-192-168-77-1", name)
+	}
+
+	k = testToml(TypeCilium)
+	plugin, _ = Get(TypeCilium)
+	plugin.Validate(k)
+	if k.Kubernetes.Cilium.Tunnel != "vxlan" {
+		t.Errorf("cilium tunnel = %s, want vxlan", k.Kubernetes.Cilium.Tunnel)
+	}
+
+	k = testToml(TypeFlannel)
+	plugin, _ = Get(TypeFlannel)
+	plugin.Validate(k)
+	if k.Kubernetes.Flannel.Backend != "vxlan" {
+		t.Errorf("flannel backend = %s, want vxlan", k.Kubernetes.Flannel.Backend)
+	}
+
+	if _, err := Get("weave"); err == nil || !strings.Contains(err.Error(), `cni "weave" is not supported`) {
+		t.Errorf("Get(weave) = %v, want an error", err)
+	}
+}
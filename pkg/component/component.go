@@ -0,0 +1,307 @@
+// Package component - Flags of the kubernetes components ([kubernetes.apiserver], [kubernetes.controller-manager],
+// [kubernetes.scheduler] and [kubernetes.kubelet])
+package component
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kore-on/pkg/model"
+	"kore-on/pkg/version"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// max-pods of a node (kubelet default: 110). A node pod cidr (/24) has 254 addresses.
+	minMaxPods = 10
+	maxMaxPods = 250
+
+	// PodSecurityPolicy is removed at v1.25, PodSecurity admission is enabled by default from v1.23
+	podSecurityPolicyRemovedVersion = "v1.25"
+	podSecurityVersion              = "v1.23"
+)
+
+var (
+	flagRegex        = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	featureGateRegex = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	quantityRegex    = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(m|k|Ki|M|Mi|G|Gi|T|Ti|P|Pi|E|Ei)?$`)
+	percentRegex     = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?%$`)
+)
+
+// managedFlags - Flags set by kore-on or kubeadm. They are changed by the koreon.toml options
+// (or the structured fields of the section), not by extra-args.
+var managedFlags = map[string][]string{
+	"apiserver": {
+		"advertise-address", "apiserver-count", "bind-address", "secure-port",
+		"etcd-servers", "etcd-cafile", "etcd-certfile", "etcd-keyfile",
+		"service-cluster-ip-range", "service-node-port-range",
+		"client-ca-file", "tls-cert-file", "tls-private-key-file",
+		"kubelet-client-certificate", "kubelet-client-key",
+		"service-account-key-file", "service-account-signing-key-file", "service-account-issuer",
+		"requestheader-client-ca-file", "proxy-client-cert-file", "proxy-client-key-file",
+		"authorization-mode", "encryption-provider-config",
+		"audit-log-path", "audit-policy-file", "audit-webhook-config-file",
+		"feature-gates", "enable-admission-plugins", "disable-admission-plugins",
+		"oidc-issuer-url", "oidc-client-id", "oidc-username-claim", "oidc-username-prefix",
+		"oidc-groups-claim", "oidc-groups-prefix", "oidc-ca-file", "oidc-required-claim", "oidc-signing-algs",
+	},
+	"controller-manager": {
+		"bind-address", "kubeconfig", "authentication-kubeconfig", "authorization-kubeconfig",
+		"cluster-cidr", "service-cluster-ip-range", "allocate-node-cidrs",
+		"cluster-signing-cert-file", "cluster-signing-key-file", "root-ca-file",
+		"service-account-private-key-file", "requestheader-client-ca-file", "client-ca-file",
+		"use-service-account-credentials", "controllers", "leader-elect",
+		"feature-gates",
+	},
+	"scheduler": {
+		"bind-address", "kubeconfig", "authentication-kubeconfig", "authorization-kubeconfig",
+		"leader-elect", "feature-gates",
+	},
+	"kubelet": {
+		"root-dir", "node-ip", "node-labels", "config", "kubeconfig", "bootstrap-kubeconfig",
+		"container-runtime", "container-runtime-endpoint", "runtime-request-timeout",
+		"cgroup-driver", "cluster-dns", "cluster-domain", "pod-infra-container-image",
+		"feature-gates", "max-pods", "kube-reserved", "system-reserved",
+		"eviction-hard", "eviction-soft", "eviction-soft-grace-period",
+	},
+}
+
+// admissionPlugins - Admission plugins of kube-apiserver (v1.19 ~ v1.26)
+var admissionPlugins = []string{
+	"AlwaysAdmit", "AlwaysDeny", "AlwaysPullImages", "CertificateApproval", "CertificateSigning",
+	"CertificateSubjectRestriction", "DefaultIngressClass", "DefaultStorageClass", "DefaultTolerationSeconds",
+	"DenyServiceExternalIPs", "EventRateLimit", "ExtendedResourceToleration", "ImagePolicyWebhook",
+	"LimitPodHardAntiAffinityTopology", "LimitRanger", "MutatingAdmissionWebhook", "NamespaceAutoProvision",
+	"NamespaceExists", "NamespaceLifecycle", "NodeRestriction", "OwnerReferencesPermissionEnforcement",
+	"PersistentVolumeClaimResize", "PersistentVolumeLabel", "PodNodeSelector", "PodSecurity",
+	"PodSecurityPolicy", "PodTolerationRestriction", "Priority", "ResourceQuota", "RuntimeClass",
+	"SecurityContextDeny", "ServiceAccount", "StorageObjectInUseProtection", "TaintNodesByCondition",
+	"ValidatingAdmissionPolicy", "ValidatingAdmissionWebhook",
+}
+
+// reservedResources - kube-reserved, system-reserved
+var reservedResources = []string{"cpu", "memory", "ephemeral-storage", "pid"}
+
+// evictionSignals - eviction-hard, eviction-soft, eviction-soft-grace-period
+var evictionSignals = []string{
+	"memory.available", "nodefs.available", "nodefs.inodesFree",
+	"imagefs.available", "imagefs.inodesFree", "pid.available",
+}
+
+// ===== [ Private Functions ] =====
+
+// checkExtraArgs - Flag names of extra-args, flags managed by kore-on are rejected
+func checkExtraArgs(component string, args map[string]string) []error {
+	errs := []error{}
+	managed := map[string]bool{}
+	for _, f := range managedFlags[component] {
+		managed[f] = true
+	}
+	for _, name := range sortedKeys(args) {
+		value := args[name]
+		switch {
+		case strings.HasPrefix(name, "-"):
+			errs = append(errs, fmt.Errorf("extra-args > %q: set the flag name without \"--\"", name))
+		case !flagRegex.MatchString(name):
+			errs = append(errs, fmt.Errorf("extra-args > %q is not a flag name", name))
+		case managed[name]:
+			errs = append(errs, fmt.Errorf("extra-args > %q is managed by kore-on and can not be set", name))
+		case value == "":
+			errs = append(errs, fmt.Errorf("extra-args > %s: the value is required", name))
+		case component == "kubelet" && strings.ContainsAny(value, " \t\n\"'\\$`"):
+			// KUBELET_EXTRA_ARGS of /etc/default/kubelet
+			errs = append(errs, fmt.Errorf("extra-args > %s: the value %q has spaces or quotes", name, value))
+		}
+	}
+	return errs
+}
+
+// checkFeatureGates - Feature gate names (e.g. GracefulNodeShutdown)
+func checkFeatureGates(gates map[string]bool) []error {
+	errs := []error{}
+	names := []string{}
+	for name := range gates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !featureGateRegex.MatchString(name) {
+			errs = append(errs, fmt.Errorf("feature-gates > %q is not a feature gate name", name))
+		}
+	}
+	return errs
+}
+
+// checkAdmissionPlugins - enable-admission-plugins, disable-admission-plugins against the kubernetes version
+func checkAdmissionPlugins(a model.KubeApiserver, k8sVersion string) []error {
+	errs := []error{}
+	known := map[string]bool{}
+	for _, p := range admissionPlugins {
+		known[p] = true
+	}
+	kv, err := version.Parse(k8sVersion)
+	if err != nil {
+		return append(errs, fmt.Errorf("kubernetes version %q: %s", k8sVersion, err.Error()))
+	}
+
+	enabled := map[string]bool{}
+	for _, p := range a.EnableAdmissionPlugins {
+		switch {
+		case !known[p]:
+			errs = append(errs, fmt.Errorf("enable-admission-plugins > %q is not an admission plugin", p))
+		case enabled[p]:
+			errs = append(errs, fmt.Errorf("enable-admission-plugins > %q is duplicated", p))
+		case p == "PodSecurityPolicy" && !before(kv, podSecurityPolicyRemovedVersion):
+			errs = append(errs, fmt.Errorf("enable-admission-plugins > PodSecurityPolicy is removed in kubernetes %s (use PodSecurity)", podSecurityPolicyRemovedVersion))
+		case p == "PodSecurity" && before(kv, podSecurityVersion):
+			errs = append(errs, fmt.Errorf("enable-admission-plugins > PodSecurity requires kubernetes %s or later", podSecurityVersion))
+		}
+		enabled[p] = true
+	}
+
+	disabled := map[string]bool{}
+	for _, p := range a.DisableAdmissionPlugins {
+		switch {
+		case !known[p]:
+			errs = append(errs, fmt.Errorf("disable-admission-plugins > %q is not an admission plugin", p))
+		case disabled[p]:
+			errs = append(errs, fmt.Errorf("disable-admission-plugins > %q is duplicated", p))
+		case p == "NodeRestriction":
+			errs = append(errs, fmt.Errorf("disable-admission-plugins > NodeRestriction is required by the node authorizer"))
+		case enabled[p]:
+			errs = append(errs, fmt.Errorf("disable-admission-plugins > %q is also in enable-admission-plugins", p))
+		}
+		disabled[p] = true
+	}
+	return errs
+}
+
+// checkKubelet - [kubernetes.kubelet] KubeletConfiguration fields
+func checkKubelet(kubelet model.KubeKubelet) []error {
+	errs := []error{}
+	if kubelet.MaxPods != 0 && (kubelet.MaxPods < minMaxPods || kubelet.MaxPods > maxMaxPods) {
+		errs = append(errs, fmt.Errorf("max-pods > %d is out of range (%d ~ %d)", kubelet.MaxPods, minMaxPods, maxMaxPods))
+	}
+
+	for _, field := range []string{"kube-reserved", "system-reserved"} {
+		reserved := kubelet.KubeReserved
+		if field == "system-reserved" {
+			reserved = kubelet.SystemReserved
+		}
+		for _, name := range sortedKeys(reserved) {
+			value := reserved[name]
+			switch {
+			case !contains(reservedResources, name):
+				errs = append(errs, fmt.Errorf("%s > %q is not a resource (%s)", field, name, strings.Join(reservedResources, ", ")))
+			case name == "pid":
+				if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+					errs = append(errs, fmt.Errorf("%s > pid %q is not a number of processes", field, value))
+				}
+			case !quantityRegex.MatchString(value):
+				errs = append(errs, fmt.Errorf("%s > %s %q is not a quantity (e.g. 500m, 1Gi)", field, name, value))
+			}
+		}
+	}
+
+	for _, field := range []string{"eviction-hard", "eviction-soft"} {
+		thresholds := kubelet.EvictionHard
+		if field == "eviction-soft" {
+			thresholds = kubelet.EvictionSoft
+		}
+		for _, signal := range sortedKeys(thresholds) {
+			value := thresholds[signal]
+			if !contains(evictionSignals, signal) {
+				errs = append(errs, fmt.Errorf("%s > %q is not an eviction signal (%s)", field, signal, strings.Join(evictionSignals, ", ")))
+				continue
+			}
+			if !checkThreshold(value) {
+				errs = append(errs, fmt.Errorf("%s > %s %q is not a quantity or a percentage (e.g. 500Mi, 10%%)", field, signal, value))
+			}
+		}
+	}
+
+	// A soft threshold needs the grace period of the signal
+	for _, signal := range sortedKeys(kubelet.EvictionSoft) {
+		if _, ok := kubelet.EvictionSoftGracePeriod[signal]; !ok && contains(evictionSignals, signal) {
+			errs = append(errs, fmt.Errorf("eviction-soft > %s: eviction-soft-grace-period of the signal is required", signal))
+		}
+	}
+	for _, signal := range sortedKeys(kubelet.EvictionSoftGracePeriod) {
+		period := kubelet.EvictionSoftGracePeriod[signal]
+		if _, ok := kubelet.EvictionSoft[signal]; !ok {
+			errs = append(errs, fmt.Errorf("eviction-soft-grace-period > %s: the signal is not in eviction-soft", signal))
+		} else if d, err := time.ParseDuration(period); err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("eviction-soft-grace-period > %s %q is not a duration (e.g. 1m30s)", signal, period))
+		}
+	}
+	return errs
+}
+
+// checkThreshold - Eviction threshold is a quantity or a percentage (0% ~ 100%)
+func checkThreshold(value string) bool {
+	if percentRegex.MatchString(value) {
+		p, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		return err == nil && p <= 100
+	}
+	return quantityRegex.MatchString(value)
+}
+
+func before(v version.Version, target string) bool {
+	t, err := version.Parse(target)
+	if err != nil {
+		return false
+	}
+	return v.Major < t.Major || (v.Major == t.Major && v.Minor < t.Minor)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ===== [ Public Functions ] =====
+
+// Validate - Flags of [kubernetes.apiserver], [kubernetes.controller-manager], [kubernetes.scheduler] and [kubernetes.kubelet]
+func Validate(k *model.KoreOnToml) []error {
+	errs := []error{}
+	add := func(section string, list []error) {
+		for _, err := range list {
+			errs = append(errs, fmt.Errorf("kubernetes.%s > %s", section, err.Error()))
+		}
+	}
+
+	apiserver := k.Kubernetes.Apiserver
+	add("apiserver", checkExtraArgs("apiserver", apiserver.ExtraArgs))
+	add("apiserver", checkFeatureGates(apiserver.FeatureGates))
+	add("apiserver", checkAdmissionPlugins(apiserver, k.Kubernetes.Version))
+
+	add("controller-manager", checkExtraArgs("controller-manager", k.Kubernetes.ControllerManager.ExtraArgs))
+	add("controller-manager", checkFeatureGates(k.Kubernetes.ControllerManager.FeatureGates))
+
+	add("scheduler", checkExtraArgs("scheduler", k.Kubernetes.Scheduler.ExtraArgs))
+	add("scheduler", checkFeatureGates(k.Kubernetes.Scheduler.FeatureGates))
+
+	add("kubelet", checkExtraArgs("kubelet", k.Kubernetes.Kubelet.ExtraArgs))
+	add("kubelet", checkFeatureGates(k.Kubernetes.Kubelet.FeatureGates))
+	add("kubelet", checkKubelet(k.Kubernetes.Kubelet))
+
+	return errs
+}
@@ -0,0 +1,160 @@
+package component
+
+import (
+	"strings"
+	"testing"
+
+	"kore-on/pkg/model"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(k *model.KoreOnToml)
+		err    string
+	}{
+		{name: "empty"},
+		{
+			name: "extra-args and feature-gates",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Apiserver.ExtraArgs = map[string]string{"max-requests-inflight": "800", "profiling": "false"}
+				k.Kubernetes.Apiserver.FeatureGates = map[string]bool{"APIPriorityAndFairness": true}
+				k.Kubernetes.ControllerManager.ExtraArgs = map[string]string{"terminated-pod-gc-threshold": "500"}
+				k.Kubernetes.Scheduler.FeatureGates = map[string]bool{"MinDomainsInPodTopologySpread": false}
+				k.Kubernetes.Kubelet.ExtraArgs = map[string]string{"v": "2"}
+				k.Kubernetes.Kubelet.FeatureGates = map[string]bool{"GracefulNodeShutdown": true}
+			},
+		},
+		// the flags of kore-on and kubeadm are not overridden by extra-args
+		{
+			name: "managed apiserver flag",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Apiserver.ExtraArgs = map[string]string{"service-cluster-ip-range": "10.96.0.0/16"}
+			},
+			err: `kubernetes.apiserver > extra-args > "service-cluster-ip-range" is managed by kore-on`,
+		},
+		{
+			name: "feature-gates of extra-args",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Scheduler.ExtraArgs = map[string]string{"feature-gates": "A=true"}
+			},
+			err: `kubernetes.scheduler > extra-args > "feature-gates" is managed by kore-on`,
+		},
+		{
+			name:   "managed kubelet flag",
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Kubelet.ExtraArgs = map[string]string{"max-pods": "200"} },
+			err:    `kubernetes.kubelet > extra-args > "max-pods" is managed by kore-on`,
+		},
+		{
+			name:   "flag with dashes",
+			change: func(k *model.KoreOnToml) { k.Kubernetes.ControllerManager.ExtraArgs = map[string]string{"--v": "2"} },
+			err:    `kubernetes.controller-manager > extra-args > "--v": set the flag name without "--"`,
+		},
+		{
+			name:   "flag name",
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Apiserver.ExtraArgs = map[string]string{"Profiling": "false"} },
+			err:    `extra-args > "Profiling" is not a flag name`,
+		},
+		{
+			name:   "flag value",
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Apiserver.ExtraArgs = map[string]string{"profiling": ""} },
+			err:    "extra-args > profiling: the value is required",
+		},
+		{
+			name: "kubelet value with spaces",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Kubelet.ExtraArgs = map[string]string{"node-status-update-frequency": "10s 20s"}
+			},
+			err: `extra-args > node-status-update-frequency: the value "10s 20s" has spaces or quotes`,
+		},
+		{
+			name: "feature gate name",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Kubelet.FeatureGates = map[string]bool{"graceful-node-shutdown": true}
+			},
+			err: `kubernetes.kubelet > feature-gates > "graceful-node-shutdown" is not a feature gate name`,
+		},
+		{
+			name:   "admission plugin",
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Apiserver.EnableAdmissionPlugins = []string{"AlwaysPull"} },
+			err:    `enable-admission-plugins > "AlwaysPull" is not an admission plugin`,
+		},
+		{
+			name: "PodSecurityPolicy",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Apiserver.EnableAdmissionPlugins = []string{"PodSecurityPolicy"}
+			},
+			err: "PodSecurityPolicy is removed in kubernetes v1.25",
+		},
+		{
+			name: "PodSecurity",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Version = "v1.22.15"
+				k.Kubernetes.Apiserver.EnableAdmissionPlugins = []string{"PodSecurity"}
+			},
+			err: "PodSecurity requires kubernetes v1.23 or later",
+		},
+		{
+			name: "NodeRestriction",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Apiserver.DisableAdmissionPlugins = []string{"NodeRestriction"}
+			},
+			err: "NodeRestriction is required by the node authorizer",
+		},
+		{
+			name: "enabled and disabled",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Apiserver.EnableAdmissionPlugins = []string{"AlwaysPullImages"}
+				k.Kubernetes.Apiserver.DisableAdmissionPlugins = []string{"AlwaysPullImages"}
+			},
+			err: `disable-admission-plugins > "AlwaysPullImages" is also in enable-admission-plugins`,
+		},
+		{
+			name:   "max-pods",
+			change: func(k *model.KoreOnToml) { k.Kubernetes.Kubelet.MaxPods = 300 },
+			err:    "max-pods > 300 is out of range (10 ~ 250)",
+		},
+		{
+			name: "reserved",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Kubelet.KubeReserved = map[string]string{"cpu": "500m", "memory": "1Gi", "pid": "1000"}
+				k.Kubernetes.Kubelet.SystemReserved = map[string]string{"memory": "1 GB"}
+			},
+			err: `system-reserved > memory "1 GB" is not a quantity`,
+		},
+		{
+			name: "eviction-soft",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Kubelet.EvictionHard = map[string]string{"memory.available": "5%"}
+				k.Kubernetes.Kubelet.EvictionSoft = map[string]string{"memory.available": "10%", "nodefs.available": "15%"}
+				k.Kubernetes.Kubelet.EvictionSoftGracePeriod = map[string]string{"memory.available": "1m30s"}
+			},
+			err: "eviction-soft > nodefs.available: eviction-soft-grace-period of the signal is required",
+		},
+		{
+			name: "eviction threshold",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Kubelet.EvictionHard = map[string]string{"memory.available": "120%"}
+			},
+			err: `eviction-hard > memory.available "120%" is not a quantity or a percentage`,
+		},
+	}
+	for _, c := range cases {
+		k := &model.KoreOnToml{}
+		k.Kubernetes.Version = "v1.25.6"
+		if c.change != nil {
+			c.change(k)
+		}
+
+		errs := []string{}
+		for _, err := range Validate(k) {
+			errs = append(errs, err.Error())
+		}
+		switch {
+		case c.err == "" && len(errs) > 0:
+			t.Errorf("%s: Validate() = %v, want no errors", c.name, errs)
+		case c.err != "" && (len(errs) != 1 || !strings.Contains(errs[0], c.err)):
+			t.Errorf("%s: Validate() = %v, want one error with %q", c.name, errs, c.err)
+		}
+	}
+}
@@ -5,22 +5,52 @@ const AddonTemplate = `
 ## You can check supported applications with the 'list' command                 ##
 ## and add applications to install. It can be used as below.                    ##
 ##                                                                              ##
+## Any [apps.<name>] is deployed as the helm release <name>.                    ##
 ## ※ If both "values" and "value_file" exist, "values" is used.                 ##
 ## -- Sample --                                                                 ##
 ## [apps.application-name]                                                      ##
 ## install = true                                                               ##
 ## chart_ref_name = "xxx"                                                       ##
 ## chart_ref = "https://helm-chart-address or helm-package-address(or path)"    ##
+##             or "oci://registry-address/project"                              ##
+## chart_name = "xxx"                                                           ##
+## chart_version = "x.y.z"                                                      ##
+## release_namespace = "xxx"                                                    ##
+## depends_on = ["other-application-name"]                                      ##
+## [apps.application-name.wait]                                                 ##
+## deployments = ["deployment-name", "namespace/deployment-name"]               ##
+## crds = ["crd-name.group"]                                                    ##
+## timeout = "5m"                                                               ##
 ## values="""                                                                   ##
 ## helm-chart-values                                                            ##
 ## """                                                                          ##
 ## value_file = "helm-chart-values file path"                                   ##
+## manifests="""                                                                ##
+## kubernetes objects deployed after the chart (release <name>-manifests)       ##
+## """                                                                          ##
+##                                                                              ##
+## values, values_file and manifests are go templates with koreon.toml:         ##
+##   .Cluster   Name, Version, ServiceCidr, PodCidr, NodePortRange,             ##
+##              ClosedNetwork                                                   ##
+##   .Storage   Install, StorageType, IP, PrivateIP, VolumeDir, VolumeSize,     ##
+##              Exports (Path, StorageClass, Default)                           ##
+##   .Registry  Install, Domain, IP, PrivateIP                                  ##
+##   .NodePool  DataDir, MasterIP, MasterPrivateIP, LbIP, LbPort,               ##
+##              NodeIP, NodePrivateIP (lists: {{ join .NodePool.NodeIP "," }})  ##
+## e.g. server: {{ .Storage.PrivateIP }}, an undefined key is an error.         ##
+## "{{" of the chart values is written as {{ "{{" }}.                           ##
+##                                                                              ##
+## In closed network, prepare-airgap bundles the charts in archive/charts/ and  ##
+## pushes them to the private registry (oci://<registry>/helm-charts).          ##
+## chart_ref = "<chart>-<version>.tgz" installs the chart archive of bundle.    ##
 ##################################################################################
 
 [addon]
 ## Required
 ## - k8s-master-ip: K8s control plane node ip address. (Deployment runs on this node.)
 ##                  If you want to deploy locally, you must use the --kubeconfig option.
+##                  With --kubeconfig, helm runs in this host and k8s-master-ip is not used.
+##                  The kubeconfig must embed its certificates (kubectl config view --minify --flatten).
 ## -
 ## Optional
 ## - ssh-port: K8s Controlplane Node ssh port (default: 22)
@@ -42,9 +72,13 @@ const AddonTemplate = `
 ## - values: chart values (If both "values" and "value_file" exist, "values" is used.	)
 ## -
 ## Optional
-## - chart_version: deploy chart version (default: "latest") 
+## - chart_version: deploy chart version (default: helm_chart_package of the kubernetes version, "latest" for other charts)
+## - login: the chart repository needs a user login (asked when deploying).
+## - depends_on: apps to deploy before this app. The app is skipped when one of them failed.
+## - [apps.<name>.wait]: deployments available and crds established after the deployment (timeout default: "5m").
 ## -
 #install = true
+#login = true
 #chart_ref_name = "helm-charts"
 #chart_ref = "https://hcapital-harbor.acloud.run/chartrepo/cube"
 #chart_name = "csi-driver-nfs"
@@ -57,8 +91,8 @@ storageClass:
   parameters:
     mountOptions:
     - nfsvers=4.1
-    server: x.x.x.x
-    share: /data/storage
+    server: {{ .Storage.PrivateIP }}
+    share: {{ .Storage.VolumeDir }}
 """
 
 [apps.koreboard]
@@ -73,6 +107,9 @@ storageClass:
 ## -
 ## Optional
 ## - chart_version: deploy chart version (default: "latest") 
+## - login: the chart repository needs a user login (asked when deploying).
+## - depends_on: apps to deploy before this app. The app is skipped when one of them failed.
+## - [apps.<name>.wait]: deployments available and crds established after the deployment (timeout default: "5m").
 ## -
 #install = true
 #chart_ref_name = "<chart repo name>"
@@ -92,4 +129,63 @@ frontend:
     type: NodePort
 
 """
+
+## -- Catalog for bare metal --
+## The chart version follows helm_chart_package of the kubernetes version and the
+## chart defaults (namespace, values for bare metal, wait) are merged under "values".
+## The charts and images are bundled by prepare-airgap.
+
+[apps.metallb]
+## Required
+## - manifests: IPAddressPool of the LoadBalancer services (CIDR or "<first ip>-<last ip>"),
+##              announced by an L2Advertisement.
+## -
+#install = true
+#chart_ref_name = "metallb"
+#chart_ref = "https://metallb.github.io/metallb"
+#chart_name = "metallb"
+#manifests = """
+#apiVersion: metallb.io/v1beta1
+#kind: IPAddressPool
+#metadata:
+#  name: default
+#spec:
+#  addresses:
+#  - 192.168.0.240-192.168.0.250
+#---
+#apiVersion: metallb.io/v1beta1
+#kind: L2Advertisement
+#metadata:
+#  name: default
+#spec:
+#  ipAddressPools:
+#  - default
+#"""
+
+[apps.ingress-nginx]
+## - The controller is a DaemonSet, the service is a LoadBalancer with metallb, a NodePort without it.
+## -
+#install = true
+#chart_ref_name = "ingress-nginx"
+#chart_ref = "https://kubernetes.github.io/ingress-nginx"
+#chart_name = "ingress-nginx"
+#depends_on = ["metallb"]
+
+[apps.cert-manager]
+## - The CRDs are installed with the chart (installCRDs).
+## -
+#install = true
+#chart_ref_name = "jetstack"
+#chart_ref = "https://charts.jetstack.io"
+#chart_name = "cert-manager"
+
+[apps.kube-prometheus-stack]
+## - release_namespace default: "monitoring"
+## -
+#install = true
+#chart_ref_name = "prometheus-community"
+#chart_ref = "https://prometheus-community.github.io/helm-charts"
+#chart_name = "kube-prometheus-stack"
+#[apps.kube-prometheus-stack.wait]
+#timeout = "10m"
 `
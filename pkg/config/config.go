@@ -2,9 +2,11 @@
 package config
 
 import (
+	"bytes"
 	"os"
 	"strings"
 
+	catalog "kore-on/conf"
 	"kore-on/pkg/logger"
 
 	"github.com/spf13/viper"
@@ -51,3 +53,12 @@ func Load() error {
 
 	return err
 }
+
+// LoadEmbedded - Load the configuration that was embedded at build time
+func LoadEmbedded() error {
+	viper.SetConfigType("yaml")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	return viper.ReadConfig(bytes.NewReader(catalog.ConfigYaml))
+}
@@ -34,21 +34,33 @@ const Template = `
 ## Optional
 ## - version: Kubernetes version (default: "latest")
 ##            If you input only the major version, the minor version automatically selects the last version.
-## - container-runtime: use k8s cri (only containerd)
+##            Constraints are also accepted. (e.g. "~1.24", ">=1.23 <1.26")
+## - container-runtime: k8s container runtime [containerd | cri-o] (default: "containerd")
+##                      The cri-o minor version is the kubernetes minor version ([kubernetes.cri-o]).
+##                      It cannot be changed after the cluster is created.
 ## - kube-proxy-mode: use k8s proxy mode [iptables | ipvs] (default: "ipvs")
 ## - service-cidr: k8s service network cidr (default: "10.96.0.0/20")
-## - pod-cidr: k8s pod network cidr (default: "10.4.0.0/24")
+## - pod-cidr: k8s pod network cidr (default: "10.4.0.0/20")
+##             IPv4/IPv6 dual-stack: "<IPv4 cidr>,<IPv6 cidr>" in pod-cidr and service-cidr (k8s v1.23 or later, calico).
+##             The IPv6 pod-cidr is /48 ~ /63, the IPv6 service-cidr is /108 or smaller.
+##             The control plane uses the IPv4 addresses, the nodes need private-ipv6 of [node-pool].
 ## - node-port-range: k8s node port network range (default: "30000-32767")
 ## - audit-log-enable: k8s audit log enabled (default: true)
 ## - api-sans: Add k8s apiserver SAN [--apiserver-cert-extra-sans same as setting] (default: master[0] ip address)
+##             IP addresses (IPv4, IPv6) or domain names.
+## - cni: k8s network plugin [calico | cilium | flannel] (default: "calico")
+##        It cannot be changed after the cluster is created.
 #version = "v1.23.12"
 #container-runtime = "containerd"
 #kube-proxy-mode = "ipvs"
 #service-cidr = "172.20.0.0/24"
 #pod-cidr = "10.10.0.0/24"
+#pod-cidr = "10.10.0.0/16,fd00:10:10::/56"
+#service-cidr = "172.20.0.0/24,fd00:172:20::/112"
 #node-port-range = "30000-32767"
 #audit-log-enable = true
 #api-sans = ["x.x.x.x"]
+#cni = "calico"
 
 [kubernetes.etcd]
 ## Required
@@ -66,9 +78,171 @@ const Template = `
 [kubernetes.calico]
 ## Required
 ## - 
-## Optional
-## - vxlan-mode: calico VXLAN mode activate (default: false)
+## Optional (used when cni = "calico")
+## - vxlan-mode: calico VXLAN mode activate, same as encapsulation = "vxlan" (default: false)
+## - encapsulation: encapsulation of the ip pools [ipip | vxlan | none] (default: "ipip")
+##                  "none" routes the pod traffic without encapsulation (BGP), "vxlan" does not run BGP.
+## - cross-subnet: encapsulate only the traffic between the subnets [IPIP/VXLAN CrossSubnet] (default: false)
+## - mtu: MTU of the pod interfaces and tunnels, 1280 ~ 9000 (default: 0, auto-detect)
+## - ip-autodetection-method: node IP address detection of calico (default: "kubernetes-internal-ip")
+##                            [first-found | kubernetes-internal-ip | can-reach=<ip> | interface=<regex> |
+##                             skip-interface=<regex> | cidr=<cidr>,...]
+## - ip6-autodetection-method: node IPv6 address detection of calico in dual-stack (default: "kubernetes-internal-ip")
+## The changes are applied with "koreonctl cluster update" without the node changes.
 #vxlan-mode = true
+#encapsulation = "ipip"
+#cross-subnet = true
+#mtu = 1440
+#ip-autodetection-method = "interface=eth0"
+#ip6-autodetection-method = "kubernetes-internal-ip"
+
+## [[kubernetes.calico.ip-pools]]
+## IP pools in the pod-cidr. The default pool of pod-cidr is not created when the ip-pools are set.
+## Dual-stack needs the IPv4 and IPv6 pools (IPv6 pools: encapsulation vxlan or none, block-size 116 ~ 128).
+## (added to a running cluster, the default pool is disabled. The pods keep their addresses until
+##  they are recreated. The pools removed from koreon.toml are not deleted from the cluster.)
+## Required
+## - name: ip pool name
+## - cidr: ip pool cidr (in the pod-cidr, not overlapped)
+## Optional
+## - block-size: address block size of a node (default: 26, IPv6: 122)
+## - node-selector: nodes of the pool, calico selector (default: "all()")
+## - encapsulation: [ipip | vxlan | none] (default: encapsulation of [kubernetes.calico])
+## - disable-nat-outgoing: no SNAT of the pod traffic leaving the pool (default: false)
+#[[kubernetes.calico.ip-pools]]
+#name = "rack1-pool"
+#cidr = "10.10.0.0/25"
+#node-selector = "rack == 'rack1'"
+
+## [kubernetes.calico.bgp]
+## Not used with encapsulation = "vxlan".
+## Optional
+## - as-number: AS number of the nodes (default: 64512)
+## - disable-node-mesh: disable the full mesh BGP between the nodes, peers are required (default: false)
+#[kubernetes.calico.bgp]
+#as-number = 64512
+#disable-node-mesh = true
+
+## [[kubernetes.calico.bgp.peers]]
+## External BGP peers (e.g. top-of-rack routers). The peers removed from koreon.toml are not deleted.
+## Required
+## - peer-ip: peer ip address
+## - as-number: AS number of the peer
+## Optional
+## - name: peer name (default: "peer-<peer-ip>")
+## - node-selector: nodes peering with the peer, calico selector (default: all nodes)
+#[[kubernetes.calico.bgp.peers]]
+#peer-ip = "x.x.x.x"
+#as-number = 65001
+#node-selector = "rack == 'rack1'"
+
+[kubernetes.cilium]
+## Required
+## - 
+## Optional (used when cni = "cilium")
+## - kube-proxy-replacement: replace kube-proxy with cilium eBPF (default: false, k8s v1.24 or later)
+## - tunnel: cilium tunnel mode [vxlan | geneve | disabled] (default: "vxlan")
+##           "disabled" uses native routing, the pod-cidr must be routable between nodes.
+#kube-proxy-replacement = true
+#tunnel = "vxlan"
+
+[kubernetes.flannel]
+## Required
+## - 
+## Optional (used when cni = "flannel")
+## - backend: flannel backend type [vxlan | host-gw | wireguard] (default: "vxlan")
+#backend = "vxlan"
+
+[kubernetes.cri-o]
+## Required
+## - 
+## Optional (used when container-runtime = "cri-o")
+## - version: CRI-O version, the minor version must be the kubernetes minor version
+##            (default: the last patch version of the kubernetes minor version, "koreonctl versions")
+##            The kubernetes upgrade needs the version of the new minor version (or no version).
+#version = "v1.24.6"
+
+## [[kubernetes.cri-o.registries]]
+## Registry mirrors and pull credentials of CRI-O (/etc/containers/registries.conf.d, /etc/crio/auth.json).
+## The private registry of [private-registry] is configured without the registries.
+## Required
+## - prefix: registry of the images (e.g. "docker.io", "quay.io/calico", "*.example.com")
+## Optional
+## - mirrors: registries pulled before the prefix, in order (host[:port][/path])
+## - insecure: http or an untrusted certificate of the prefix and the mirrors (default: false)
+## - username, password: pull credential of the mirrors (or the prefix without the mirrors)
+#[[kubernetes.cri-o.registries]]
+#prefix = "docker.io"
+#mirrors = ["mirror.example.com/docker.io"]
+#username = "user"
+#password = "password"
+
+[kubernetes.apiserver]
+## Required
+## - 
+## Optional
+## Flags of the components are applied by "koreonctl update" without node changes, one master (node) at a time.
+## The component is restored when it is not healthy, and the update is stopped.
+## - extra-args: flags (without "--"), the flags managed by koreon (e.g. bind-address, etcd-servers) can not be set
+##               default-not-ready-toleration-seconds, default-unreachable-toleration-seconds and audit-log-max* are replaced
+## - feature-gates: feature gates of kube-apiserver
+## - enable-admission-plugins: admission plugins enabled with NodeRestriction (e.g. PodSecurity, AlwaysPullImages)
+## - disable-admission-plugins: admission plugins disabled (NodeRestriction can not be disabled)
+#extra-args = { "profiling" = "false", "max-requests-inflight" = "800" }
+#feature-gates = { GracefulNodeShutdown = true }
+#enable-admission-plugins = ["PodSecurity"]
+#disable-admission-plugins = []
+
+[kubernetes.controller-manager]
+## Optional
+## - extra-args: flags (without "--"), node-monitor-period and node-monitor-grace-period are replaced
+## - feature-gates: feature gates of kube-controller-manager
+#extra-args = { "terminated-pod-gc-threshold" = "1000" }
+#feature-gates = {}
+
+[kubernetes.scheduler]
+## Optional
+## - extra-args: flags (without "--")
+## - feature-gates: feature gates of kube-scheduler
+#extra-args = { "profiling" = "false" }
+#feature-gates = {}
+
+[kubernetes.kubelet]
+## Optional (all nodes)
+## - extra-args: flags (without "--"), values without spaces and quotes
+## - feature-gates: feature gates of kubelet
+## - max-pods: max pods of a node, 10 ~ 250 (default: 110)
+## - kube-reserved, system-reserved: resources reserved for kubernetes and the os [cpu | memory | ephemeral-storage | pid]
+## - eviction-hard: hard eviction thresholds, a quantity or a percentage (the kubelet defaults are replaced)
+##                  signals [memory.available | nodefs.available | nodefs.inodesFree | imagefs.available | imagefs.inodesFree | pid.available]
+## - eviction-soft: soft eviction thresholds, eviction-soft-grace-period of each signal is required
+#extra-args = { "image-gc-high-threshold" = "80" }
+#feature-gates = { GracefulNodeShutdown = true }
+#max-pods = 110
+#kube-reserved = { cpu = "200m", memory = "512Mi" }
+#system-reserved = { cpu = "200m", memory = "512Mi", pid = "1000" }
+#eviction-hard = { "memory.available" = "500Mi", "nodefs.available" = "10%" }
+#eviction-soft = { "memory.available" = "1Gi" }
+#eviction-soft-grace-period = { "memory.available" = "1m30s" }
+
+[kubernetes.oidc]
+## Optional (OIDC authentication of the apiserver, disabled without issuer-url)
+## - issuer-url: https url of the OpenID Connect issuer, the same as "iss" of the id tokens (e.g. Keycloak realm, Dex)
+## - client-id: client id of the cluster, the audience of the id tokens (required with issuer-url)
+## - username-claim: claim of the username (default: "sub")
+## - username-prefix: prefix of the usernames, "-" for no prefix (default: "<issuer-url>#", no prefix for "email")
+## - groups-claim, groups-prefix: claim and prefix of the groups
+## - ca-file: CA certificate of the issuer. Copy it to the config directory (default: system CA)
+## - extra-scopes: scopes requested by config/oidc-kubeconfig in addition to "openid"
+## config/oidc-kubeconfig (kubectl oidc-login plugin) is created by create and update.
+## 'koreonctl oidc check --stand-in' checks the configuration without network.
+#issuer-url = "https://keycloak.example.com/realms/kubernetes"
+#client-id = "kubernetes"
+#username-claim = "email"
+#groups-claim = "groups"
+#groups-prefix = "oidc:"
+#ca-file = "keycloak-ca.crt"
+#extra-scopes = ["email", "groups"]
 
 [node-pool]
 ## Required
@@ -85,12 +259,14 @@ const Template = `
 ## - private-ip: K8s control plane nodes private ip address.
 ##               If you use the same IP address, you can skip it.
 ## Optional
+## - private-ipv6: K8s control plane nodes IPv6 address, required in dual-stack (same order as ip)
 ## - lb-ip: loadbalancer ip address (default: master[0] node ip address)
 ## - isolated: K8s control plane nodes isolated (default: false)
 ## - lb-ip: Enter the IP address when using a load balancer (default: master[0] ip address)
 ## - lb-port: Enter the port when using a load balancer (default: 6443)
 #ip = ["x.x.x.x","x.x.x.x","x.x.x.x"]
 #private-ip = ["x.x.x.x","x.x.x.x","x.x.x.x"]
+#private-ipv6 = ["fd00::x","fd00::x","fd00::x"]
 #isolated = true
 #lb-ip = "x.x.x.x"
 #lb-port = 6443
@@ -101,8 +277,10 @@ const Template = `
 ## - private-ip: K8s work nodes private ip address.
 ##               If you use the same IP address, you can skip it.
 ## Optional
+## - private-ipv6: K8s work nodes IPv6 address, required in dual-stack (same order as ip)
 #ip = ["x.x.x.x", "x.x.x.x"]
 #private-ip = ["x.x.x.x", "x.x.x.x"]
+#private-ipv6 = ["fd00::x", "fd00::x"]
 
 [private-registry]
 ## Required
@@ -137,24 +315,123 @@ const Template = `
 ## Optional
 #ca-cert = ""
 
+#[private-registry.external]
+## Use an existing registry(Harbor, Nexus, ...) instead of installing harbor. (install must be false)
+## Images are uploaded by 'koreonctl images upload' and the container runtimes of the nodes pull from it.
+## Required
+## - url: Registry url. (e.g. "https://registry.example.com", "http://x.x.x.x:5000")
+## Optional
+## - path-prefix: Path in the registry under which the images are stored. (default: "")
+##                (e.g. "kore-on" -> registry.example.com/kore-on/registry.k8s.io/pause:3.9)
+## - ca-file: CA certificate of the registry. Copy it to the config directory next to koreon.toml. (default: "")
+## - username, password: Credential used to upload and pull images. (default: anonymous)
+#url = "https://registry.example.com"
+#path-prefix = "kore-on"
+#ca-file = "registry-ca.crt"
+#username = "kore-on"
+#password = ""
+
+#[private-registry.projects.<name>]
+## Harbor projects managed by 'koreonctl registry-manager project|robot|retention|proxy-cache'.
+## Projects are never deleted. Projects that exist only in harbor are listed as unmanaged.
+## Optional
+## - public: Anyone can pull images of the project. (default: false)
+## - storage-limit: Storage quota of the project in GiB. (default: -1, unlimited)
+#[private-registry.projects.library]
+#public = true
+#storage-limit = 100
+
+#[private-registry.projects.<name>.retention]
+## Required
+## - keep-last: Retain the most recently pushed N tags of each repository.
+## - keep-days: Retain the tags pushed within the last N days.
+##              keep-last or keep-days is required. If both are set, a tag matching either is retained.
+## Optional
+## - tags: Tags to which the rule applies. (default: "**")
+## - repositories: Repositories to which the rule applies. (default: "**")
+## - schedule: Cron expression with seconds. (default: "0 0 0 * * *", daily)
+#[private-registry.projects.library.retention]
+#keep-last = 10
+#tags = "**"
+#repositories = "**"
+#schedule = "0 0 0 * * 0"
+
+#[private-registry.projects.<name>.robots.<robot-name>]
+## Required
+## - permissions: pull, push, delete, chart-pull, chart-push
+## Optional
+## - description: Robot account description. (default: "")
+## - duration: Days until the robot account expires. (default: -1, never expires)
+## The secret of a new robot account is printed once by 'registry-manager robot'.
+#[private-registry.projects.library.robots.ci]
+#permissions = ["pull", "push"]
+#description = "CI pipeline"
+#duration = 90
+
+#[private-registry.projects.<name>.proxy-cache]
+## Proxy-cache project pulling through an upstream registry. (e.g. registry-domain/dockerhub/library/nginx)
+## Required
+## - type: docker-hub, docker-registry, harbor, aws-ecr, azure-acr, google-gcr, quay, jfrog-artifactory, github-ghcr
+## - url: Upstream registry url.
+## Optional
+## - username, password: Upstream registry credential. (default: anonymous)
+## - insecure: Skip TLS verification of the upstream registry. (default: false)
+#[private-registry.projects.dockerhub]
+#public = true
+#[private-registry.projects.dockerhub.proxy-cache]
+#type = "docker-hub"
+#url = "https://hub.docker.com"
+
 [shared-storage]
 ## Required
-## - storage-ip: Storage node ip address.
-##               This is a required field used when installing the nfs server.
+## - storage-ip: Storage node ip address. (storage-type: nfs, external-nfs)
+##               nfs: the nfs server is installed on this node.
+##               external-nfs: address of the existing nfs server.
 ##               (this is using it to generate an inventory and generate an extra vars)
-## - private-ip: Storage node ip address.
+## - private-ip: Storage node ip address. (storage-type: nfs)
 ##               This is a required field used when installing the nfs server.
 ##               If you use the same IP address, you can skip it.
 ##               (this is using it to generate an inventory)
-## - volume-dir: Storage node data directory. (default: /data/storage)
-##               This is a required field used when installing the nfs server.
+## - volume-dir: Volume directory of the storage-type.
+##               nfs: data directory of the nfs server (default: /data/storage)
+##               external-nfs: exported directory of the nfs server (required)
+##               local-path: directory on every node (default: /opt/local-path-provisioner)
+##               longhorn: data directory on every node (default: /var/lib/longhorn)
 ##               (this is using it to generate an extra vars)
 ## Optional
-## - install: NFS Server Installation (default: false)
+## - install: Shared storage and the default StorageClass installation (default: false)
+## - storage-type: Backend of the default StorageClass (default: "nfs")
+##                 nfs: an nfs server on storage-ip and csi-driver-nfs
+##                 external-nfs: an existing nfs export (storage-ip:volume-dir) and csi-driver-nfs
+##                 local-path: local-path-provisioner, volumes on the node of the pod
+##                 longhorn: longhorn, volumes replicated over the nodes (kubernetes v1.21 or later)
+## - replica-count: Replicas of a longhorn volume (default: 3, at most the number of nodes)
+## - [[shared-storage.exports]]: NFS exports, a StorageClass of each export (storage-type: nfs, external-nfs)
+##                               Used instead of volume-dir and volume-size.
+##   - path: Exported directory (required)
+##   - size: Size quota of the export in GiB (storage-type: nfs, default: 0, no quota)
+##   - allowed-cidrs: Clients of the export (storage-type: nfs, default: all)
+##   - storage-class: StorageClass name (required with more than one export, default: "default-storage")
+##   - reclaim-policy: "delete" or "retain" (default: "delete")
+##   - mount-options: NFS mount options (default: ["nfsvers=4.1"])
+##   - default: The default StorageClass. Only one export (default: the first export)
 #install = true
+#storage-type = "nfs"
 #storage-ip = "x.x.x.x"
 #private-ip = "x.x.x.x"
 #volume-dir = "/data/storage"
+#replica-count = 3
+#[[shared-storage.exports]]
+#path = "/data/storage/db"
+#size = 100
+#allowed-cidrs = ["x.x.x.x/24"]
+#storage-class = "nfs-retain"
+#reclaim-policy = "retain"
+#mount-options = ["nfsvers=4.1", "hard"]
+#[[shared-storage.exports]]
+#path = "/data/storage/scratch"
+#storage-class = "nfs-delete"
+#default = true
 
 [prepare-airgap]
 ## Required
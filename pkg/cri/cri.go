@@ -0,0 +1,124 @@
+// Package cri - Container runtimes of [kubernetes] container-runtime
+package cri
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kore-on/pkg/model"
+	"kore-on/pkg/version"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// TypeContainerd installs containerd (default)
+	TypeContainerd = "containerd"
+	// TypeCrio installs CRI-O of the kubernetes minor version
+	TypeCrio = "cri-o"
+)
+
+// registryRegex - host[:port][/path] of containers-registries.conf (prefix may start with "*.")
+var registryRegex = regexp.MustCompile(`^(\*\.)?[a-zA-Z0-9]([-a-zA-Z0-9.]*[a-zA-Z0-9])?(:[0-9]{1,5})?(/[a-zA-Z0-9._-]+)*$`)
+
+// ===== [ Types ] =====
+
+// Runtime - Container runtime. Install is the ansible role cri/<distribution> (tasks <type>).
+type Runtime interface {
+	// Validate - [kubernetes.<runtime>] of the runtime against the kubernetes version
+	Validate(k *model.KoreOnToml) []error
+}
+
+type containerd struct{}
+
+type crio struct{}
+
+// ===== [ Implements ] =====
+
+func (containerd) Validate(k *model.KoreOnToml) []error {
+	errs := []error{}
+	if crioSet(k) {
+		errs = append(errs, fmt.Errorf("[kubernetes.cri-o] is set, but the container-runtime is %s", TypeContainerd))
+	}
+	if k.SupportVersion.PackageVersion.Containerd == "" {
+		errs = append(errs, fmt.Errorf("containerd is not supported on kubernetes %s", k.Kubernetes.Version))
+	}
+	return errs
+}
+
+// Validate - The CRI-O minor version follows the kubernetes minor version (CRI-O v1.24.x runs kubernetes v1.24.x)
+func (crio) Validate(k *model.KoreOnToml) []error {
+	errs := []error{}
+
+	crioVersion := k.SupportVersion.PackageVersion.Crio
+	if crioVersion == "" {
+		errs = append(errs, fmt.Errorf("cri-o is not supported on kubernetes %s", k.Kubernetes.Version))
+	} else if err := checkMinorVersion(crioVersion, k.Kubernetes.Version); err != nil {
+		errs = append(errs, err)
+	}
+
+	prefixes := map[string]bool{}
+	for i, r := range k.Kubernetes.Crio.Registries {
+		name := fmt.Sprintf("registries[%d]", i)
+		if r.Prefix == "" {
+			errs = append(errs, fmt.Errorf("%s > prefix is required", name))
+		} else if !registryRegex.MatchString(r.Prefix) {
+			errs = append(errs, fmt.Errorf("%s > prefix %q is not a registry (host[:port][/path])", name, r.Prefix))
+		} else if prefixes[r.Prefix] {
+			errs = append(errs, fmt.Errorf("%s > prefix %q is duplicated", name, r.Prefix))
+		}
+		prefixes[r.Prefix] = true
+
+		for j, m := range r.Mirrors {
+			if !registryRegex.MatchString(m) || strings.HasPrefix(m, "*.") {
+				errs = append(errs, fmt.Errorf("%s > mirrors[%d] %q is not a registry (host[:port][/path])", name, j, m))
+			} else if m == r.Prefix {
+				errs = append(errs, fmt.Errorf("%s > mirrors[%d] %q is the prefix", name, j, m))
+			}
+		}
+		if (r.Username == "") != (r.Password == "") {
+			errs = append(errs, fmt.Errorf("%s > username and password are required together", name))
+		}
+		if r.Username != "" && len(r.Mirrors) == 0 && strings.HasPrefix(r.Prefix, "*.") {
+			errs = append(errs, fmt.Errorf("%s > the credential of the wildcard prefix %q needs mirrors", name, r.Prefix))
+		}
+	}
+	return errs
+}
+
+// ===== [ Private Functions ] =====
+
+// crioSet - [kubernetes.cri-o] is set
+func crioSet(k *model.KoreOnToml) bool {
+	return k.Kubernetes.Crio.Version != "" || len(k.Kubernetes.Crio.Registries) > 0
+}
+
+// checkMinorVersion - The runtime and kubernetes have the same minor version
+func checkMinorVersion(runtimeVersion string, k8sVersion string) error {
+	rv, err := version.Parse(runtimeVersion)
+	if err != nil {
+		return fmt.Errorf("version %q: %s", runtimeVersion, err.Error())
+	}
+	kv, err := version.Parse(k8sVersion)
+	if err != nil {
+		return fmt.Errorf("kubernetes version %q: %s", k8sVersion, err.Error())
+	}
+	if rv.Major != kv.Major || rv.Minor != kv.Minor {
+		return fmt.Errorf("cri-o %s does not match kubernetes %s (cri-o v%d.%d.x)", runtimeVersion, k8sVersion, kv.Major, kv.Minor)
+	}
+	return nil
+}
+
+// ===== [ Public Functions ] =====
+
+// Get - Runtime of the container-runtime ("" is containerd)
+func Get(runtime string) (Runtime, error) {
+	switch runtime {
+	case "", TypeContainerd:
+		return containerd{}, nil
+	case TypeCrio:
+		return crio{}, nil
+	}
+	return nil, fmt.Errorf("container-runtime %q is not supported (%s, %s)", runtime, TypeContainerd, TypeCrio)
+}
@@ -0,0 +1,136 @@
+package cri
+
+import (
+	"strings"
+	"testing"
+
+	"kore-on/pkg/model"
+)
+
+func testToml(runtime string) *model.KoreOnToml {
+	k := &model.KoreOnToml{}
+	k.Kubernetes.Version = "v1.24.10"
+	k.Kubernetes.ContainerRuntime = runtime
+	k.SupportVersion.PackageVersion.Containerd = "1.6.20"
+	k.SupportVersion.PackageVersion.Crio = "v1.24.6"
+	return k
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		runtime string
+		change  func(k *model.KoreOnToml)
+		err     string
+	}{
+		{name: "containerd", runtime: ""},
+		{name: "cri-o", runtime: TypeCrio},
+		{
+			name:    "cri-o section of containerd",
+			runtime: TypeContainerd,
+			change:  func(k *model.KoreOnToml) { k.Kubernetes.Crio.Version = "v1.24.6" },
+			err:     "[kubernetes.cri-o] is set, but the container-runtime is containerd",
+		},
+		{
+			name:    "containerd not supported",
+			runtime: TypeContainerd,
+			change:  func(k *model.KoreOnToml) { k.SupportVersion.PackageVersion.Containerd = "" },
+			err:     "containerd is not supported on kubernetes v1.24.10",
+		},
+		{
+			name:    "cri-o not supported",
+			runtime: TypeCrio,
+			change:  func(k *model.KoreOnToml) { k.SupportVersion.PackageVersion.Crio = "" },
+			err:     "cri-o is not supported on kubernetes v1.24.10",
+		},
+		{
+			name:    "cri-o minor version",
+			runtime: TypeCrio,
+			change:  func(k *model.KoreOnToml) { k.SupportVersion.PackageVersion.Crio = "v1.25.2" },
+			err:     "cri-o v1.25.2 does not match kubernetes v1.24.10 (cri-o v1.24.x)",
+		},
+		{
+			name:    "registries",
+			runtime: TypeCrio,
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Crio.Registries = []model.CrioRegistry{
+					{Prefix: "docker.io", Mirrors: []string{"harbor.local/docker-hub"}, Username: "robot", Password: "secret"},
+					{Prefix: "*.example.com", Mirrors: []string{"harbor.local:5000/example"}},
+				}
+			},
+		},
+		{
+			name:    "registry prefix",
+			runtime: TypeCrio,
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Crio.Registries = []model.CrioRegistry{{Prefix: "https://docker.io"}}
+			},
+			err: `registries[0] > prefix "https://docker.io" is not a registry`,
+		},
+		{
+			name:    "duplicated prefix",
+			runtime: TypeCrio,
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Crio.Registries = []model.CrioRegistry{{Prefix: "quay.io"}, {Prefix: "quay.io"}}
+			},
+			err: `registries[1] > prefix "quay.io" is duplicated`,
+		},
+		{
+			name:    "wildcard mirror",
+			runtime: TypeCrio,
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Crio.Registries = []model.CrioRegistry{{Prefix: "quay.io", Mirrors: []string{"*.harbor.local"}}}
+			},
+			err: `registries[0] > mirrors[0] "*.harbor.local" is not a registry`,
+		},
+		{
+			name:    "mirror of the prefix",
+			runtime: TypeCrio,
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Crio.Registries = []model.CrioRegistry{{Prefix: "quay.io", Mirrors: []string{"quay.io"}}}
+			},
+			err: `registries[0] > mirrors[0] "quay.io" is the prefix`,
+		},
+		{
+			name:    "credential pair",
+			runtime: TypeCrio,
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Crio.Registries = []model.CrioRegistry{{Prefix: "quay.io", Username: "robot"}}
+			},
+			err: "registries[0] > username and password are required together",
+		},
+		{
+			name:    "credential of a wildcard prefix",
+			runtime: TypeCrio,
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Crio.Registries = []model.CrioRegistry{{Prefix: "*.example.com", Username: "robot", Password: "secret"}}
+			},
+			err: `registries[0] > the credential of the wildcard prefix "*.example.com" needs mirrors`,
+		},
+	}
+	for _, c := range cases {
+		k := testToml(c.runtime)
+		if c.change != nil {
+			c.change(k)
+		}
+		runtime, err := Get(c.runtime)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		errs := []string{}
+		for _, err := range runtime.Validate(k) {
+			errs = append(errs, err.Error())
+		}
+		switch {
+		case c.err == "" && len(errs) > 0:
+			t.Errorf("%s: Validate() = %v, want no errors", c.name, errs)
+		case c.err != "" && (len(errs) != 1 || !strings.Contains(errs[0], c.err)):
+			t.Errorf("%s: Validate() = %v, want one error with %q", c.name, errs, c.err)
+		}
+	}
+
+	if _, err := Get("docker"); err == nil || !strings.Contains(err.Error(), `container-runtime "docker" is not supported`) {
+		t.Errorf("Get(docker) = %v, want an error", err)
+	}
+}
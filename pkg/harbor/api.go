@@ -0,0 +1,268 @@
+package harbor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ===== [ Constants and Variables ] =====
+
+// pageSize - Page size of list requests (maximum of the Harbor API)
+const pageSize = 100
+
+// ===== [ Types ] =====
+
+// Project - Harbor project
+type Project struct {
+	ProjectID  int64             `json:"project_id,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	RegistryID int64             `json:"registry_id,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// ProjectReq - Request body of the project create and update
+type ProjectReq struct {
+	ProjectName  string            `json:"project_name,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	StorageLimit *int64            `json:"storage_limit,omitempty"`
+	RegistryID   *int64            `json:"registry_id,omitempty"`
+}
+
+// Quota - Storage quota of a project (bytes, -1: unlimited)
+type Quota struct {
+	ID   int64            `json:"id,omitempty"`
+	Hard map[string]int64 `json:"hard"`
+}
+
+// Registry - Registry endpoint (upstream of proxy-cache projects and replications)
+type Registry struct {
+	ID         int64               `json:"id,omitempty"`
+	Name       string              `json:"name"`
+	Type       string              `json:"type"`
+	URL        string              `json:"url"`
+	Insecure   bool                `json:"insecure"`
+	Credential *RegistryCredential `json:"credential,omitempty"`
+}
+
+// RegistryCredential - Credential of a registry endpoint
+type RegistryCredential struct {
+	Type         string `json:"type"`
+	AccessKey    string `json:"access_key"`
+	AccessSecret string `json:"access_secret"`
+}
+
+// RegistryUpdate - Request body of the registry endpoint update
+type RegistryUpdate struct {
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	Insecure       bool   `json:"insecure"`
+	CredentialType string `json:"credential_type"`
+	AccessKey      string `json:"access_key"`
+	AccessSecret   string `json:"access_secret,omitempty"`
+}
+
+// Robot - Robot account
+type Robot struct {
+	ID          int64             `json:"id,omitempty"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Duration    int               `json:"duration"`
+	Level       string            `json:"level"`
+	Disable     bool              `json:"disable"`
+	Permissions []RobotPermission `json:"permissions"`
+}
+
+// RobotPermission - Permissions of a robot account in one project
+type RobotPermission struct {
+	Kind      string        `json:"kind"`
+	Namespace string        `json:"namespace"`
+	Access    []RobotAccess `json:"access"`
+}
+
+// RobotAccess - Action on a resource
+type RobotAccess struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// RobotCreated - Response of the robot account create. The secret is only returned here.
+type RobotCreated struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Secret string `json:"secret"`
+}
+
+// RetentionPolicy - Tag retention policy of a project
+type RetentionPolicy struct {
+	ID        int64            `json:"id,omitempty"`
+	Algorithm string           `json:"algorithm"`
+	Rules     []RetentionRule  `json:"rules"`
+	Trigger   RetentionTrigger `json:"trigger"`
+	Scope     RetentionScope   `json:"scope"`
+}
+
+// RetentionRule - Rule of a retention policy
+type RetentionRule struct {
+	Disabled       bool                           `json:"disabled"`
+	Action         string                         `json:"action"`
+	Template       string                         `json:"template"`
+	Params         map[string]interface{}         `json:"params"`
+	TagSelectors   []RetentionSelector            `json:"tag_selectors"`
+	ScopeSelectors map[string][]RetentionSelector `json:"scope_selectors"`
+}
+
+// RetentionSelector - Tag or repository selector of a retention rule
+type RetentionSelector struct {
+	Kind       string `json:"kind"`
+	Decoration string `json:"decoration"`
+	Pattern    string `json:"pattern"`
+	Extras     string `json:"extras,omitempty"`
+}
+
+// RetentionTrigger - Schedule of a retention policy
+type RetentionTrigger struct {
+	Kind     string                 `json:"kind"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// RetentionScope - Project of a retention policy
+type RetentionScope struct {
+	Level string `json:"level"`
+	Ref   int64  `json:"ref"`
+}
+
+// ===== [ Implementations ] =====
+
+// list - GET every page of a list request and decode the items into out (pointer to a slice)
+func (c *Client) list(p string, query url.Values, out interface{}) error {
+	items := []json.RawMessage{}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("page_size", strconv.Itoa(pageSize))
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		result := []json.RawMessage{}
+		if _, err := c.do(http.MethodGet, p+"?"+query.Encode(), nil, &result); err != nil {
+			return err
+		}
+		items = append(items, result...)
+		if len(result) < pageSize {
+			break
+		}
+	}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// Projects - All projects
+func (c *Client) Projects() ([]Project, error) {
+	projects := []Project{}
+	if err := c.list("/projects", nil, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// CreateProject - Create a project and return its ID
+func (c *Client) CreateProject(req ProjectReq) (int64, error) {
+	return c.create("/projects", req)
+}
+
+// UpdateProject - Update the metadata of a project
+func (c *Client) UpdateProject(id int64, req ProjectReq) error {
+	_, err := c.do(http.MethodPut, fmt.Sprintf("/projects/%d", id), req, nil)
+	return err
+}
+
+// ProjectQuota - Storage quota of a project
+func (c *Client) ProjectQuota(id int64) (*Quota, error) {
+	query := url.Values{"reference": {"project"}, "reference_id": {strconv.FormatInt(id, 10)}}
+	quotas := []Quota{}
+	if err := c.list("/quotas", query, &quotas); err != nil {
+		return nil, err
+	}
+	if len(quotas) == 0 {
+		return nil, fmt.Errorf("quota of project %d: %w", id, ErrNotFound)
+	}
+	return &quotas[0], nil
+}
+
+// UpdateQuota - Set the storage limit of a quota (bytes, -1: unlimited)
+func (c *Client) UpdateQuota(id int64, storage int64) error {
+	_, err := c.do(http.MethodPut, fmt.Sprintf("/quotas/%d", id), Quota{Hard: map[string]int64{"storage": storage}}, nil)
+	return err
+}
+
+// Registries - All registry endpoints
+func (c *Client) Registries() ([]Registry, error) {
+	registries := []Registry{}
+	if err := c.list("/registries", nil, &registries); err != nil {
+		return nil, err
+	}
+	return registries, nil
+}
+
+// CreateRegistry - Create a registry endpoint and return its ID
+func (c *Client) CreateRegistry(r Registry) (int64, error) {
+	return c.create("/registries", r)
+}
+
+// UpdateRegistry - Update a registry endpoint
+func (c *Client) UpdateRegistry(id int64, r RegistryUpdate) error {
+	_, err := c.do(http.MethodPut, fmt.Sprintf("/registries/%d", id), r, nil)
+	return err
+}
+
+// ProjectRobots - Robot accounts of a project
+func (c *Client) ProjectRobots(projectID int64) ([]Robot, error) {
+	query := url.Values{"q": {fmt.Sprintf("Level=project,ProjectID=%d", projectID)}}
+	robots := []Robot{}
+	if err := c.list("/robots", query, &robots); err != nil {
+		return nil, err
+	}
+	return robots, nil
+}
+
+// CreateRobot - Create a robot account. The returned secret cannot be read again.
+func (c *Client) CreateRobot(r Robot) (*RobotCreated, error) {
+	created := &RobotCreated{}
+	if _, err := c.do(http.MethodPost, "/robots", r, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateRobot - Update the description, duration and permissions of a robot account
+func (c *Client) UpdateRobot(r Robot) error {
+	_, err := c.do(http.MethodPut, fmt.Sprintf("/robots/%d", r.ID), r, nil)
+	return err
+}
+
+// Retention - Retention policy by ID
+func (c *Client) Retention(id int64) (*RetentionPolicy, error) {
+	policy := &RetentionPolicy{}
+	if _, err := c.do(http.MethodGet, fmt.Sprintf("/retentions/%d", id), nil, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// CreateRetention - Create the retention policy of a project and return its ID
+func (c *Client) CreateRetention(policy RetentionPolicy) (int64, error) {
+	return c.create("/retentions", policy)
+}
+
+// UpdateRetention - Replace a retention policy
+func (c *Client) UpdateRetention(id int64, policy RetentionPolicy) error {
+	policy.ID = id
+	_, err := c.do(http.MethodPut, fmt.Sprintf("/retentions/%d", id), policy, nil)
+	return err
+}
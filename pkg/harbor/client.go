@@ -0,0 +1,187 @@
+// Package harbor - Harbor v2 REST API client for the day-2 management of the private registry
+package harbor
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ===== [ Constants and Variables ] =====
+
+// apiPath - Base path of the Harbor v2 API (Harbor v2.0 and later)
+const apiPath = "/api/v2.0"
+
+// ErrNotFound is returned when the requested resource does not exist
+var ErrNotFound = errors.New("not found")
+
+// ===== [ Types ] =====
+
+// Options - Connection options of a Harbor instance
+type Options struct {
+	URL      string // https://harbor.local
+	Username string
+	Password string
+	CAFile   string
+	Insecure bool // skip TLS verification
+}
+
+// Client - Harbor v2 REST API client
+type Client struct {
+	URL     string
+	options Options
+	client  *http.Client
+}
+
+// APIError - Error response of the Harbor API
+type APIError struct {
+	Method string
+	Path   string
+	Status int
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// ===== [ Implementations ] =====
+
+func (e *APIError) Error() string {
+	messages := []string{}
+	for _, m := range e.Errors {
+		messages = append(messages, m.Message)
+	}
+	if len(messages) == 0 {
+		messages = append(messages, http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("harbor: %s %s: %d %s", e.Method, e.Path, e.Status, strings.Join(messages, ", "))
+}
+
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.Status == http.StatusNotFound
+}
+
+// do - Send a request to the API. in is sent as JSON, a JSON response is decoded into out.
+// The location header of a created resource is returned.
+func (c *Client) do(method string, p string, in interface{}, out interface{}) (string, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return "", err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.URL+apiPath+p, body)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(c.options.Username, c.options.Password)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{Method: method, Path: p, Status: resp.StatusCode}
+		b, _ := ioutil.ReadAll(resp.Body)
+		_ = json.Unmarshal(b, apiErr)
+		return "", apiErr
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+			return "", fmt.Errorf("harbor: %s %s: %s", method, p, err.Error())
+		}
+	}
+	return resp.Header.Get("Location"), nil
+}
+
+// create - POST a resource and return the ID of its location header (/api/v2.0/projects/12 -> 12)
+func (c *Client) create(p string, in interface{}) (int64, error) {
+	location, err := c.do(http.MethodPost, p, in, nil)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(path.Base(location), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("harbor: POST %s: unexpected location %q", p, location)
+	}
+	return id, nil
+}
+
+// Ping - Check the connection (GET /ping) and, when a username is given, the credentials
+func (c *Client) Ping() error {
+	if _, err := c.do(http.MethodGet, "/ping", nil, nil); err != nil {
+		return err
+	}
+	if c.options.Username == "" {
+		return nil
+	}
+	_, err := c.do(http.MethodGet, "/users/current", nil, nil)
+	return err
+}
+
+// SystemInfo - Version of the Harbor instance (also checks the connection and credentials)
+func (c *Client) SystemInfo() (string, error) {
+	info := struct {
+		HarborVersion string `json:"harbor_version"`
+	}{}
+	if _, err := c.do(http.MethodGet, "/users/current", nil, nil); err != nil {
+		return "", err
+	}
+	if _, err := c.do(http.MethodGet, "/systeminfo", nil, &info); err != nil {
+		return "", err
+	}
+	return info.HarborVersion, nil
+}
+
+// ===== [ Public Functions ] =====
+
+// NewClient - Harbor API client
+func NewClient(options Options) (*Client, error) {
+	if !strings.HasPrefix(options.URL, "http://") && !strings.HasPrefix(options.URL, "https://") {
+		options.URL = "https://" + options.URL
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.Insecure}
+	if options.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificate found", options.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &Client{
+		URL:     strings.TrimSuffix(options.URL, "/"),
+		options: options,
+		client:  &http.Client{Transport: transport, Timeout: 60 * time.Second},
+	}, nil
+}
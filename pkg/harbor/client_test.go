@@ -0,0 +1,68 @@
+package harbor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testHarbor - Harbor with /ping and /users/current of admin:Harbor12345
+func testHarbor(t *testing.T) *httptest.Server {
+	t.Helper()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case apiPath + "/ping":
+			w.Write([]byte("Pong"))
+		case apiPath + "/users/current":
+			if username, password, ok := r.BasicAuth(); !ok || username != "admin" || password != "Harbor12345" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"errors":[{"code":"UNAUTHORIZED","message":"unauthorized"}]}`))
+				return
+			}
+			w.Write([]byte(`{"username":"admin"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestPing(t *testing.T) {
+	s := testHarbor(t)
+
+	cases := []struct {
+		name     string
+		url      string
+		username string
+		password string
+		err      string
+	}{
+		{name: "credentials", url: s.URL, username: "admin", password: "Harbor12345"},
+		{name: "anonymous", url: s.URL},
+		{name: "wrong password", url: s.URL, username: "admin", password: "wrong", err: "401 unauthorized"},
+	}
+	for _, c := range cases {
+		client, err := NewClient(Options{URL: c.url, Username: c.username, Password: c.password})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = client.Ping()
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%s: Ping() = %v, want nil", c.name, err)
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			t.Errorf("%s: Ping() = %v, want an error with %q", c.name, err, c.err)
+		}
+	}
+
+	client, err := NewClient(Options{URL: s.URL + "/other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Ping() = %v, want not found", err)
+	}
+}
@@ -0,0 +1,577 @@
+package harbor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kore-on/pkg/model"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+	ActionSkip      = "skip"
+	ActionUnmanaged = "unmanaged"
+
+	// DefaultRetentionSchedule - Daily at midnight (Harbor cron with seconds)
+	DefaultRetentionSchedule = "0 0 0 * * *"
+)
+
+// ProxyCacheTypes - Upstream registry types supported by proxy-cache projects
+var ProxyCacheTypes = []string{
+	"docker-hub", "docker-registry", "harbor", "aws-ecr", "azure-acr", "google-gcr", "quay", "jfrog-artifactory", "github-ghcr",
+}
+
+// RobotPermissions - Robot permissions of koreon.toml and the project resources and actions they grant
+var RobotPermissions = map[string][]RobotAccess{
+	"pull":       {{Resource: "repository", Action: "pull"}},
+	"push":       {{Resource: "repository", Action: "push"}},
+	"delete":     {{Resource: "repository", Action: "delete"}, {Resource: "artifact", Action: "delete"}},
+	"chart-pull": {{Resource: "helm-chart", Action: "read"}},
+	"chart-push": {{Resource: "helm-chart-version", Action: "create"}},
+}
+
+// ===== [ Types ] =====
+
+// Change - Planned change of one harbor resource
+type Change struct {
+	Kind   string // project, endpoint, robot, retention
+	Name   string
+	Action string
+	Detail string
+
+	apply func() (string, error)
+}
+
+// Plan - Changes to bring harbor to the state of [private-registry.projects]
+type Plan struct {
+	Changes []Change
+}
+
+// ===== [ Implementations ] =====
+
+// Pending - Number of changes to apply
+func (p *Plan) Pending() int {
+	n := 0
+	for _, ch := range p.Changes {
+		if ch.apply != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Apply - Apply the changes in order. report is called with the output of each applied change
+// (e.g. the secret of a new robot account). Stops at the first error.
+func (p *Plan) Apply(report func(ch Change, output string)) error {
+	for _, ch := range p.Changes {
+		if ch.apply == nil {
+			continue
+		}
+		output, err := ch.apply()
+		if err != nil {
+			return fmt.Errorf("%s %s %s: %w", ch.Action, ch.Kind, ch.Name, err)
+		}
+		report(ch, output)
+	}
+	return nil
+}
+
+func (p *Plan) add(ch Change) {
+	p.Changes = append(p.Changes, ch)
+}
+
+// projectSettings - Change of the public flag and storage limit of an existing project
+func (c *Client) projectSettings(project Project, want model.RegistryProject) (Change, error) {
+	ch := Change{Kind: "project", Name: project.Name, Action: ActionUnchanged}
+
+	quota, err := c.ProjectQuota(project.ProjectID)
+	if err != nil {
+		return ch, err
+	}
+
+	diff := []string{}
+	public := strconv.FormatBool(want.Public)
+	if project.Metadata["public"] != public {
+		diff = append(diff, fmt.Sprintf("public: %s -> %s", project.Metadata["public"], public))
+	}
+	limit := storageLimit(want.StorageLimit)
+	if quota.Hard["storage"] != limit {
+		diff = append(diff, fmt.Sprintf("storage-limit: %s -> %s", storageString(quota.Hard["storage"]), storageString(limit)))
+	}
+	if len(diff) == 0 {
+		return ch, nil
+	}
+
+	ch.Action, ch.Detail = ActionUpdate, strings.Join(diff, ", ")
+	ch.apply = func() (string, error) {
+		if err := c.UpdateProject(project.ProjectID, ProjectReq{Metadata: map[string]string{"public": public}}); err != nil {
+			return "", err
+		}
+		return "", c.UpdateQuota(quota.ID, limit)
+	}
+	return ch, nil
+}
+
+// createProject - Change to create a project. registryID is read when the change is applied
+// so that the endpoint of a proxy-cache project can be created by a previous change.
+func (c *Client) createProject(name string, want model.RegistryProject, registryID *int64) Change {
+	limit := storageLimit(want.StorageLimit)
+	detail := fmt.Sprintf("public: %t, storage-limit: %s", want.Public, storageString(limit))
+	if want.ProxyCache != nil {
+		detail += ", proxy-cache: " + want.ProxyCache.URL
+	}
+
+	return Change{
+		Kind:   "project",
+		Name:   name,
+		Action: ActionCreate,
+		Detail: detail,
+		apply: func() (string, error) {
+			req := ProjectReq{
+				ProjectName:  name,
+				Metadata:     map[string]string{"public": strconv.FormatBool(want.Public)},
+				StorageLimit: &limit,
+			}
+			if registryID != nil {
+				req.RegistryID = registryID
+			}
+			_, err := c.CreateProject(req)
+			return "", err
+		},
+	}
+}
+
+// PlanProjects - Create the projects without proxy-cache and update public and storage-limit.
+// Projects are never deleted; projects missing in koreon.toml are listed as unmanaged.
+func (c *Client) PlanProjects(projects map[string]model.RegistryProject) (*Plan, error) {
+	existing, err := c.projectsByName()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	for _, name := range projectNames(projects) {
+		want := projects[name]
+		project, ok := existing[name]
+
+		switch {
+		case want.ProxyCache != nil:
+			plan.add(Change{Kind: "project", Name: name, Action: ActionSkip, Detail: "proxy-cache project (registry-manager proxy-cache)"})
+		case !ok:
+			plan.add(c.createProject(name, want, nil))
+		case project.RegistryID != 0:
+			return nil, fmt.Errorf("project %s is a proxy-cache project in harbor, but has no proxy-cache in koreon.toml", name)
+		default:
+			ch, err := c.projectSettings(project, want)
+			if err != nil {
+				return nil, err
+			}
+			plan.add(ch)
+		}
+	}
+
+	unmanaged := []string{}
+	for name := range existing {
+		if _, ok := projects[name]; !ok {
+			unmanaged = append(unmanaged, name)
+		}
+	}
+	sort.Strings(unmanaged)
+	for _, name := range unmanaged {
+		plan.add(Change{Kind: "project", Name: name, Action: ActionUnmanaged, Detail: "not in koreon.toml"})
+	}
+	return plan, nil
+}
+
+// PlanProxyCache - Create or update the upstream registry endpoints and the proxy-cache projects.
+// An endpoint is named "<project>-upstream".
+func (c *Client) PlanProxyCache(projects map[string]model.RegistryProject) (*Plan, error) {
+	existing, err := c.projectsByName()
+	if err != nil {
+		return nil, err
+	}
+	registries, err := c.Registries()
+	if err != nil {
+		return nil, err
+	}
+	endpoints := map[string]Registry{}
+	for _, r := range registries {
+		endpoints[r.Name] = r
+	}
+
+	plan := &Plan{}
+	for _, name := range projectNames(projects) {
+		want := projects[name]
+		if want.ProxyCache == nil {
+			continue
+		}
+		upstream := *want.ProxyCache
+		endpointName := name + "-upstream"
+		endpoint, ok := endpoints[endpointName]
+		registryID := endpoint.ID
+
+		switch {
+		case !ok:
+			plan.add(Change{
+				Kind:   "endpoint",
+				Name:   endpointName,
+				Action: ActionCreate,
+				Detail: fmt.Sprintf("%s %s", upstream.Type, upstream.URL),
+				apply: func() (string, error) {
+					r := Registry{Name: endpointName, Type: upstream.Type, URL: upstream.URL, Insecure: upstream.Insecure}
+					if upstream.Username != "" {
+						r.Credential = &RegistryCredential{Type: "basic", AccessKey: upstream.Username, AccessSecret: upstream.Password}
+					}
+					id, err := c.CreateRegistry(r)
+					registryID = id
+					return "", err
+				},
+			})
+		case endpoint.Type != upstream.Type:
+			return nil, fmt.Errorf("endpoint %s is of type %s in harbor, the type cannot be changed to %s", endpointName, endpoint.Type, upstream.Type)
+		default:
+			diff := []string{}
+			if endpoint.URL != upstream.URL {
+				diff = append(diff, fmt.Sprintf("url: %s -> %s", endpoint.URL, upstream.URL))
+			}
+			if endpoint.Insecure != upstream.Insecure {
+				diff = append(diff, fmt.Sprintf("insecure: %t -> %t", endpoint.Insecure, upstream.Insecure))
+			}
+			accessKey := ""
+			if endpoint.Credential != nil {
+				accessKey = endpoint.Credential.AccessKey
+			}
+			if accessKey != upstream.Username {
+				diff = append(diff, fmt.Sprintf("username: %q -> %q", accessKey, upstream.Username))
+			}
+
+			ch := Change{Kind: "endpoint", Name: endpointName, Action: ActionUnchanged}
+			if len(diff) > 0 {
+				ch.Action, ch.Detail = ActionUpdate, strings.Join(diff, ", ")
+				ch.apply = func() (string, error) {
+					update := RegistryUpdate{Name: endpointName, URL: upstream.URL, Insecure: upstream.Insecure, CredentialType: "basic", AccessKey: upstream.Username, AccessSecret: upstream.Password}
+					return "", c.UpdateRegistry(endpoint.ID, update)
+				}
+			}
+			plan.add(ch)
+		}
+
+		project, ok := existing[name]
+		switch {
+		case !ok:
+			plan.add(c.createProject(name, want, &registryID))
+		case project.RegistryID == 0:
+			return nil, fmt.Errorf("project %s already exists without proxy-cache in harbor and cannot be converted", name)
+		case project.RegistryID != endpoint.ID:
+			return nil, fmt.Errorf("project %s is a proxy-cache of another endpoint (id %d) in harbor", name, project.RegistryID)
+		default:
+			ch, err := c.projectSettings(project, want)
+			if err != nil {
+				return nil, err
+			}
+			plan.add(ch)
+		}
+	}
+	return plan, nil
+}
+
+// PlanRobots - Create or update the robot accounts of the projects. Robot accounts missing
+// in koreon.toml are listed as unmanaged.
+func (c *Client) PlanRobots(projects map[string]model.RegistryProject) (*Plan, error) {
+	existing, err := c.projectsByName()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	for _, name := range projectNames(projects) {
+		want := projects[name]
+		if len(want.Robots) == 0 {
+			continue
+		}
+		project, ok := existing[name]
+		if !ok {
+			return nil, fmt.Errorf("project %s does not exist. Run 'registry-manager project' or 'registry-manager proxy-cache' first", name)
+		}
+
+		robots, err := c.ProjectRobots(project.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		declared := map[string]bool{}
+		robotNames := []string{}
+		for robotName := range want.Robots {
+			robotNames = append(robotNames, robotName)
+		}
+		sort.Strings(robotNames)
+
+		for _, robotName := range robotNames {
+			wantRobot := want.Robots[robotName]
+			robot := Robot{
+				Name:        robotName,
+				Description: wantRobot.Description,
+				Duration:    robotDuration(wantRobot.Duration),
+				Level:       "project",
+				Permissions: []RobotPermission{{Kind: "project", Namespace: name, Access: robotAccess(wantRobot.Permissions)}},
+			}
+			detail := fmt.Sprintf("permissions: %s, duration: %s", strings.Join(wantRobot.Permissions, ","), durationString(robot.Duration))
+
+			current, ok := findRobot(robots, name, robotName)
+			if !ok {
+				plan.add(Change{
+					Kind:   "robot",
+					Name:   name + "+" + robotName,
+					Action: ActionCreate,
+					Detail: detail,
+					apply: func() (string, error) {
+						created, err := c.CreateRobot(robot)
+						if err != nil {
+							return "", err
+						}
+						return fmt.Sprintf("%s secret: %s (shown only once)", created.Name, created.Secret), nil
+					},
+				})
+				continue
+			}
+			declared[current.Name] = true
+
+			ch := Change{Kind: "robot", Name: current.Name, Action: ActionUnchanged}
+			if current.Description != robot.Description || current.Duration != robot.Duration || !sameAccess(current.Permissions, robot.Permissions) {
+				robot.ID, robot.Name, robot.Disable = current.ID, current.Name, current.Disable
+				ch.Action, ch.Detail = ActionUpdate, detail
+				ch.apply = func() (string, error) {
+					return "", c.UpdateRobot(robot)
+				}
+			}
+			plan.add(ch)
+		}
+
+		for _, robot := range robots {
+			if !declared[robot.Name] {
+				plan.add(Change{Kind: "robot", Name: robot.Name, Action: ActionUnmanaged, Detail: "not in koreon.toml"})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// PlanRetention - Create or replace the tag retention policies of the projects
+func (c *Client) PlanRetention(projects map[string]model.RegistryProject) (*Plan, error) {
+	existing, err := c.projectsByName()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	for _, name := range projectNames(projects) {
+		want := projects[name]
+		if want.Retention == nil {
+			continue
+		}
+		project, ok := existing[name]
+		if !ok {
+			return nil, fmt.Errorf("project %s does not exist. Run 'registry-manager project' or 'registry-manager proxy-cache' first", name)
+		}
+
+		policy := retentionPolicy(project.ProjectID, *want.Retention)
+		detail := retentionString(policy)
+
+		id, _ := strconv.ParseInt(project.Metadata["retention_id"], 10, 64)
+		if id == 0 {
+			plan.add(Change{
+				Kind:   "retention",
+				Name:   name,
+				Action: ActionCreate,
+				Detail: detail,
+				apply: func() (string, error) {
+					_, err := c.CreateRetention(policy)
+					return "", err
+				},
+			})
+			continue
+		}
+
+		current, err := c.Retention(id)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		ch := Change{Kind: "retention", Name: name, Action: ActionUnchanged, Detail: detail}
+		if current == nil || retentionString(*current) != detail {
+			ch.Action = ActionUpdate
+			ch.apply = func() (string, error) {
+				return "", c.UpdateRetention(id, policy)
+			}
+		}
+		plan.add(ch)
+	}
+	return plan, nil
+}
+
+func (c *Client) projectsByName() (map[string]Project, error) {
+	projects, err := c.Projects()
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]Project{}
+	for _, p := range projects {
+		result[p.Name] = p
+	}
+	return result, nil
+}
+
+// ===== [ Public Functions ] =====
+
+// IsProxyCacheType - Whether proxy-cache projects support the upstream registry type
+func IsProxyCacheType(t string) bool {
+	for _, v := range ProxyCacheTypes {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// ===== [ Private Functions ] =====
+
+func projectNames(projects map[string]model.RegistryProject) []string {
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// storageLimit - GiB of koreon.toml to bytes (-1: unlimited)
+func storageLimit(gib int) int64 {
+	if gib <= 0 {
+		return -1
+	}
+	return int64(gib) << 30
+}
+
+func storageString(bytes int64) string {
+	if bytes < 0 {
+		return "unlimited"
+	}
+	if bytes%(1<<30) == 0 {
+		return fmt.Sprintf("%dGiB", bytes>>30)
+	}
+	return fmt.Sprintf("%dB", bytes)
+}
+
+// robotDuration - Days of koreon.toml to the robot duration (-1: never expires)
+func robotDuration(days int) int {
+	if days <= 0 {
+		return -1
+	}
+	return days
+}
+
+func durationString(days int) string {
+	if days < 0 {
+		return "never expires"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+func robotAccess(permissions []string) []RobotAccess {
+	access := []RobotAccess{}
+	for _, p := range permissions {
+		access = append(access, RobotPermissions[p]...)
+	}
+	return access
+}
+
+// findRobot - Robot account of a project by its short name (harbor names it "robot$<project>+<name>")
+func findRobot(robots []Robot, project string, name string) (Robot, bool) {
+	for _, r := range robots {
+		if r.Name == name || strings.HasSuffix(r.Name, "$"+project+"+"+name) {
+			return r, true
+		}
+	}
+	return Robot{}, false
+}
+
+func sameAccess(a []RobotPermission, b []RobotPermission) bool {
+	set := func(permissions []RobotPermission) string {
+		items := []string{}
+		for _, p := range permissions {
+			for _, access := range p.Access {
+				items = append(items, p.Namespace+"/"+access.Resource+"/"+access.Action)
+			}
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	}
+	return set(a) == set(b)
+}
+
+func retentionPolicy(projectID int64, r model.RegistryRetention) RetentionPolicy {
+	tags, repositories, schedule := r.Tags, r.Repositories, r.Schedule
+	if tags == "" {
+		tags = "**"
+	}
+	if repositories == "" {
+		repositories = "**"
+	}
+	if schedule == "" {
+		schedule = DefaultRetentionSchedule
+	}
+
+	rule := func(template string, n int) RetentionRule {
+		return RetentionRule{
+			Action:   "retain",
+			Template: template,
+			Params:   map[string]interface{}{template: n},
+			TagSelectors: []RetentionSelector{
+				{Kind: "doublestar", Decoration: "matches", Pattern: tags, Extras: `{"untagged":true}`},
+			},
+			ScopeSelectors: map[string][]RetentionSelector{
+				"repository": {{Kind: "doublestar", Decoration: "repoMatches", Pattern: repositories}},
+			},
+		}
+	}
+
+	policy := RetentionPolicy{
+		Algorithm: "or",
+		Rules:     []RetentionRule{},
+		Trigger:   RetentionTrigger{Kind: "Schedule", Settings: map[string]interface{}{"cron": schedule}},
+		Scope:     RetentionScope{Level: "project", Ref: projectID},
+	}
+	if r.KeepLast > 0 {
+		policy.Rules = append(policy.Rules, rule("latestPushedK", r.KeepLast))
+	}
+	if r.KeepDays > 0 {
+		policy.Rules = append(policy.Rules, rule("nDaysSinceLastPush", r.KeepDays))
+	}
+	return policy
+}
+
+// retentionString - Comparable summary of a retention policy
+func retentionString(policy RetentionPolicy) string {
+	rules := []string{}
+	for _, r := range policy.Rules {
+		if r.Disabled {
+			continue
+		}
+		tags, repositories := []string{}, []string{}
+		for _, s := range r.TagSelectors {
+			tags = append(tags, s.Pattern)
+		}
+		for _, s := range r.ScopeSelectors["repository"] {
+			repositories = append(repositories, s.Pattern)
+		}
+		rules = append(rules, fmt.Sprintf("%s=%v (repositories: %s, tags: %s)", r.Template, r.Params[r.Template],
+			strings.Join(repositories, ","), strings.Join(tags, ",")))
+	}
+	sort.Strings(rules)
+	return fmt.Sprintf("%s, schedule: %v", strings.Join(rules, " or "), policy.Trigger.Settings["cron"])
+}
@@ -0,0 +1,428 @@
+package harbor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"kore-on/pkg/model"
+)
+
+// fakeHarbor - Projects, quotas, registry endpoints, robot accounts and retention policies of the Harbor API
+type fakeHarbor struct {
+	mu         sync.Mutex
+	nextID     int64
+	projects   map[int64]*Project
+	quotas     map[int64]*Quota // by project id
+	registries map[int64]*Registry
+	robots     map[int64]*Robot
+	retentions map[int64]*RetentionPolicy
+	writes     []string // "POST /projects"
+}
+
+func newFakeHarbor(t *testing.T) (*fakeHarbor, *Client) {
+	t.Helper()
+	h := &fakeHarbor{
+		nextID:     100,
+		projects:   map[int64]*Project{},
+		quotas:     map[int64]*Quota{},
+		registries: map[int64]*Registry{},
+		robots:     map[int64]*Robot{},
+		retentions: map[int64]*RetentionPolicy{},
+	}
+	s := httptest.NewServer(h)
+	t.Cleanup(s.Close)
+
+	c, err := NewClient(Options{URL: s.URL, Username: "admin", Password: "Harbor12345"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, c
+}
+
+func (h *fakeHarbor) id() int64 {
+	h.nextID++
+	return h.nextID
+}
+
+// addProject - Existing project with its quota
+func (h *fakeHarbor) addProject(name string, public bool, storage int64, registryID int64) int64 {
+	id := h.id()
+	h.projects[id] = &Project{ProjectID: id, Name: name, RegistryID: registryID, Metadata: map[string]string{"public": strconv.FormatBool(public)}}
+	h.quotas[id] = &Quota{ID: id, Hard: map[string]int64{"storage": storage}}
+	return id
+}
+
+func (h *fakeHarbor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p := strings.TrimPrefix(r.URL.Path, apiPath)
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	id := int64(0)
+	if len(parts) > 1 {
+		id, _ = strconv.ParseInt(parts[1], 10, 64)
+	}
+	if r.Method != http.MethodGet {
+		h.writes = append(h.writes, r.Method+" /"+parts[0])
+	}
+
+	// list requests: every item on the first page
+	list := func(items interface{}) {
+		if r.URL.Query().Get("page") != "1" {
+			w.Write([]byte("[]"))
+			return
+		}
+		json.NewEncoder(w).Encode(items)
+	}
+	created := func(id int64) {
+		w.Header().Set("Location", fmt.Sprintf("%s/%s/%d", apiPath, parts[0], id))
+		w.WriteHeader(http.StatusCreated)
+	}
+	decode := func(v interface{}) bool {
+		if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return false
+		}
+		return true
+	}
+
+	switch r.Method + " " + parts[0] {
+	case "GET projects":
+		projects := []Project{}
+		for _, id := range sortedIDs(h.projects) {
+			projects = append(projects, *h.projects[id])
+		}
+		list(projects)
+	case "POST projects":
+		req := ProjectReq{}
+		if !decode(&req) {
+			return
+		}
+		registryID := int64(0)
+		if req.RegistryID != nil {
+			registryID = *req.RegistryID
+		}
+		id := h.addProject(req.ProjectName, req.Metadata["public"] == "true", *req.StorageLimit, registryID)
+		created(id)
+	case "PUT projects":
+		req := ProjectReq{}
+		if !decode(&req) {
+			return
+		}
+		for k, v := range req.Metadata {
+			h.projects[id].Metadata[k] = v
+		}
+	case "GET quotas":
+		id, _ := strconv.ParseInt(r.URL.Query().Get("reference_id"), 10, 64)
+		list([]Quota{*h.quotas[id]})
+	case "PUT quotas":
+		q := Quota{}
+		if !decode(&q) {
+			return
+		}
+		h.quotas[id].Hard = q.Hard
+	case "GET registries":
+		registries := []Registry{}
+		for _, id := range sortedIDs(h.registries) {
+			registries = append(registries, *h.registries[id])
+		}
+		list(registries)
+	case "POST registries":
+		reg := Registry{}
+		if !decode(&reg) {
+			return
+		}
+		reg.ID = h.id()
+		h.registries[reg.ID] = &reg
+		created(reg.ID)
+	case "PUT registries":
+		update := RegistryUpdate{}
+		if !decode(&update) {
+			return
+		}
+		reg := h.registries[id]
+		reg.URL, reg.Insecure = update.URL, update.Insecure
+		reg.Credential = &RegistryCredential{Type: update.CredentialType, AccessKey: update.AccessKey}
+	case "GET robots":
+		robots := []Robot{}
+		for _, id := range sortedIDs(h.robots) {
+			robot := h.robots[id]
+			if strings.HasSuffix(r.URL.Query().Get("q"), fmt.Sprintf("ProjectID=%d", h.projectID(robot.Permissions[0].Namespace))) {
+				robots = append(robots, *robot)
+			}
+		}
+		list(robots)
+	case "POST robots":
+		robot := Robot{}
+		if !decode(&robot) {
+			return
+		}
+		robot.ID = h.id()
+		robot.Name = "robot$" + robot.Permissions[0].Namespace + "+" + robot.Name
+		h.robots[robot.ID] = &robot
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(RobotCreated{ID: robot.ID, Name: robot.Name, Secret: "s3cr3t"})
+	case "PUT robots":
+		robot := Robot{}
+		if !decode(&robot) {
+			return
+		}
+		h.robots[id] = &robot
+	case "GET retentions":
+		policy, ok := h.retentions[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	case "POST retentions", "PUT retentions":
+		policy := RetentionPolicy{}
+		if !decode(&policy) {
+			return
+		}
+		if r.Method == http.MethodPost {
+			policy.ID = h.id()
+			// harbor keeps the policy id in the project metadata
+			h.projects[policy.Scope.Ref].Metadata["retention_id"] = strconv.FormatInt(policy.ID, 10)
+			created(policy.ID)
+		}
+		h.retentions[policy.ID] = &policy
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *fakeHarbor) projectID(name string) int64 {
+	for id, p := range h.projects {
+		if p.Name == name {
+			return id
+		}
+	}
+	return 0
+}
+
+func sortedIDs(m interface{}) []int64 {
+	ids := []int64{}
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		ids = append(ids, k.Int())
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// actions - "<action> <kind> <name>" of the changes
+func actions(plan *Plan) []string {
+	result := []string{}
+	for _, ch := range plan.Changes {
+		result = append(result, ch.Action+" "+ch.Kind+" "+ch.Name)
+	}
+	return result
+}
+
+// apply - Apply the plan and return the outputs of the changes
+func apply(t *testing.T, plan *Plan) []string {
+	t.Helper()
+	outputs := []string{}
+	if err := plan.Apply(func(ch Change, output string) {
+		if output != "" {
+			outputs = append(outputs, output)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return outputs
+}
+
+func TestPlanProjects(t *testing.T) {
+	h, c := newFakeHarbor(t)
+	h.addProject("library", true, -1, 0)
+	apps := h.addProject("apps", false, -1, 0)
+	h.addProject("old", false, -1, 0)
+
+	projects := map[string]model.RegistryProject{
+		"library":   {Public: true},
+		"apps":      {Public: true, StorageLimit: 10},
+		"ci":        {StorageLimit: -1},
+		"dockerhub": {ProxyCache: &model.RegistryProxyCache{Type: "docker-hub", URL: "https://hub.docker.com"}},
+	}
+	plan, err := c.PlanProjects(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"update project apps",
+		"create project ci",
+		"skip project dockerhub",
+		"unchanged project library",
+		"unmanaged project old",
+	}
+	if got := actions(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("PlanProjects() = %v, want %v", got, want)
+	}
+	if plan.Changes[0].Detail != "public: false -> true, storage-limit: unlimited -> 10GiB" {
+		t.Errorf("detail = %q", plan.Changes[0].Detail)
+	}
+	if plan.Pending() != 2 {
+		t.Errorf("Pending() = %d, want 2", plan.Pending())
+	}
+
+	apply(t, plan)
+	if h.projects[apps].Metadata["public"] != "true" || h.quotas[apps].Hard["storage"] != 10<<30 {
+		t.Errorf("apps = %+v %+v, want public and 10GiB", h.projects[apps], h.quotas[apps])
+	}
+	if q := h.quotas[h.projectID("ci")]; q == nil || q.Hard["storage"] != -1 {
+		t.Errorf("ci quota = %+v, want unlimited", q)
+	}
+
+	// the applied plan leaves nothing to change
+	plan, err = c.PlanProjects(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Pending() != 0 {
+		t.Errorf("Pending() after apply = %d, want 0 (%v)", plan.Pending(), actions(plan))
+	}
+
+	// a proxy-cache project of harbor is not changed to a normal project
+	h.addProject("mirror", false, -1, 7)
+	if _, err := c.PlanProjects(map[string]model.RegistryProject{"mirror": {}}); err == nil || !strings.Contains(err.Error(), "is a proxy-cache project in harbor") {
+		t.Errorf("PlanProjects() = %v, want a proxy-cache error", err)
+	}
+}
+
+func TestPlanProxyCache(t *testing.T) {
+	h, c := newFakeHarbor(t)
+	projects := map[string]model.RegistryProject{
+		"dockerhub": {ProxyCache: &model.RegistryProxyCache{Type: "docker-hub", URL: "https://hub.docker.com", Username: "user", Password: "token"}},
+		"library":   {},
+	}
+
+	plan, err := c.PlanProxyCache(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"create endpoint dockerhub-upstream", "create project dockerhub"}
+	if got := actions(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("PlanProxyCache() = %v, want %v", got, want)
+	}
+	apply(t, plan)
+
+	// the project is created with the id of the endpoint created before
+	project := h.projects[h.projectID("dockerhub")]
+	if project == nil || h.registries[project.RegistryID] == nil || h.registries[project.RegistryID].Name != "dockerhub-upstream" {
+		t.Fatalf("project = %+v, want the proxy-cache of dockerhub-upstream", project)
+	}
+
+	plan, err = c.PlanProxyCache(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"unchanged endpoint dockerhub-upstream", "unchanged project dockerhub"}
+	if got := actions(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("PlanProxyCache() after apply = %v, want %v", got, want)
+	}
+
+	projects["dockerhub"].ProxyCache.URL = "https://registry-1.docker.io"
+	plan, err = c.PlanProxyCache(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch := plan.Changes[0]; ch.Action != ActionUpdate || ch.Detail != "url: https://hub.docker.com -> https://registry-1.docker.io" {
+		t.Errorf("change = %+v, want the url update", ch)
+	}
+
+	projects["dockerhub"].ProxyCache.Type = "quay"
+	if _, err := c.PlanProxyCache(projects); err == nil || !strings.Contains(err.Error(), "the type cannot be changed to quay") {
+		t.Errorf("PlanProxyCache() = %v, want a type error", err)
+	}
+}
+
+func TestPlanRobots(t *testing.T) {
+	h, c := newFakeHarbor(t)
+	h.addProject("apps", false, -1, 0)
+	h.robots[1] = &Robot{ID: 1, Name: "robot$apps+deploy", Duration: -1, Level: "project",
+		Permissions: []RobotPermission{{Kind: "project", Namespace: "apps", Access: robotAccess([]string{"pull"})}}}
+	h.robots[2] = &Robot{ID: 2, Name: "robot$apps+manual", Duration: -1, Level: "project",
+		Permissions: []RobotPermission{{Kind: "project", Namespace: "apps", Access: robotAccess([]string{"pull"})}}}
+
+	projects := map[string]model.RegistryProject{
+		"apps": {Robots: map[string]model.RegistryRobot{
+			"ci":     {Permissions: []string{"pull", "push"}, Duration: 30},
+			"deploy": {Permissions: []string{"pull", "chart-pull"}},
+		}},
+	}
+	plan, err := c.PlanRobots(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"create robot apps+ci", "update robot robot$apps+deploy", "unmanaged robot robot$apps+manual"}
+	if got := actions(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("PlanRobots() = %v, want %v", got, want)
+	}
+
+	outputs := apply(t, plan)
+	if len(outputs) != 1 || outputs[0] != "robot$apps+ci secret: s3cr3t (shown only once)" {
+		t.Errorf("outputs = %v, want the secret of the new robot", outputs)
+	}
+
+	plan, err = c.PlanRobots(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Pending() != 0 {
+		t.Errorf("Pending() after apply = %d, want 0 (%v)", plan.Pending(), actions(plan))
+	}
+
+	if _, err := c.PlanRobots(map[string]model.RegistryProject{"ci": {Robots: map[string]model.RegistryRobot{"a": {}}}}); err == nil || !strings.Contains(err.Error(), "project ci does not exist") {
+		t.Errorf("PlanRobots() = %v, want a missing project error", err)
+	}
+}
+
+func TestPlanRetention(t *testing.T) {
+	h, c := newFakeHarbor(t)
+	h.addProject("apps", false, -1, 0)
+	projects := map[string]model.RegistryProject{
+		"apps": {Retention: &model.RegistryRetention{KeepLast: 10, Repositories: "apps/**"}},
+	}
+
+	plan, err := c.PlanRetention(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(plan); !reflect.DeepEqual(got, []string{"create retention apps"}) {
+		t.Errorf("PlanRetention() = %v", got)
+	}
+	if detail := plan.Changes[0].Detail; detail != "latestPushedK=10 (repositories: apps/**, tags: **), schedule: "+DefaultRetentionSchedule {
+		t.Errorf("detail = %q", detail)
+	}
+	apply(t, plan)
+
+	plan, err = c.PlanRetention(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(plan); !reflect.DeepEqual(got, []string{"unchanged retention apps"}) {
+		t.Errorf("PlanRetention() after apply = %v", got)
+	}
+
+	projects["apps"].Retention.KeepDays = 30
+	plan, err = c.PlanRetention(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(plan); !reflect.DeepEqual(got, []string{"update retention apps"}) {
+		t.Errorf("PlanRetention() of keep-days = %v", got)
+	}
+	apply(t, plan)
+	if writes := strings.Join(h.writes, ", "); writes != "POST /retentions, PUT /retentions" {
+		t.Errorf("writes = %s, want the create and the replace of the policy", writes)
+	}
+}
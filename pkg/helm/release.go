@@ -0,0 +1,170 @@
+// Package helm - Helm release state of the addon apps and the drift from addon.toml
+package helm
+
+import (
+	"encoding/json"
+	"fmt"
+	"kore-on/pkg/model"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// Drift - the release is not installed although install = true
+	DriftNotInstalled = "not installed"
+	// Drift - the release is installed although install = false
+	DriftNotDesired = "installed but install = false"
+)
+
+// ===== [ Types ] =====
+
+// Release - Item of "helm list --output json"
+type Release struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Revision   string `json:"revision"`
+	Updated    string `json:"updated"`
+	Status     string `json:"status"`
+	Chart      string `json:"chart"`
+	AppVersion string `json:"app_version"`
+}
+
+// ===== [ Public Functions ] =====
+
+// ListArgs - helm arguments to list the releases of every namespace as json
+func ListArgs(kubeconfig string) []string {
+	args := []string{"list", "--all-namespaces", "--all", "--output", "json"}
+	if kubeconfig != "" {
+		args = append(args, "--kubeconfig", kubeconfig)
+	}
+	return args
+}
+
+// ParseReleases - Parse the output of "helm list --output json"
+func ParseReleases(data []byte) ([]Release, error) {
+	releases := []Release{}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return releases, nil
+	}
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("helm list: %w", err)
+	}
+	return releases, nil
+}
+
+// ChartVersion - Chart version of the "<chart name>-<version>" chart column of helm list.
+// Without the chart name the version starts at the first "-" followed by a digit (or "v" and a digit).
+func ChartVersion(chart string, chartName string) string {
+	if chartName != "" && strings.HasPrefix(chart, chartName+"-") {
+		return strings.TrimPrefix(chart, chartName+"-")
+	}
+	for i := 0; i < len(chart)-1; i++ {
+		if chart[i] != '-' {
+			continue
+		}
+		rest := chart[i+1:]
+		if isDigit(rest[0]) || (rest[0] == 'v' && len(rest) > 1 && isDigit(rest[1])) {
+			return rest
+		}
+	}
+	return ""
+}
+
+// Desired - Desired state of every app in addon.toml, sorted by name
+func Desired(apps map[string]model.AddonApp) []model.AddonAppStatus {
+	names := []string{}
+	for name := range apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := []model.AddonAppStatus{}
+	for _, name := range names {
+		app := apps[name]
+		result = append(result, model.AddonAppStatus{
+			Name:           name,
+			Install:        app.Install,
+			Chart:          chartName(app),
+			Namespace:      app.ReleaseNamespace,
+			DesiredVersion: app.ChartVersion,
+			Drift:          []string{},
+		})
+	}
+	return result
+}
+
+// Compare - Status of every app in addon.toml with the drift between the desired and the installed release.
+// The release name is the app name.
+func Compare(apps map[string]model.AddonApp, releases []Release) []model.AddonAppStatus {
+	result := Desired(apps)
+	for i := range result {
+		status := &result[i]
+		app := apps[status.Name]
+
+		release := findRelease(releases, status.Name, app.ReleaseNamespace)
+		if release == nil {
+			status.Namespace = ""
+			if app.Install {
+				status.Drift = append(status.Drift, DriftNotInstalled)
+			}
+			continue
+		}
+
+		status.Namespace = release.Namespace
+		status.InstalledVersion = ChartVersion(release.Chart, app.ChartName)
+		status.Revision = release.Revision
+		status.Status = release.Status
+
+		if !app.Install {
+			status.Drift = append(status.Drift, DriftNotDesired)
+		}
+		if app.ChartVersion != "" && !sameVersion(app.ChartVersion, status.InstalledVersion) {
+			status.Drift = append(status.Drift, fmt.Sprintf("chart version %s, desired %s", status.InstalledVersion, app.ChartVersion))
+		}
+		if app.ReleaseNamespace != "" && app.ReleaseNamespace != release.Namespace {
+			status.Drift = append(status.Drift, fmt.Sprintf("namespace %s, desired %s", release.Namespace, app.ReleaseNamespace))
+		}
+		if release.Status != "deployed" {
+			status.Drift = append(status.Drift, "status "+release.Status)
+		}
+	}
+
+	return result
+}
+
+// ===== [ Private Functions ] =====
+
+// findRelease - Release of the name, the one in the namespace first when there are several
+func findRelease(releases []Release, name string, namespace string) *Release {
+	var found *Release
+	for i, r := range releases {
+		if r.Name != name {
+			continue
+		}
+		if found == nil || r.Namespace == namespace {
+			found = &releases[i]
+		}
+	}
+	return found
+}
+
+func chartName(app model.AddonApp) string {
+	if strings.HasSuffix(app.ChartRef, ".tgz") {
+		return filepath.Base(app.ChartRef)
+	}
+	if app.ChartRefName == "" {
+		return app.ChartName
+	}
+	return app.ChartRefName + "/" + app.ChartName
+}
+
+func sameVersion(a string, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
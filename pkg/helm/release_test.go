@@ -0,0 +1,89 @@
+package helm
+
+import (
+	"reflect"
+	"testing"
+
+	"kore-on/pkg/model"
+)
+
+func TestChartVersion(t *testing.T) {
+	cases := []struct {
+		chart     string
+		chartName string
+		version   string
+	}{
+		{chart: "ingress-nginx-4.5.2", chartName: "ingress-nginx", version: "4.5.2"},
+		{chart: "ingress-nginx-4.5.2", version: "4.5.2"},
+		{chart: "kube-prometheus-stack-45.7.1", version: "45.7.1"},
+		{chart: "cert-manager-v1.11.0", version: "v1.11.0"},
+		{chart: "k8s-dashboard-6.0.0", chartName: "kubernetes-dashboard", version: "6.0.0"},
+		{chart: "metallb", version: ""},
+	}
+	for _, c := range cases {
+		if got := ChartVersion(c.chart, c.chartName); got != c.version {
+			t.Errorf("ChartVersion(%q, %q) = %q, want %q", c.chart, c.chartName, got, c.version)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	apps := map[string]model.AddonApp{
+		"cert-manager":  {Install: true, ChartRefName: "jetstack", ChartName: "cert-manager", ChartVersion: "v1.11.0", ReleaseNamespace: "cert-manager"},
+		"ingress-nginx": {Install: true, ChartName: "ingress-nginx", ChartVersion: "4.5.2", ReleaseNamespace: "ingress-nginx"},
+		"koreboard":     {Install: false, ChartRef: "charts/koreboard-0.5.0.tgz"},
+		"metallb":       {Install: true, ChartName: "metallb", ChartVersion: "0.13.9", ReleaseNamespace: "metallb-system"},
+		"velero":        {Install: true, ChartName: "velero"},
+	}
+	releases, err := ParseReleases([]byte(`[
+		{"name":"cert-manager","namespace":"cert-manager","revision":"1","status":"deployed","chart":"cert-manager-1.11.0"},
+		{"name":"ingress-nginx","namespace":"default","revision":"2","status":"deployed","chart":"ingress-nginx-4.4.0"},
+		{"name":"ingress-nginx","namespace":"ingress-nginx","revision":"3","status":"failed","chart":"ingress-nginx-4.5.2"},
+		{"name":"koreboard","namespace":"koreboard","revision":"1","status":"deployed","chart":"koreboard-0.5.0"},
+		{"name":"metallb","namespace":"kube-system","revision":"1","status":"deployed","chart":"metallb-0.13.7"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		// "v" of the chart version is ignored
+		"cert-manager": {},
+		// the release in the namespace of addon.toml first
+		"ingress-nginx": {"status failed"},
+		"koreboard":     {DriftNotDesired},
+		"metallb":       {"chart version 0.13.7, desired 0.13.9", "namespace kube-system, desired metallb-system"},
+		"velero":        {DriftNotInstalled},
+	}
+	result := Compare(apps, releases)
+	if len(result) != len(want) {
+		t.Fatalf("Compare() = %d apps, want %d", len(result), len(want))
+	}
+	for _, status := range result {
+		if !reflect.DeepEqual(status.Drift, want[status.Name]) {
+			t.Errorf("%s: drift = %q, want %q", status.Name, status.Drift, want[status.Name])
+		}
+	}
+
+	if s := result[1]; s.Name != "ingress-nginx" || s.Namespace != "ingress-nginx" || s.Revision != "3" || s.InstalledVersion != "4.5.2" {
+		t.Errorf("ingress-nginx = %+v, want the release of ingress-nginx namespace", s)
+	}
+	if s := result[0]; s.Chart != "jetstack/cert-manager" {
+		t.Errorf("cert-manager chart = %q, want jetstack/cert-manager", s.Chart)
+	}
+	if s := result[2]; s.Chart != "koreboard-0.5.0.tgz" {
+		t.Errorf("koreboard chart = %q, want the chart archive", s.Chart)
+	}
+	if s := result[4]; s.Namespace != "" || s.InstalledVersion != "" {
+		t.Errorf("velero = %+v, want no release", s)
+	}
+}
+
+func TestParseReleases(t *testing.T) {
+	if releases, err := ParseReleases([]byte("\n")); err != nil || len(releases) != 0 {
+		t.Errorf("ParseReleases(empty) = %v, %v", releases, err)
+	}
+	if _, err := ParseReleases([]byte("Error: Kubernetes cluster unreachable")); err == nil {
+		t.Error("ParseReleases() of a helm error, want an error")
+	}
+}
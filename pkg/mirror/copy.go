@@ -0,0 +1,569 @@
+package mirror
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ===== [ Constants and Variables ] =====
+
+// Event kinds reported to Copier.Progress
+const (
+	EventStart    = "start"    // image copy started
+	EventProgress = "progress" // blob download in progress (at most once a second per blob)
+	EventBlob     = "blob"     // blob copied
+	EventExists   = "exists"   // blob already in the destination
+	EventDone     = "done"     // image copied
+	EventFailed   = "failed"   // image copy failed
+)
+
+// ===== [ Types ] =====
+
+// Endpoint - Source or destination of a copy.
+//
+//	""                   upstream registries of the image names (source only)
+//	"harbor.local/proj"  registry host with an optional path prefix
+//	"oci:/path/images"   OCI layout directory, or archive when the path ends with ".tar"
+type Endpoint struct {
+	Host    string
+	Prefix  string
+	Layout  *Layout
+	options RegistryOptions
+
+	mu         sync.Mutex
+	registries map[string]*Registry
+}
+
+// Event - Progress of a copy
+type Event struct {
+	Image       string
+	Kind        string
+	Digest      string
+	Size        int64
+	Transferred int64
+	Err         error
+}
+
+// Summary - Result of a copy
+type Summary struct {
+	Images  int
+	Failed  int
+	Blobs   int
+	Skipped int
+	Bytes   int64
+}
+
+// Copier - Copies images between endpoints. Blobs are staged in CacheDir so that an interrupted
+// transfer is resumed from where it stopped; blobs that already exist in the destination are skipped.
+type Copier struct {
+	Source       *Endpoint
+	Destination  *Endpoint
+	CacheDir     string
+	Parallel     int
+	Retries      int
+	Platform     string // "linux/amd64"; only this platform of a multi-arch image is copied
+	AllPlatforms bool
+	Progress     func(Event)
+
+	locks   sync.Map
+	blobs   int64
+	skipped int64
+	bytes   int64
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	URLs        []string          `json:"urls,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Variant      string `json:"variant,omitempty"`
+	} `json:"platform,omitempty"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    descriptor   `json:"config"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+// progressWriter - Counts written bytes and reports them at most once a second
+type progressWriter struct {
+	c          *Copier
+	image      string
+	d          descriptor
+	written    int64
+	reportedAt time.Time
+}
+
+// ===== [ Implementations ] =====
+
+// String - Endpoint as given on the command line
+func (e *Endpoint) String() string {
+	switch {
+	case e.Layout != nil:
+		return "oci:" + e.Layout.Path
+	case e.Host == "":
+		return "upstream"
+	case e.Prefix != "":
+		return e.Host + "/" + e.Prefix
+	}
+	return e.Host
+}
+
+// Target - Reference of an image in this endpoint. A registry keeps the full source name under
+// the host and prefix ("docker.io/calico/node:v3.25.1" -> "harbor.local/docker.io/calico/node:v3.25.1").
+func (e *Endpoint) Target(ref Reference) Reference {
+	if e.Layout != nil || e.Host == "" {
+		return ref
+	}
+	repo := ref.Registry + "/" + ref.Repository
+	if e.Prefix != "" {
+		repo = e.Prefix + "/" + repo
+	}
+	return Reference{Registry: e.Host, Repository: repo, Tag: ref.Tag, Digest: ref.Digest}
+}
+
+// Close - Write the OCI layout of the endpoint
+func (e *Endpoint) Close() error {
+	if e.Layout != nil {
+		return e.Layout.Close()
+	}
+	return nil
+}
+
+// Ping - Check the connection and the credentials of a registry endpoint
+func (e *Endpoint) Ping(ctx context.Context) error {
+	if e.Layout != nil {
+		return nil
+	}
+	if e.Host == "" {
+		return fmt.Errorf("%s: not a registry", e.String())
+	}
+	r, err := e.registry(e.Host)
+	if err != nil {
+		return err
+	}
+	return r.Ping(ctx)
+}
+
+func (e *Endpoint) registry(host string) (*Registry, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if r, ok := e.registries[host]; ok {
+		return r, nil
+	}
+	options := e.options
+	if e.Host == "" {
+		// credentials and certificates are meant for the given registry, not for upstream registries
+		options = RegistryOptions{}
+	}
+	r, err := NewRegistry(host, options)
+	if err != nil {
+		return nil, err
+	}
+	e.registries[host] = r
+	return r, nil
+}
+
+func (e *Endpoint) manifest(ctx context.Context, ref Reference) ([]byte, string, error) {
+	if e.Layout != nil {
+		return e.Layout.Manifest(ref)
+	}
+	r, err := e.registry(ref.Registry)
+	if err != nil {
+		return nil, "", err
+	}
+	return r.Manifest(ctx, ref.Repository, ref.Reference())
+}
+
+func (e *Endpoint) putManifest(ctx context.Context, ref Reference, mediaType string, body []byte) error {
+	if e.Layout != nil {
+		return e.Layout.PutManifest(ref, mediaType, body)
+	}
+	r, err := e.registry(ref.Registry)
+	if err != nil {
+		return err
+	}
+	return r.PutManifest(ctx, ref.Repository, ref.Reference(), mediaType, body)
+}
+
+func (e *Endpoint) hasBlob(ctx context.Context, ref Reference, d descriptor) (bool, error) {
+	if e.Layout != nil {
+		return e.Layout.HasBlob(d.Digest, d.Size), nil
+	}
+	r, err := e.registry(ref.Registry)
+	if err != nil {
+		return false, err
+	}
+	return r.HasBlob(ctx, ref.Repository, d.Digest)
+}
+
+func (e *Endpoint) pushBlob(ctx context.Context, ref Reference, digest string, path string) error {
+	if e.Layout != nil {
+		return e.Layout.PutBlob(digest, path)
+	}
+	r, err := e.registry(ref.Registry)
+	if err != nil {
+		return err
+	}
+	return r.PushBlob(ctx, ref.Repository, digest, path)
+}
+
+func (e *Endpoint) blob(ctx context.Context, ref Reference, digest string, offset int64) (io.ReadCloser, int64, error) {
+	r, err := e.registry(ref.Registry)
+	if err != nil {
+		return nil, 0, err
+	}
+	return r.Blob(ctx, ref.Repository, digest, offset)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if time.Since(w.reportedAt) >= time.Second {
+		w.reportedAt = time.Now()
+		w.c.emit(Event{Image: w.image, Kind: EventProgress, Digest: w.d.Digest, Size: w.d.Size, Transferred: w.written})
+	}
+	return len(p), nil
+}
+
+func (c *Copier) emit(e Event) {
+	if c.Progress != nil {
+		c.Progress(e)
+	}
+}
+
+// lock - Serialize the transfer of one blob shared by images copied in parallel
+func (c *Copier) lock(digest string) func() {
+	m, _ := c.locks.LoadOrStore(digest, &sync.Mutex{})
+	mu := m.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// stage - Local file of a blob. Downloads go to <cache>/blobs/sha256/<hex>.partial and are resumed
+// with a range request after an interruption; the digest is checked before the file is used, also of a cached blob.
+func (c *Copier) stage(ctx context.Context, image string, src Reference, d descriptor) (string, error) {
+	if c.Source.Layout != nil {
+		if !c.Source.Layout.HasBlob(d.Digest, d.Size) {
+			return "", fmt.Errorf("%s: blob %s %w in %s", image, d.Digest, ErrNotFound, c.Source.Layout.Path)
+		}
+		return c.Source.Layout.BlobPath(d.Digest), nil
+	}
+
+	path := filepath.Join(c.CacheDir, "blobs", strings.Replace(d.Digest, ":", string(filepath.Separator), 1))
+	if info, err := os.Stat(path); err == nil && info.Size() == d.Size {
+		// a cached blob of another run is used only with its digest, a damaged one is downloaded again
+		if sum, err := fileDigest(path); err == nil && sum == d.Digest {
+			return path, nil
+		}
+		os.Remove(path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+
+	partial := path + ".partial"
+	var lastErr error
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if lastErr = c.download(ctx, image, src, d, partial); lastErr == nil {
+			break
+		}
+		if errors.Is(lastErr, ErrNotFound) {
+			return "", lastErr
+		}
+	}
+	if lastErr != nil {
+		return "", lastErr
+	}
+
+	sum, err := fileDigest(partial)
+	if err != nil {
+		return "", err
+	}
+	if sum != d.Digest {
+		os.Remove(partial)
+		return "", fmt.Errorf("%s: blob %s digest mismatch (got %s)", image, d.Digest, sum)
+	}
+	return path, os.Rename(partial, path)
+}
+
+// download - Append the rest of a blob to the partial file
+func (c *Copier) download(ctx context.Context, image string, src Reference, d descriptor, partial string) error {
+	offset := int64(0)
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+	if offset >= d.Size && d.Size > 0 {
+		return nil
+	}
+
+	rc, start, err := c.Source.blob(ctx, src, d.Digest, offset)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if start == 0 {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+	f, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	pw := &progressWriter{c: c, image: image, d: d, written: start, reportedAt: time.Now()}
+	_, err = io.Copy(io.MultiWriter(f, pw), rc)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// copyBlob - Copy one blob unless the destination already has it
+func (c *Copier) copyBlob(ctx context.Context, image string, src Reference, dst Reference, d descriptor) error {
+	// foreign layers (e.g. windows base layers) are not distributed by registries
+	if len(d.URLs) > 0 {
+		return nil
+	}
+
+	unlock := c.lock(d.Digest)
+	defer unlock()
+
+	exists, err := c.Destination.hasBlob(ctx, dst, d)
+	if err != nil {
+		return err
+	}
+	if exists {
+		atomic.AddInt64(&c.skipped, 1)
+		c.emit(Event{Image: image, Kind: EventExists, Digest: d.Digest, Size: d.Size})
+		return nil
+	}
+
+	path, err := c.stage(ctx, image, src, d)
+	if err != nil {
+		return err
+	}
+	if err := c.Destination.pushBlob(ctx, dst, d.Digest, path); err != nil {
+		return err
+	}
+	atomic.AddInt64(&c.blobs, 1)
+	atomic.AddInt64(&c.bytes, d.Size)
+	c.emit(Event{Image: image, Kind: EventBlob, Digest: d.Digest, Size: d.Size, Transferred: d.Size})
+	return nil
+}
+
+// copyManifest - Copy the blobs of an image manifest and then the manifest itself
+func (c *Copier) copyManifest(ctx context.Context, image string, src Reference, dst Reference, mediaType string, body []byte) error {
+	m := manifest{}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return fmt.Errorf("%s: manifest: %s", image, err.Error())
+	}
+	if m.Config.Digest == "" {
+		return fmt.Errorf("%s: unsupported manifest %s", image, mediaType)
+	}
+
+	for _, d := range append([]descriptor{m.Config}, m.Layers...) {
+		if err := c.copyBlob(ctx, image, src, dst, d); err != nil {
+			return err
+		}
+	}
+	return c.Destination.putManifest(ctx, dst, mediaType, body)
+}
+
+// copyImage - Copy one image (a single manifest, or the platforms of an index)
+func (c *Copier) copyImage(ctx context.Context, image string) error {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return err
+	}
+	src, dst := c.Source.Target(ref), c.Destination.Target(ref)
+
+	body, mt, err := c.Source.manifest(ctx, src)
+	if err != nil {
+		return err
+	}
+	if mt != MediaTypeOCIIndex && mt != MediaTypeDockerManifestList {
+		return c.copyManifest(ctx, image, src, dst, mt, body)
+	}
+
+	idx := manifest{}
+	if err := json.Unmarshal(body, &idx); err != nil {
+		return fmt.Errorf("%s: index: %s", image, err.Error())
+	}
+
+	for _, child := range idx.Manifests {
+		if !c.AllPlatforms && !c.matchPlatform(child) {
+			continue
+		}
+		childBody, childType, err := c.Source.manifest(ctx, src.WithDigest(child.Digest))
+		if err != nil {
+			return err
+		}
+		if !c.AllPlatforms {
+			// the platform manifest is stored under the tag, as "docker pull" would do
+			if dst.Tag == "" {
+				dst = dst.WithDigest(child.Digest)
+			}
+			return c.copyManifest(ctx, image, src.WithDigest(child.Digest), dst, childType, childBody)
+		}
+		if err := c.copyManifest(ctx, image, src.WithDigest(child.Digest), dst.WithDigest(child.Digest), childType, childBody); err != nil {
+			return err
+		}
+	}
+	if !c.AllPlatforms {
+		return fmt.Errorf("%s: no manifest for platform %s", image, c.Platform)
+	}
+	return c.Destination.putManifest(ctx, dst, mt, body)
+}
+
+func (c *Copier) matchPlatform(d descriptor) bool {
+	if d.Platform == nil {
+		return false
+	}
+	p := d.Platform.OS + "/" + d.Platform.Architecture
+	if d.Platform.Variant != "" && strings.Count(c.Platform, "/") == 2 {
+		p += "/" + d.Platform.Variant
+	}
+	return p == c.Platform
+}
+
+// Copy - Copy the images with Parallel workers. Every image is attempted; the error lists the failed ones.
+func (c *Copier) Copy(ctx context.Context, images []string) (Summary, error) {
+	if c.Parallel < 1 {
+		c.Parallel = 1
+	}
+	if c.Platform == "" {
+		c.Platform = "linux/amd64"
+	}
+	if c.CacheDir == "" {
+		c.CacheDir = filepath.Join(os.TempDir(), "koreon-image-cache")
+	}
+
+	jobs := make(chan string)
+	failed := []string{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < c.Parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for image := range jobs {
+				c.emit(Event{Image: image, Kind: EventStart})
+				err := c.copyImage(ctx, image)
+				if err != nil {
+					mu.Lock()
+					failed = append(failed, image)
+					mu.Unlock()
+					c.emit(Event{Image: image, Kind: EventFailed, Err: err})
+					continue
+				}
+				c.emit(Event{Image: image, Kind: EventDone})
+			}
+		}()
+	}
+
+	for _, image := range images {
+		select {
+		case jobs <- image:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	summary := Summary{
+		Images:  len(images),
+		Failed:  len(failed),
+		Blobs:   int(atomic.LoadInt64(&c.blobs)),
+		Skipped: int(atomic.LoadInt64(&c.skipped)),
+		Bytes:   atomic.LoadInt64(&c.bytes),
+	}
+	if ctx.Err() != nil {
+		return summary, ctx.Err()
+	}
+	if len(failed) > 0 {
+		return summary, fmt.Errorf("%d of %d images failed: %s", len(failed), len(images), strings.Join(failed, ", "))
+	}
+	// every image is in the destination; the staged blobs are no longer needed
+	if c.Source.Layout == nil {
+		os.RemoveAll(c.CacheDir)
+	}
+	return summary, nil
+}
+
+// ===== [ Private Functions ] =====
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ===== [ Public Functions ] =====
+
+// ParseEndpoint - Parse a copy source or destination (see Endpoint)
+func ParseEndpoint(s string, options RegistryOptions) (*Endpoint, error) {
+	e := &Endpoint{options: options, registries: map[string]*Registry{}}
+
+	str := strings.TrimSpace(s)
+	switch {
+	case str == "":
+		return e, nil
+	case strings.HasPrefix(str, "oci:"):
+		layout, err := OpenLayout(strings.TrimPrefix(str, "oci:"))
+		if err != nil {
+			return nil, err
+		}
+		e.Layout = layout
+		return e, nil
+	}
+
+	str = strings.TrimPrefix(strings.TrimPrefix(str, "docker://"), "https://")
+	if strings.HasPrefix(str, "http://") {
+		str = strings.TrimPrefix(str, "http://")
+		e.options.PlainHTTP = true
+	}
+	parts := strings.SplitN(strings.Trim(str, "/"), "/", 2)
+	e.Host = parts[0]
+	if len(parts) == 2 {
+		e.Prefix = parts[1]
+	}
+	if e.Host == "" || strings.ContainsAny(e.Host, " @") {
+		return nil, fmt.Errorf("%q: invalid registry", s)
+	}
+	return e, nil
+}
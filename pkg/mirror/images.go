@@ -0,0 +1,182 @@
+package mirror
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+
+	"kore-on/pkg/model"
+)
+
+// ===== [ Constants and Variables ] =====
+
+// addonImages - Images of the addons and ClusterAPI that do not follow the kubernetes version.
+// Keep in sync with prepare_airgap_images in roles/init/templates/images.yaml.j2.
+var addonImages = []string{
+	// Addon images
+	"registry.k8s.io/sig-storage/livenessprobe:v2.7.0",
+	"registry.k8s.io/sig-storage/nfsplugin:v4.1.0",
+	"registry.k8s.io/sig-storage/csi-node-driver-registrar:v2.5.1",
+	"registry.k8s.io/sig-storage/csi-provisioner:v3.2.0",
+	"ghcr.io/kore3lab/kore-board.backend:v0.5.5",
+	"ghcr.io/kore3lab/kore-board.frontend:v0.5.5",
+	"ghcr.io/kore3lab/kore-board.metrics-scraper:v0.5.5",
+	"ghcr.io/kore3lab/kore-board.terminal:v0.5.5",
+	"docker.io/rancher/local-path-provisioner:v0.0.24",
+	"docker.io/library/busybox:1.36",
+	// ClusterAPI deployment images
+	"registry.k8s.io/capi-openstack/capi-openstack-controller:v0.7.3",
+	"registry.k8s.io/cluster-api/kubeadm-bootstrap-controller:v1.4.3",
+	"registry.k8s.io/cluster-api/kubeadm-control-plane-controller:v1.4.3",
+	"registry.k8s.io/cluster-api/cluster-api-controller:v1.4.3",
+	"gcr.io/kubebuilder/kube-rbac-proxy:v0.8.0",
+	"docker.io/cdkbot/capi-bootstrap-provider-microk8s:0.5.0",
+	"docker.io/cdkbot/capi-control-plane-provider-microk8s:0.5.0",
+	"quay.io/jetstack/cert-manager-controller:v1.12.1",
+	"quay.io/jetstack/cert-manager-webhook:v1.12.1",
+	"quay.io/jetstack/cert-manager-cainjector:v1.12.1",
+	// ClusterAPI k8s images
+	"registry.k8s.io/coredns/coredns:v1.9.3",
+	"registry.k8s.io/etcd:3.5.6-0",
+	"docker.io/calico/cni:v3.25.1",
+	"docker.io/calico/kube-controllers:v3.25.1",
+	"docker.io/calico/node:v3.25.1",
+	"registry.k8s.io/provider-os/openstack-cloud-controller-manager:v1.27.1",
+}
+
+// chartImages - Images of the catalog charts by chart version (HelmChartVersion) for the air gap bundle.
+// The playbooks get them as SupportVersion.ChartImages (roles/init/templates/images.yaml.j2).
+var chartImages = map[string]map[string][]string{
+	"ingress-nginx": {
+		"v4.0.19": {"registry.k8s.io/ingress-nginx/controller:v1.1.3"},
+		"v4.4.2":  {"registry.k8s.io/ingress-nginx/controller:v1.5.1"},
+		"v4.5.2":  {"registry.k8s.io/ingress-nginx/controller:v1.6.4"},
+	},
+	"metallb": {
+		"v0.13.9": {
+			"quay.io/metallb/controller:v0.13.9",
+			"quay.io/metallb/speaker:v0.13.9",
+		},
+	},
+	"cert-manager": {
+		"v1.8.2": {
+			"quay.io/jetstack/cert-manager-controller:v1.8.2",
+			"quay.io/jetstack/cert-manager-webhook:v1.8.2",
+			"quay.io/jetstack/cert-manager-cainjector:v1.8.2",
+		},
+		"v1.12.1": {
+			"quay.io/jetstack/cert-manager-controller:v1.12.1",
+			"quay.io/jetstack/cert-manager-webhook:v1.12.1",
+			"quay.io/jetstack/cert-manager-cainjector:v1.12.1",
+		},
+	},
+	"kube-prometheus-stack": {
+		"v45.7.1": {
+			"quay.io/prometheus-operator/prometheus-operator:v0.63.0",
+			"quay.io/prometheus-operator/prometheus-config-reloader:v0.63.0",
+			"quay.io/prometheus/prometheus:v2.42.0",
+			"quay.io/prometheus/alertmanager:v0.25.0",
+			"quay.io/prometheus/node-exporter:v1.5.0",
+			"registry.k8s.io/kube-state-metrics/kube-state-metrics:v2.8.1",
+			"docker.io/grafana/grafana:9.3.8",
+			"quay.io/kiwigrid/k8s-sidecar:1.22.0",
+		},
+	},
+	"longhorn": {
+		"v1.4.2": {
+			"docker.io/longhornio/longhorn-manager:v1.4.2",
+			"docker.io/longhornio/longhorn-engine:v1.4.2",
+			"docker.io/longhornio/longhorn-ui:v1.4.2",
+			"docker.io/longhornio/longhorn-instance-manager:v1.4.2",
+			"docker.io/longhornio/longhorn-share-manager:v1.4.2",
+			"docker.io/longhornio/backing-image-manager:v1.4.2",
+			"docker.io/longhornio/support-bundle-kit:v0.0.19",
+			"docker.io/longhornio/csi-attacher:v3.4.0",
+			"docker.io/longhornio/csi-provisioner:v2.1.2",
+			"docker.io/longhornio/csi-node-driver-registrar:v2.5.0",
+			"docker.io/longhornio/csi-resizer:v1.3.0",
+			"docker.io/longhornio/csi-snapshotter:v5.0.1",
+			"docker.io/longhornio/livenessprobe:v2.8.0",
+		},
+	},
+}
+
+// ===== [ Public Functions ] =====
+
+// SupportImages - Images of a kubernetes version with the image versions of the support map
+// (same list as prepare_airgap_images of the prepare-airgap playbook)
+func SupportImages(k8sVersion string, v model.ImageVersion) []string {
+	images := []string{
+		"docker.io/library/nginx:latest",
+		"docker.io/library/haproxy:latest",
+		"gcr.io/kubernetes-e2e-test-images/dnsutils:1.3",
+		"registry.k8s.io/pause:" + strings.TrimPrefix(v.Pause, "v"),
+		"docker.io/coredns/coredns:" + strings.TrimPrefix(v.Coredns, "v"),
+		"docker.io/calico/cni:" + v.Calico,
+		"docker.io/calico/node:" + v.Calico,
+		"docker.io/calico/kube-controllers:" + v.Calico,
+		"docker.io/calico/typha:" + v.Calico,
+		"docker.io/calico/pod2daemon-flexvol:" + v.Calico,
+		"quay.io/cilium/cilium:" + v.Cilium,
+		"quay.io/cilium/operator-generic:" + v.Cilium,
+		"docker.io/flannel/flannel:" + v.Flannel,
+		"docker.io/flannel/flannel-cni-plugin:v1.1.2",
+		"registry.k8s.io/metrics-server/metrics-server:" + v.MetricsServer,
+		"registry.k8s.io/kube-apiserver:" + k8sVersion,
+		"registry.k8s.io/kube-controller-manager:" + k8sVersion,
+		"registry.k8s.io/kube-scheduler:" + k8sVersion,
+		"registry.k8s.io/kube-proxy:" + k8sVersion,
+		"registry.k8s.io/e2e-test-images/jessie-dnsutils:" + strings.TrimPrefix(v.DnsUtils, "v"),
+	}
+	return append(images, addonImages...)
+}
+
+// ChartImages - Images of the catalog chart versions pinned for a kubernetes version (helm_chart_package)
+func ChartImages(v model.HelmChartVersion) []string {
+	// cert-manager of ClusterAPI is already in addonImages
+	seen := map[string]bool{}
+	for _, image := range addonImages {
+		seen[image] = true
+	}
+
+	images := []string{}
+	for name, ver := range map[string]string{
+		"ingress-nginx":         v.IngressNginx,
+		"metallb":               v.Metallb,
+		"cert-manager":          v.CertManager,
+		"kube-prometheus-stack": v.KubePrometheusStack,
+		"longhorn":              v.Longhorn,
+	} {
+		for _, image := range chartImages[name][ver] {
+			if !seen[image] {
+				seen[image] = true
+				images = append(images, image)
+			}
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// ReadImageList - Image names of a file, one per line. Empty lines and "#" comments are skipped.
+func ReadImageList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	images := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line != "" {
+			images = append(images, line)
+		}
+	}
+	return images, scanner.Err()
+}
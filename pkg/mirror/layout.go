@@ -0,0 +1,334 @@
+package mirror
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// AnnotationRefName - Tag of an image in an OCI layout
+	AnnotationRefName = "org.opencontainers.image.ref.name"
+	// AnnotationImageName - Full image name in an OCI layout (same key as containerd and podman)
+	AnnotationImageName = "io.containerd.image.name"
+)
+
+// ===== [ Types ] =====
+
+// Layout - OCI image layout in a directory, or in a tar archive when the path ends with ".tar".
+// An archive is extracted to a work directory and written back by Close.
+type Layout struct {
+	Path string
+
+	dir     string
+	archive bool
+	changed bool
+
+	mu    sync.Mutex
+	index index
+}
+
+type index struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Manifests     []descriptor `json:"manifests"`
+}
+
+// ===== [ Implementations ] =====
+
+// BlobPath - File of a blob ("sha256:abc" -> blobs/sha256/abc)
+func (l *Layout) BlobPath(digest string) string {
+	return filepath.Join(l.dir, "blobs", strings.Replace(digest, ":", string(filepath.Separator), 1))
+}
+
+// HasBlob - Whether the layout has the blob with the expected size
+func (l *Layout) HasBlob(digest string, size int64) bool {
+	info, err := os.Stat(l.BlobPath(digest))
+	return err == nil && (size < 0 || info.Size() == size)
+}
+
+// PutBlob - Add a blob from a local file
+func (l *Layout) PutBlob(digest string, path string) error {
+	dest := l.BlobPath(digest)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.changed = true
+	l.mu.Unlock()
+
+	// hard link when the cache is on the same file system
+	if err := os.Link(path, dest); err == nil || os.IsExist(err) {
+		return nil
+	}
+	return copyFile(path, dest)
+}
+
+// Manifest - Manifest of an image name (by full name or tag) or of a digest
+func (l *Layout) Manifest(ref Reference) ([]byte, string, error) {
+	digest, mt := ref.Digest, ""
+	if ref.Tag != "" {
+		l.mu.Lock()
+		for _, d := range l.index.Manifests {
+			if d.Annotations[AnnotationImageName] == ref.Name()+":"+ref.Tag || d.Annotations[AnnotationRefName] == ref.Name()+":"+ref.Tag {
+				digest, mt = d.Digest, d.MediaType
+			}
+		}
+		l.mu.Unlock()
+	}
+	if digest == "" {
+		return nil, "", fmt.Errorf("%s: %w in %s", ref.String(), ErrNotFound, l.Path)
+	}
+
+	body, err := ioutil.ReadFile(l.BlobPath(digest))
+	if os.IsNotExist(err) {
+		return nil, "", fmt.Errorf("%s: %w in %s", ref.String(), ErrNotFound, l.Path)
+	} else if err != nil {
+		return nil, "", err
+	}
+	return body, mediaType(mt, body), nil
+}
+
+// PutManifest - Add a manifest. A tagged reference is listed in index.json under its full name.
+func (l *Layout) PutManifest(ref Reference, mediaType string, body []byte) error {
+	digest := digestOf(body)
+	path := l.BlobPath(digest)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, body, 0644); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.changed = true
+	if ref.Tag == "" {
+		return nil
+	}
+
+	name := ref.Name() + ":" + ref.Tag
+	entry := descriptor{
+		MediaType: mediaType,
+		Digest:    digest,
+		Size:      int64(len(body)),
+		Annotations: map[string]string{
+			AnnotationImageName: name,
+			AnnotationRefName:   ref.Tag,
+		},
+	}
+	for i, d := range l.index.Manifests {
+		if d.Annotations[AnnotationImageName] == name {
+			l.index.Manifests[i] = entry
+			return nil
+		}
+	}
+	l.index.Manifests = append(l.index.Manifests, entry)
+	return nil
+}
+
+// Images - Full names of the images listed in index.json
+func (l *Layout) Images() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := []string{}
+	for _, d := range l.index.Manifests {
+		if name := d.Annotations[AnnotationImageName]; name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// Close - Write index.json and, for an archive, the tar file
+func (l *Layout) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.changed {
+		if err := ioutil.WriteFile(filepath.Join(l.dir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644); err != nil {
+			return err
+		}
+		b, err := json.MarshalIndent(l.index, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(l.dir, "index.json"), b, 0644); err != nil {
+			return err
+		}
+	}
+
+	if !l.archive {
+		return nil
+	}
+	defer os.RemoveAll(l.dir)
+	if !l.changed {
+		return nil
+	}
+	return writeTar(l.dir, l.Path)
+}
+
+// ===== [ Private Functions ] =====
+
+func copyFile(src string, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dest + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+func extractTar(archive string, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %s", archive, err.Error())
+		}
+
+		name := filepath.Clean(hdr.Name)
+		if strings.HasPrefix(name, "..") || filepath.IsAbs(name) {
+			return fmt.Errorf("%s: invalid path %q", archive, hdr.Name)
+		}
+		path := filepath.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			out, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeTar(dir string, archive string) error {
+	tmp := archive + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(f)
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, archive)
+}
+
+// ===== [ Public Functions ] =====
+
+// OpenLayout - Open an OCI layout directory or ".tar" archive. A missing layout is created on Close.
+func OpenLayout(path string) (*Layout, error) {
+	l := &Layout{
+		Path:    path,
+		dir:     path,
+		archive: strings.HasSuffix(path, ".tar"),
+		index:   index{SchemaVersion: 2, MediaType: MediaTypeOCIIndex, Manifests: []descriptor{}},
+	}
+
+	if l.archive {
+		dir, err := ioutil.TempDir(filepath.Dir(path), ".oci-layout-")
+		if err != nil {
+			return nil, err
+		}
+		l.dir = dir
+		if _, err := os.Stat(path); err == nil {
+			if err := extractTar(path, dir); err != nil {
+				os.RemoveAll(dir)
+				return nil, err
+			}
+		}
+	} else if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(l.dir, "index.json"))
+	if os.IsNotExist(err) {
+		return l, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &l.index); err != nil {
+		return nil, fmt.Errorf("%s: index.json: %s", path, err.Error())
+	}
+	return l, nil
+}
@@ -0,0 +1,101 @@
+// Package mirror - Copy container images between registries and OCI archives without podman or docker
+package mirror
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// DefaultRegistry is used when an image name has no registry part
+	DefaultRegistry = "docker.io"
+	// DefaultTag is used when an image name has neither tag nor digest
+	DefaultTag = "latest"
+)
+
+// ===== [ Types ] =====
+
+// Reference - Image reference (registry/repository:tag@digest)
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ===== [ Implementations ] =====
+
+// Name - Registry and repository without tag and digest
+func (r Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String - Full reference
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Reference - Tag, or digest when the reference has no tag (manifest path of the registry API)
+func (r Reference) Reference() string {
+	if r.Tag != "" {
+		return r.Tag
+	}
+	return r.Digest
+}
+
+// WithDigest - Same repository pointing to a digest (child manifests of an index)
+func (r Reference) WithDigest(digest string) Reference {
+	return Reference{Registry: r.Registry, Repository: r.Repository, Digest: digest}
+}
+
+// ===== [ Public Functions ] =====
+
+// ParseReference - Parse "nginx", "docker.io/calico/node:v3.25.1" or "localhost:5000/pause@sha256:..."
+func ParseReference(s string) (Reference, error) {
+	ref := Reference{}
+	name := strings.TrimSpace(s)
+	if name == "" {
+		return ref, fmt.Errorf("empty image reference")
+	}
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !strings.HasPrefix(ref.Digest, "sha256:") {
+			return ref, fmt.Errorf("%q: unsupported digest", s)
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry = parts[0]
+		ref.Repository = parts[1]
+	} else {
+		ref.Registry = DefaultRegistry
+		ref.Repository = name
+	}
+	if ref.Registry == DefaultRegistry && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+
+	if ref.Repository == "" || ref.Repository != strings.ToLower(ref.Repository) {
+		return ref, fmt.Errorf("%q: invalid repository name", s)
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = DefaultTag
+	}
+	return ref, nil
+}
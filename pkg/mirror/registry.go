@@ -0,0 +1,511 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// uploadChunkSize - Blobs larger than this are uploaded in chunks so that a failed upload can be resumed
+var uploadChunkSize int64 = 32 << 20
+
+// ErrNotFound is returned when a manifest or blob does not exist
+var ErrNotFound = errors.New("not found")
+
+var manifestAccept = strings.Join([]string{
+	MediaTypeOCIIndex,
+	MediaTypeDockerManifestList,
+	MediaTypeOCIManifest,
+	MediaTypeDockerManifest,
+}, ", ")
+
+// ===== [ Types ] =====
+
+// RegistryOptions - Connection options of a registry
+type RegistryOptions struct {
+	Username  string
+	Password  string
+	CAFile    string
+	Insecure  bool // skip TLS verification
+	PlainHTTP bool // http instead of https (local test registry)
+}
+
+// Registry - Docker registry HTTP API v2 client for one registry host
+type Registry struct {
+	Host    string
+	options RegistryOptions
+	client  *http.Client
+
+	mu     sync.Mutex
+	basic  bool
+	tokens map[string]string
+}
+
+// ===== [ Implementations ] =====
+
+func (r *Registry) apiHost() string {
+	// Docker Hub serves the API on a different host
+	if r.Host == DefaultRegistry {
+		return "registry-1.docker.io"
+	}
+	return r.Host
+}
+
+func (r *Registry) url(path string) string {
+	scheme := "https"
+	if r.options.PlainHTTP {
+		scheme = "http"
+	}
+	return scheme + "://" + r.apiHost() + "/v2/" + path
+}
+
+// authorize - Add the cached credentials for the scope
+func (r *Registry) authorize(req *http.Request, scope string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if token, ok := r.tokens[scope]; ok {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if r.basic {
+		req.SetBasicAuth(r.options.Username, r.options.Password)
+	}
+}
+
+// login - Handle the WWW-Authenticate challenge of a 401 response
+func (r *Registry) login(ctx context.Context, challenge string, scope string) error {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if r.options.Username == "" {
+			return fmt.Errorf("%s: authentication required", r.Host)
+		}
+		r.mu.Lock()
+		r.basic = true
+		r.mu.Unlock()
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("%s: unsupported authentication %q", r.Host, scheme)
+	}
+
+	u, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("%s: invalid token realm %q", r.Host, params["realm"])
+	}
+	q := u.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	if scope != "" {
+		q.Set("scope", scope)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if r.options.Username != "" {
+		req.SetBasicAuth(r.options.Username, r.options.Password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: token request failed: %s", r.Host, resp.Status)
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("%s: token response: %s", r.Host, err.Error())
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	r.mu.Lock()
+	r.tokens[scope] = token.Token
+	r.mu.Unlock()
+	return nil
+}
+
+// do - Send a request, logging in once when the registry asks for authentication.
+// newRequest is called again for the retry because a request body can be read only once.
+func (r *Registry) do(ctx context.Context, scope string, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		r.authorize(req, scope)
+
+		resp, err := r.client.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := r.login(ctx, challenge, scope); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Ping - Check the connection and the credentials (GET /v2/)
+func (r *Registry) Ping(ctx context.Context) error {
+	resp, err := r.do(ctx, "", func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, r.url(""), nil)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, r.Host, http.StatusOK)
+}
+
+// Manifest - Manifest body and media type of a tag or digest
+func (r *Registry) Manifest(ctx context.Context, repository string, reference string) ([]byte, string, error) {
+	resp, err := r.do(ctx, pullScope(repository), func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, r.url(repository+"/manifests/"+reference), nil)
+		if err == nil {
+			req.Header.Set("Accept", manifestAccept)
+		}
+		return req, err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, repository+":"+reference, http.StatusOK); err != nil {
+		return nil, "", err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, mediaType(resp.Header.Get("Content-Type"), body), nil
+}
+
+// PutManifest - Upload a manifest under a tag or digest
+func (r *Registry) PutManifest(ctx context.Context, repository string, reference string, mediaType string, body []byte) error {
+	resp, err := r.do(ctx, pushScope(repository), func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPut, r.url(repository+"/manifests/"+reference), bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", mediaType)
+		}
+		return req, err
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, repository+":"+reference, http.StatusCreated, http.StatusOK)
+}
+
+// HasBlob - Whether the repository already has the blob
+func (r *Registry) HasBlob(ctx context.Context, repository string, digest string) (bool, error) {
+	resp, err := r.do(ctx, pushScope(repository), func() (*http.Request, error) {
+		return http.NewRequest(http.MethodHead, r.url(repository+"/blobs/"+digest), nil)
+	})
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err := checkResponse(resp, repository+"@"+digest, http.StatusOK); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Blob - Read a blob from offset. The returned offset is 0 when the registry ignored the range request.
+func (r *Registry) Blob(ctx context.Context, repository string, digest string, offset int64) (io.ReadCloser, int64, error) {
+	resp, err := r.do(ctx, pullScope(repository), func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, r.url(repository+"/blobs/"+digest), nil)
+		if err == nil && offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+		return req, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := checkResponse(resp, repository+"@"+digest, http.StatusOK, http.StatusPartialContent); err != nil {
+		resp.Body.Close()
+		return nil, 0, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		offset = 0
+	}
+	return resp.Body, offset, nil
+}
+
+// PushBlob - Upload a blob from a local file. Large blobs are sent in chunks and an interrupted
+// chunk is resumed from the offset reported by the registry.
+func (r *Registry) PushBlob(ctx context.Context, repository string, digest string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+
+	resp, err := r.do(ctx, pushScope(repository), func() (*http.Request, error) {
+		return http.NewRequest(http.MethodPost, r.url(repository+"/blobs/uploads/"), nil)
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if err := checkResponse(resp, repository+"@"+digest, http.StatusAccepted); err != nil {
+		return err
+	}
+	location, err := r.location(resp)
+	if err != nil {
+		return err
+	}
+
+	offset := int64(0)
+	retries := 0
+	for size > uploadChunkSize && offset < size {
+		end := offset + uploadChunkSize
+		if end > size {
+			end = size
+		}
+		resp, err := r.do(ctx, pushScope(repository), func() (*http.Request, error) {
+			req, err := http.NewRequest(http.MethodPatch, location, io.NewSectionReader(f, offset, end-offset))
+			if err == nil {
+				req.ContentLength = end - offset
+				req.Header.Set("Content-Type", "application/octet-stream")
+				req.Header.Set("Content-Range", fmt.Sprintf("%d-%d", offset, end-1))
+			}
+			return req, err
+		})
+		if err == nil {
+			resp.Body.Close()
+			err = checkResponse(resp, repository+"@"+digest, http.StatusAccepted, http.StatusNoContent)
+		}
+		if err != nil {
+			if retries++; retries > 3 || ctx.Err() != nil {
+				return err
+			}
+			// ask the registry how much it has received and continue from there
+			if offset, err = r.uploadOffset(ctx, repository, location); err != nil {
+				return err
+			}
+			continue
+		}
+		if location, err = r.location(resp); err != nil {
+			return err
+		}
+		offset = end
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("digest", digest)
+	u.RawQuery = q.Encode()
+
+	resp, err = r.do(ctx, pushScope(repository), func() (*http.Request, error) {
+		var body io.Reader = http.NoBody
+		length := int64(0)
+		if offset < size {
+			body = io.NewSectionReader(f, offset, size-offset)
+			length = size - offset
+		}
+		req, err := http.NewRequest(http.MethodPut, u.String(), body)
+		if err == nil {
+			req.ContentLength = length
+			req.Header.Set("Content-Type", "application/octet-stream")
+		}
+		return req, err
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, repository+"@"+digest, http.StatusCreated)
+}
+
+// location - Absolute upload URL of a response
+func (r *Registry) location(resp *http.Response) (string, error) {
+	loc, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("%s: upload location: %s", r.Host, err.Error())
+	}
+	return loc.String(), nil
+}
+
+// uploadOffset - Number of bytes the registry has received for an upload session
+func (r *Registry) uploadOffset(ctx context.Context, repository string, location string) (int64, error) {
+	resp, err := r.do(ctx, pushScope(repository), func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, location, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if err := checkResponse(resp, repository, http.StatusNoContent); err != nil {
+		return 0, err
+	}
+	// Range: 0-<last byte>
+	rng := resp.Header.Get("Range")
+	if i := strings.LastIndex(rng, "-"); i >= 0 {
+		last, err := strconv.ParseInt(rng[i+1:], 10, 64)
+		if err == nil {
+			return last + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// ===== [ Private Functions ] =====
+
+func pullScope(repository string) string {
+	return "repository:" + repository + ":pull"
+}
+
+func pushScope(repository string) string {
+	return "repository:" + repository + ":pull,push"
+}
+
+// parseChallenge - Split `Bearer realm="...",service="...",scope="a:b:pull,push"` into scheme and parameters
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	scheme, rest := challenge, ""
+	if i := strings.Index(challenge, " "); i >= 0 {
+		scheme, rest = challenge[:i], challenge[i+1:]
+	}
+
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+
+		value := ""
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma >= 0 {
+			value, rest = rest[:comma], rest[comma+1:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+	}
+	return scheme, params
+}
+
+func checkResponse(resp *http.Response, what string, expected ...int) error {
+	for _, code := range expected {
+		if resp.StatusCode == code {
+			return nil
+		}
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", what, ErrNotFound)
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s: %s %s", what, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// mediaType - Manifest media type from the Content-Type header or the mediaType field of the body
+func mediaType(contentType string, body []byte) string {
+	mt := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	switch mt {
+	case MediaTypeDockerManifest, MediaTypeDockerManifestList, MediaTypeOCIManifest, MediaTypeOCIIndex:
+		return mt
+	}
+
+	m := struct {
+		MediaType string          `json:"mediaType"`
+		Manifests json.RawMessage `json:"manifests"`
+	}{}
+	if err := json.Unmarshal(body, &m); err == nil {
+		if m.MediaType != "" {
+			return m.MediaType
+		}
+		if m.Manifests != nil {
+			return MediaTypeOCIIndex
+		}
+	}
+	return MediaTypeOCIManifest
+}
+
+// ===== [ Public Functions ] =====
+
+// NewRegistry - Registry client for a host ("harbor.local", "localhost:5000")
+func NewRegistry(host string, options RegistryOptions) (*Registry, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.Insecure}
+	if options.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificate found", options.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &Registry{
+		Host:    host,
+		options: options,
+		client:  &http.Client{Transport: transport},
+		tokens:  map[string]string{},
+	}, nil
+}
@@ -0,0 +1,361 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry - In-memory registry API v2 with the endpoints used by the Copier
+type fakeRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte // <repository>@<digest>
+	manifests map[string][]byte // <repository>:<reference>
+	uploads   map[string][]byte
+	uploadID  int
+
+	ranges []string // Range headers of blob downloads
+	// failPatch - the upload chunk starting at this offset keeps only half of its body and fails once
+	failPatch int64
+	patches   []string // Content-Range headers of upload chunks
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{
+		blobs:     map[string][]byte{},
+		manifests: map[string][]byte{},
+		uploads:   map[string][]byte{},
+		failPatch: -1,
+	}
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	if path == "" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	switch {
+	case strings.Contains(path, "/blobs/uploads/"):
+		i := strings.Index(path, "/blobs/uploads/")
+		f.upload(w, r, path[:i], path[i+len("/blobs/uploads/"):])
+	case strings.Contains(path, "/blobs/"):
+		i := strings.Index(path, "/blobs/")
+		f.blob(w, r, path[:i]+"@"+path[i+len("/blobs/"):])
+	case strings.Contains(path, "/manifests/"):
+		i := strings.Index(path, "/manifests/")
+		f.manifest(w, r, path[:i]+":"+path[i+len("/manifests/"):])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeRegistry) manifest(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodGet:
+		body, ok := f.manifests[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", MediaTypeOCIManifest)
+		w.Write(body)
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		f.manifests[key] = body
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (f *fakeRegistry) blob(w http.ResponseWriter, r *http.Request, key string) {
+	body, ok := f.blobs[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	rng := r.Header.Get("Range")
+	if rng == "" {
+		w.Write(body)
+		return
+	}
+	f.ranges = append(f.ranges, rng)
+	start, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+	if err != nil || start >= len(body) {
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(body)-1, len(body)))
+	w.WriteHeader(http.StatusPartialContent)
+	w.Write(body[start:])
+}
+
+func (f *fakeRegistry) upload(w http.ResponseWriter, r *http.Request, repository string, id string) {
+	location := func(id string) {
+		w.Header().Set("Location", "/v2/"+repository+"/blobs/uploads/"+id)
+		if n := len(f.uploads[id]); n > 0 {
+			w.Header().Set("Range", fmt.Sprintf("0-%d", n-1))
+		}
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		f.uploadID++
+		id = strconv.Itoa(f.uploadID)
+		f.uploads[id] = []byte{}
+		location(id)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	data, ok := f.uploads[id]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		location(id)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPatch:
+		body, _ := ioutil.ReadAll(r.Body)
+		f.patches = append(f.patches, r.Header.Get("Content-Range"))
+		if int64(len(data)) == f.failPatch {
+			// the connection broke in the middle of the chunk
+			f.uploads[id] = append(data, body[:len(body)/2]...)
+			f.failPatch = -1
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Content-Range"), fmt.Sprintf("%d-", len(data))) {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		f.uploads[id] = append(data, body...)
+		location(id)
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		data = append(data, body...)
+		digest := r.URL.Query().Get("digest")
+		if digestOf(data) != digest {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "digest mismatch: got %s", digestOf(data))
+			return
+		}
+		f.blobs[repository+"@"+digest] = data
+		delete(f.uploads, id)
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// putImage - Image with a config and one layer under <repository>:<tag>
+func (f *fakeRegistry) putImage(repository string, tag string, layer []byte) manifest {
+	config := []byte(`{"architecture":"amd64","os":"linux"}`)
+	m := manifest{
+		MediaType: MediaTypeOCIManifest,
+		Config:    descriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: digestOf(config), Size: int64(len(config))},
+		Layers:    []descriptor{{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digestOf(layer), Size: int64(len(layer))}},
+	}
+	body, _ := json.Marshal(m)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blobs[repository+"@"+m.Config.Digest] = config
+	f.blobs[repository+"@"+m.Layers[0].Digest] = layer
+	f.manifests[repository+":"+tag] = body
+	return m
+}
+
+func testLayer(size int) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+func testCopier(t *testing.T, server *httptest.Server) *Copier {
+	t.Helper()
+	host := strings.TrimPrefix(server.URL, "http://")
+	src, err := ParseEndpoint("http://"+host+"/src", RegistryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := ParseEndpoint("http://"+host+"/dst", RegistryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Copier{Source: src, Destination: dst, CacheDir: filepath.Join(t.TempDir(), "cache"), Retries: 1}
+}
+
+func TestCopyImage(t *testing.T) {
+	reg := newFakeRegistry()
+	server := httptest.NewServer(reg)
+	defer server.Close()
+
+	layer := testLayer(4096)
+	m := reg.putImage("src/docker.io/library/app", "v1", layer)
+
+	c := testCopier(t, server)
+	summary, err := c.Copy(context.Background(), []string{"app:v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Images != 1 || summary.Failed != 0 || summary.Blobs != 2 {
+		t.Errorf("summary = %+v, want 1 image and 2 blobs", summary)
+	}
+	if _, ok := reg.manifests["dst/docker.io/library/app:v1"]; !ok {
+		t.Fatal("manifest is not copied to dst/docker.io/library/app:v1")
+	}
+	if !bytes.Equal(reg.blobs["dst/docker.io/library/app@"+m.Layers[0].Digest], layer) {
+		t.Error("layer in the destination differs from the source")
+	}
+	if _, err := os.Stat(c.CacheDir); !os.IsNotExist(err) {
+		t.Errorf("cache %s is not removed after the copy", c.CacheDir)
+	}
+
+	// the second copy finds every blob in the destination
+	c = testCopier(t, server)
+	summary, err = c.Copy(context.Background(), []string{"docker.io/library/app:v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Blobs != 0 || summary.Skipped != 2 {
+		t.Errorf("summary = %+v, want 2 skipped blobs", summary)
+	}
+}
+
+func TestCopyResumesPartialBlob(t *testing.T) {
+	reg := newFakeRegistry()
+	server := httptest.NewServer(reg)
+	defer server.Close()
+
+	layer := testLayer(8192)
+	m := reg.putImage("src/docker.io/library/app", "v1", layer)
+	digest := m.Layers[0].Digest
+
+	c := testCopier(t, server)
+	// an earlier run stopped after half of the layer
+	partial := filepath.Join(c.CacheDir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:")+".partial")
+	if err := os.MkdirAll(filepath.Dir(partial), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(partial, layer[:3000], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Copy(context.Background(), []string{"app:v1"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(reg.ranges) != 1 || reg.ranges[0] != "bytes=3000-" {
+		t.Errorf("range requests = %v, want [bytes=3000-]", reg.ranges)
+	}
+	if !bytes.Equal(reg.blobs["dst/docker.io/library/app@"+digest], layer) {
+		t.Error("resumed layer differs from the source")
+	}
+}
+
+func TestCopyVerifiesCachedBlob(t *testing.T) {
+	reg := newFakeRegistry()
+	server := httptest.NewServer(reg)
+	defer server.Close()
+
+	layer := testLayer(4096)
+	m := reg.putImage("src/docker.io/library/app", "v1", layer)
+	digest := m.Layers[0].Digest
+
+	c := testCopier(t, server)
+	// a cached blob of the right size with other content
+	cached := filepath.Join(c.CacheDir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:"))
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(cached, bytes.Repeat([]byte{0}, len(layer)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Copy(context.Background(), []string{"app:v1"}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reg.blobs["dst/docker.io/library/app@"+digest], layer) {
+		t.Error("damaged cached blob is pushed instead of the source layer")
+	}
+}
+
+func TestBlobRangeIgnored(t *testing.T) {
+	layer := testLayer(1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// registries without range support send the whole blob
+		w.Write(layer)
+	}))
+	defer server.Close()
+
+	r, err := NewRegistry(strings.TrimPrefix(server.URL, "http://"), RegistryOptions{PlainHTTP: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, offset, err := r.Blob(context.Background(), "library/app", digestOf(layer), 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if offset != 0 {
+		t.Errorf("offset = %d, want 0 when the range is ignored", offset)
+	}
+	if b, _ := ioutil.ReadAll(rc); !bytes.Equal(b, layer) {
+		t.Error("blob differs from the source")
+	}
+}
+
+func TestPushBlobRecoversUploadOffset(t *testing.T) {
+	defer func(size int64) { uploadChunkSize = size }(uploadChunkSize)
+	uploadChunkSize = 1000
+
+	reg := newFakeRegistry()
+	// the second chunk breaks after 500 bytes
+	reg.failPatch = 1000
+	server := httptest.NewServer(reg)
+	defer server.Close()
+
+	layer := testLayer(3500)
+	path := filepath.Join(t.TempDir(), "layer")
+	if err := ioutil.WriteFile(path, layer, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRegistry(strings.TrimPrefix(server.URL, "http://"), RegistryOptions{PlainHTTP: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.PushBlob(context.Background(), "library/app", digestOf(layer), path); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"0-999", "1000-1999", "1500-2499", "2500-3499"}
+	if strings.Join(reg.patches, " ") != strings.Join(want, " ") {
+		t.Errorf("chunks = %v, want %v", reg.patches, want)
+	}
+	if !bytes.Equal(reg.blobs["library/app@"+digestOf(layer)], layer) {
+		t.Error("uploaded blob differs from the file")
+	}
+}
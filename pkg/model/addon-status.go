@@ -0,0 +1,14 @@
+package model
+
+// AddonAppStatus - Desired state of [apps.<name>] and the installed helm release
+type AddonAppStatus struct {
+	Name             string
+	Install          bool
+	Chart            string
+	Namespace        string
+	DesiredVersion   string
+	InstalledVersion string
+	Revision         string
+	Status           string
+	Drift            []string
+}
@@ -3,4 +3,6 @@ package model
 type AddonText struct {
 	Command   string
 	AddonTemp AddonToml
+	AppList   []string
+	Revision  int
 }
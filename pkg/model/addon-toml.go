@@ -7,72 +7,37 @@ type AddonToml struct {
 		AddonDataDir   string `toml:"addon-data-dir,omitempty"`
 		ClosedNetwork  bool   `toml:"closed-network,omitempty"`
 		KubeConfig     string
+		Local          bool
 		HelmVersion    string
 		HelmInstall    bool
 		HelmBinaryFile string
 		WorkDir        string
 	} `toml:"addon,omitempty"`
 
-	Apps struct {
-		CsiDriverNfs  AppsCsiDriverNfs  `toml:"csi-driver-nfs,omitempty"`
-		BitnamiNginx  AppsBitnamiNginx  `toml:"bitnami-nginx,omitempty"`
-		Elasticsearch AppsElasticsearch `toml:"elasticsearch,omitempty"`
-		FluentBit     AppsFluentBit     `toml:"fluent-bit,omitempty"`
-		Koreboard     AppsKoreboard     `toml:"koreboard,omitempty"`
-	} `toml:"apps,omitempty"`
+	Apps map[string]AddonApp `toml:"apps,omitempty"`
 }
 
-type AppsCsiDriverNfs struct {
-	Install          bool   `toml:"install,omitempty"`
-	ChartRefName     string `toml:"chart_ref_name,omitempty"`
-	ChartRef         string `toml:"chart_ref,omitempty"`
-	ChartName        string `toml:"chart_name,omitempty"`
-	ReleaseNamespace string `toml:"chart_name,omitempty"`
-	ChartVersion     string `toml:"chart_version,omitempty"`
+// AddonApp - Helm chart of [apps.<name>]. The name is the helm release name.
+type AddonApp struct {
+	Install          bool         `toml:"install,omitempty"`
+	ChartRefName     string       `toml:"chart_ref_name,omitempty"`
+	ChartRef         string       `toml:"chart_ref,omitempty"`
+	ChartName        string       `toml:"chart_name,omitempty"`
+	ChartVersion     string       `toml:"chart_version,omitempty"`
+	ReleaseNamespace string       `toml:"release_namespace,omitempty"`
+	Login            bool         `toml:"login,omitempty"`
+	Values           string       `toml:"values,omitempty"`
+	ValuesFile       string       `toml:"values_file,omitempty"`
+	DependsOn        []string     `toml:"depends_on,omitempty"`
+	Wait             AddonAppWait `toml:"wait,omitempty"`
+	Manifests        string       `toml:"manifests,omitempty"` // objects deployed after the chart as the release <name>-manifests
 	ChartRefID       string
 	ChartRefPW       string
-	Values           string `toml:"values,omitempty"`
-	ValuesFile       string `toml:"values_file,omitempty"`
 }
 
-type AppsBitnamiNginx struct {
-	Install          bool   `toml:"install,omitempty"`
-	ChartRefName     string `toml:"chart_ref_name,omitempty"`
-	ChartRef         string `toml:"chart_ref,omitempty"`
-	ChartName        string `toml:"chart_name,omitempty"`
-	ReleaseNamespace string `toml:"chart_name,omitempty"`
-	ChartVersion     string `toml:"chart_version,omitempty"`
-	Values           string `toml:"values,omitempty"`
-	ValuesFile       string `toml:"values_file,omitempty"`
-}
-
-type AppsElasticsearch struct {
-	Install          bool   `toml:"install,omitempty"`
-	ChartRefName     string `toml:"chart_ref_name,omitempty"`
-	ChartRef         string `toml:"chart_ref,omitempty"`
-	ChartName        string `toml:"chart_name,omitempty"`
-	ReleaseNamespace string `toml:"chart_name,omitempty"`
-	ChartVersion     string `toml:"chart_version,omitempty"`
-	Values           string `toml:"values,omitempty"`
-	ValuesFile       string `toml:"values_file,omitempty"`
-}
-type AppsFluentBit struct {
-	Install          bool   `toml:"install,omitempty"`
-	ChartRefName     string `toml:"chart_ref_name,omitempty"`
-	ChartRef         string `toml:"chart_ref,omitempty"`
-	ChartName        string `toml:"chart_name,omitempty"`
-	ReleaseNamespace string `toml:"chart_name,omitempty"`
-	ChartVersion     string `toml:"chart_version,omitempty"`
-	Values           string `toml:"values,omitempty"`
-	ValuesFile       string `toml:"values_file,omitempty"`
-}
-
-type AppsKoreboard struct {
-	Install          bool   `toml:"install,omitempty"`
-	ChartRefName     string `toml:"chart_ref_name,omitempty"`
-	ChartRef         string `toml:"chart_ref,omitempty"`
-	ChartName        string `toml:"chart_name,omitempty"`
-	ReleaseNamespace string `toml:"chart_name,omitempty"`
-	Values           string `toml:"values,omitempty"`
-	ValuesFile       string `toml:"values_file,omitempty"`
+// AddonAppWait - Readiness of [apps.<name>.wait] checked after the deployment
+type AddonAppWait struct {
+	Deployments []string `toml:"deployments,omitempty"` // "<name>" in the release namespace or "<namespace>/<name>"
+	CRDs        []string `toml:"crds,omitempty"`        // e.g. "certificates.cert-manager.io"
+	Timeout     string   `toml:"timeout,omitempty"`     // e.g. "5m" (default: "5m")
 }
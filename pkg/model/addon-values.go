@@ -0,0 +1,52 @@
+package model
+
+// AddonValuesContext - Context of the addon values templates (e.g. {{ .Storage.PrivateIP }}) built from koreon.toml
+type AddonValuesContext struct {
+	Cluster  AddonClusterContext
+	Storage  AddonStorageContext
+	Registry AddonRegistryContext
+	NodePool AddonNodePoolContext
+}
+
+type AddonClusterContext struct {
+	Name          string
+	Version       string
+	ServiceCidr   string
+	PodCidr       string
+	NodePortRange string
+	ClosedNetwork bool
+}
+
+type AddonStorageContext struct {
+	Install     bool
+	StorageType string // nfs, external-nfs, local-path, longhorn
+	IP          string
+	PrivateIP   string
+	VolumeDir   string
+	VolumeSize  int
+	Exports     []AddonStorageExportContext // nfs and external-nfs
+}
+
+// AddonStorageExportContext - StorageClass of an export in [[shared-storage.exports]]
+type AddonStorageExportContext struct {
+	Path         string
+	StorageClass string
+	Default      bool
+}
+
+type AddonRegistryContext struct {
+	Install   bool
+	Domain    string // registry-domain, registry-ip without the domain
+	IP        string
+	PrivateIP string
+}
+
+type AddonNodePoolContext struct {
+	DataDir         string
+	MasterIP        []string
+	MasterPrivateIP []string
+	LbIP            string
+	LbPort          int
+	NodeIP          []string
+	NodePrivateIP   []string
+}
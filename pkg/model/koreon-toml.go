@@ -34,8 +34,9 @@ type KoreOnToml struct {
 
 	Kubernetes struct {
 		Version          string   `toml:"version,omitempty"`
-		ContainerRuntime string   `toml:"container-runtime"`
+		ContainerRuntime string   `toml:"container-runtime"` // containerd (default), cri-o
 		KubeProxyMode    string   `toml:"kube-proxy-mode"`
+		Cni              string   `toml:"cni,omitempty"` // calico (default), cilium, flannel
 		CalicoVersion    string   `toml:"calico-version"`
 		ServiceCidr      string   `toml:"service-cidr,omitempty"`
 		PodCidr          string   `toml:"pod-cidr,omitempty"`
@@ -46,9 +47,40 @@ type KoreOnToml struct {
 
 		Calico struct {
 			Version   string `toml:"version,omitempty"`
-			VxlanMode bool   `toml:"vxlan-mode"`
+			VxlanMode bool   `toml:"vxlan-mode"` // same as encapsulation = "vxlan"
+
+			Encapsulation          string         `toml:"encapsulation,omitempty"` // ipip (default), vxlan, none
+			CrossSubnet            bool           `toml:"cross-subnet,omitempty"`  // encapsulate only between subnets
+			Mtu                    int            `toml:"mtu,omitempty"`           // 0: auto-detect
+			IPAutodetectionMethod  string         `toml:"ip-autodetection-method,omitempty"`
+			IP6AutodetectionMethod string         `toml:"ip6-autodetection-method,omitempty"` // dual-stack
+			IPPools                []CalicoIPPool `toml:"ip-pools,omitempty"`
+			Bgp                    CalicoBgp      `toml:"bgp,omitempty"`
 		} `toml:"calico,omitempty"`
 
+		Cilium struct {
+			KubeProxyReplacement bool   `toml:"kube-proxy-replacement,omitempty"`
+			Tunnel               string `toml:"tunnel,omitempty"` // vxlan (default), geneve, disabled
+		} `toml:"cilium,omitempty"`
+
+		Flannel struct {
+			Backend string `toml:"backend,omitempty"` // vxlan (default), host-gw, wireguard
+		} `toml:"flannel,omitempty"`
+
+		Crio struct {
+			Version    string         `toml:"version,omitempty"` // default: latest patch of the kubernetes minor version
+			Registries []CrioRegistry `toml:"registries,omitempty"`
+		} `toml:"cri-o,omitempty"`
+
+		// Flags of the kubernetes components, applied by 'update' without node changes
+		Apiserver         KubeApiserver `toml:"apiserver,omitempty"`
+		ControllerManager KubeComponent `toml:"controller-manager,omitempty"`
+		Scheduler         KubeComponent `toml:"scheduler,omitempty"`
+		Kubelet           KubeKubelet   `toml:"kubelet,omitempty"`
+
+		// OIDC authentication of the apiserver (developers log in through the identity provider)
+		Oidc KubeOidc `toml:"oidc,omitempty"`
+
 		Etcd struct {
 			ExternalEtcd  bool     `toml:"external-etcd,omitempty"`
 			IP            []string `toml:"ip"`
@@ -71,6 +103,7 @@ type KoreOnToml struct {
 			Name           string   `toml:"name,omitempty"`
 			IP             []string `toml:"ip"`
 			PrivateIP      []string `toml:"private-ip"`
+			PrivateIPv6    []string `toml:"private-ipv6,omitempty"` // dual-stack
 			LbIP           string   `toml:"lb-ip,omitempty"`
 			LbPort         int      `toml:"lb-port,omitempty"`
 			Isolated       bool     `toml:"isolated,omitempty"`
@@ -80,15 +113,7 @@ type KoreOnToml struct {
 		Node StrNode `toml:"node,omitempty"`
 	} `toml:"node-pool,omitempty"`
 
-	SharedStorage struct {
-		Install    bool   `toml:"install"`
-		StorageIP  string `toml:"storage-ip,omitempty"`
-		PrivateIP  string `toml:"private-ip,omitempty"`
-		VolumeDir  string `toml:"volume-dir,omitempty"`
-		VolumeSize int    `toml:"volume-size,omitempty"`
-		//StorageType       string `toml:"storage-type,omitempty"`
-
-	} `toml:"shared-storage,omitempty"`
+	SharedStorage SharedStorage `toml:"shared-storage,omitempty"`
 
 	PrivateRegistry struct {
 		Install             bool   `toml:"install,omitempty"`
@@ -104,6 +129,18 @@ type KoreOnToml struct {
 			SslCert    string `toml:"ssl-cert,omitempty"`
 			SslCertKey string `toml:"ssl-cert-key,omitempty"`
 		} `toml:"cert-file,omitempty"`
+		External struct {
+			URL        string `toml:"url,omitempty"`
+			PathPrefix string `toml:"path-prefix,omitempty"`
+			CAFile     string `toml:"ca-file,omitempty"`
+			Username   string `toml:"username,omitempty"`
+			Password   string `toml:"password,omitempty"`
+		} `toml:"external,omitempty"`
+		Projects map[string]RegistryProject `toml:"projects,omitempty"`
+
+		// registry-manager backup, restore and upgrade
+		InstalledVersion string
+		BackupFile       string
 	} `toml:"private-registry,omitempty"`
 
 	PrepareAirgap struct {
@@ -116,6 +153,7 @@ type KoreOnToml struct {
 		PackageVersion   PackageVersion
 		ImageVersion     ImageVersion
 		HelmChartVersion HelmChartVersion
+		ChartImages      []string // images of the HelmChartVersion charts (mirror.ChartImages), prepare_airgap_images of the playbooks
 	}
 
 	ListVersion struct {
@@ -125,8 +163,143 @@ type KoreOnToml struct {
 	}
 }
 
+// SharedStorage - [shared-storage] backend of the default StorageClass
+type SharedStorage struct {
+	Install      bool   `toml:"install"`
+	StorageType  string `toml:"storage-type,omitempty"` // nfs (default), external-nfs, local-path, longhorn
+	StorageIP    string `toml:"storage-ip,omitempty"`
+	PrivateIP    string `toml:"private-ip,omitempty"`
+	VolumeDir    string `toml:"volume-dir,omitempty"`
+	VolumeSize   int    `toml:"volume-size,omitempty"`
+	ReplicaCount int    `toml:"replica-count,omitempty"` // longhorn volume replicas
+
+	// [[shared-storage.exports]] of nfs and external-nfs. volume-dir and volume-size are one export.
+	Exports []StorageExport `toml:"exports,omitempty"`
+
+	// An NFS server is installed on storage-ip (node-storage of the inventory)
+	Server bool `toml:"-"`
+}
+
+// StorageExport - NFS export and the StorageClass of the export
+type StorageExport struct {
+	Path          string   `toml:"path"`
+	Size          int      `toml:"size,omitempty"`          // quota in GiB (0: no quota)
+	AllowedCIDRs  []string `toml:"allowed-cidrs,omitempty"` // clients of the export (default: all)
+	StorageClass  string   `toml:"storage-class,omitempty"`
+	ReclaimPolicy string   `toml:"reclaim-policy,omitempty"` // Delete (default), Retain
+	MountOptions  []string `toml:"mount-options,omitempty"`
+	Default       bool     `toml:"default,omitempty"`
+}
+
+// CalicoIPPool - [[kubernetes.calico.ip-pools]]
+type CalicoIPPool struct {
+	Name               string `toml:"name"`
+	Cidr               string `toml:"cidr"`
+	BlockSize          int    `toml:"block-size,omitempty"`    // default: 26
+	NodeSelector       string `toml:"node-selector,omitempty"` // default: all()
+	Encapsulation      string `toml:"encapsulation,omitempty"` // default: [kubernetes.calico] encapsulation
+	DisableNatOutgoing bool   `toml:"disable-nat-outgoing,omitempty"`
+}
+
+// CalicoBgp - [kubernetes.calico.bgp]
+type CalicoBgp struct {
+	AsNumber        int             `toml:"as-number,omitempty"` // default: 64512
+	DisableNodeMesh bool            `toml:"disable-node-mesh,omitempty"`
+	Peers           []CalicoBgpPeer `toml:"peers,omitempty"`
+}
+
+// CalicoBgpPeer - [[kubernetes.calico.bgp.peers]] (e.g. top-of-rack router)
+type CalicoBgpPeer struct {
+	Name         string `toml:"name,omitempty"`
+	PeerIP       string `toml:"peer-ip"`
+	AsNumber     int    `toml:"as-number"`
+	NodeSelector string `toml:"node-selector,omitempty"` // default: all nodes
+}
+
+// CrioRegistry - [[kubernetes.cri-o.registries]] mirrors and the pull credential of a registry
+type CrioRegistry struct {
+	Prefix   string   `toml:"prefix"`            // e.g. docker.io, quay.io/calico
+	Mirrors  []string `toml:"mirrors,omitempty"` // host[:port][/path], tried in order before the prefix
+	Insecure bool     `toml:"insecure,omitempty"`
+	Username string   `toml:"username,omitempty"` // credential of the mirrors (or the prefix without mirrors)
+	Password string   `toml:"password,omitempty"`
+}
+
+// KubeComponent - [kubernetes.controller-manager], [kubernetes.scheduler]
+type KubeComponent struct {
+	ExtraArgs    map[string]string `toml:"extra-args,omitempty"`    // flag name (without "--") = value
+	FeatureGates map[string]bool   `toml:"feature-gates,omitempty"` // e.g. { GracefulNodeShutdown = true }
+}
+
+// KubeApiserver - [kubernetes.apiserver]
+type KubeApiserver struct {
+	ExtraArgs               map[string]string `toml:"extra-args,omitempty"`
+	FeatureGates            map[string]bool   `toml:"feature-gates,omitempty"`
+	EnableAdmissionPlugins  []string          `toml:"enable-admission-plugins,omitempty"` // NodeRestriction is always enabled
+	DisableAdmissionPlugins []string          `toml:"disable-admission-plugins,omitempty"`
+}
+
+// KubeKubelet - [kubernetes.kubelet] KubeletConfiguration fields and extra flags of all nodes
+type KubeKubelet struct {
+	ExtraArgs               map[string]string `toml:"extra-args,omitempty"`
+	FeatureGates            map[string]bool   `toml:"feature-gates,omitempty"`
+	MaxPods                 int               `toml:"max-pods,omitempty"`        // 0: kubelet default (110)
+	KubeReserved            map[string]string `toml:"kube-reserved,omitempty"`   // cpu, memory, ephemeral-storage, pid
+	SystemReserved          map[string]string `toml:"system-reserved,omitempty"` // cpu, memory, ephemeral-storage, pid
+	EvictionHard            map[string]string `toml:"eviction-hard,omitempty"`   // e.g. { "memory.available" = "500Mi" }
+	EvictionSoft            map[string]string `toml:"eviction-soft,omitempty"`
+	EvictionSoftGracePeriod map[string]string `toml:"eviction-soft-grace-period,omitempty"` // e.g. { "memory.available" = "1m30s" }
+}
+
+// KubeOidc - [kubernetes.oidc] OpenID Connect issuer trusted by the apiserver
+type KubeOidc struct {
+	IssuerURL      string   `toml:"issuer-url,omitempty"`      // e.g. https://keycloak.example.com/realms/k8s
+	ClientID       string   `toml:"client-id,omitempty"`       // audience (aud) of the id token
+	UsernameClaim  string   `toml:"username-claim,omitempty"`  // default: sub
+	UsernamePrefix string   `toml:"username-prefix,omitempty"` // "-": no prefix, default: "<issuer-url>#" (except email)
+	GroupsClaim    string   `toml:"groups-claim,omitempty"`
+	GroupsPrefix   string   `toml:"groups-prefix,omitempty"`
+	CAFile         string   `toml:"ca-file,omitempty"`      // CA of the issuer, copied to the config directory
+	ExtraScopes    []string `toml:"extra-scopes,omitempty"` // scopes requested by kubelogin (e.g. email, groups)
+}
+
 type StrNode struct {
-	Name      []string
-	IP        []string `toml:"ip"`
-	PrivateIP []string `toml:"private-ip"`
+	Name        []string
+	IP          []string `toml:"ip"`
+	PrivateIP   []string `toml:"private-ip"`
+	PrivateIPv6 []string `toml:"private-ipv6,omitempty"` // dual-stack
+}
+
+// RegistryProject - Harbor project of [private-registry.projects.<name>] managed by 'registry-manager'
+type RegistryProject struct {
+	Public       bool                     `toml:"public,omitempty"`
+	StorageLimit int                      `toml:"storage-limit,omitempty"` // GiB, 0 or -1: unlimited
+	ProxyCache   *RegistryProxyCache      `toml:"proxy-cache,omitempty"`
+	Retention    *RegistryRetention       `toml:"retention,omitempty"`
+	Robots       map[string]RegistryRobot `toml:"robots,omitempty"`
+}
+
+// RegistryProxyCache - Upstream registry of a proxy-cache project
+type RegistryProxyCache struct {
+	Type     string `toml:"type,omitempty"`
+	URL      string `toml:"url,omitempty"`
+	Username string `toml:"username,omitempty"`
+	Password string `toml:"password,omitempty"`
+	Insecure bool   `toml:"insecure,omitempty"`
+}
+
+// RegistryRetention - Tag retention rule of a project
+type RegistryRetention struct {
+	KeepLast     int    `toml:"keep-last,omitempty"`
+	KeepDays     int    `toml:"keep-days,omitempty"`
+	Tags         string `toml:"tags,omitempty"`
+	Repositories string `toml:"repositories,omitempty"`
+	Schedule     string `toml:"schedule,omitempty"`
+}
+
+// RegistryRobot - Project robot account (e.g. for CI pipelines)
+type RegistryRobot struct {
+	Description string   `toml:"description,omitempty"`
+	Duration    int      `toml:"duration,omitempty"` // days, 0 or -1: never expires
+	Permissions []string `toml:"permissions,omitempty"`
 }
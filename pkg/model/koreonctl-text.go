@@ -12,9 +12,10 @@ type KoreonctlText struct {
 }
 
 type updateNode struct {
-	IP        []string
-	PrivateIP []string
-	Name      []string
+	IP          []string
+	PrivateIP   []string
+	PrivateIPv6 []string
+	Name        []string
 }
 
 type printFormat struct {
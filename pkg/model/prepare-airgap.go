@@ -2,14 +2,16 @@ package model
 
 // validate: The first argument is a variable of SupportVersion,
 // and the second argument is a variable of the list of supported versions.
+// "optional" as the third argument: the component is not shipped with every kubernetes version.
 type PackageVersion struct {
 	Containerd    string `validate:"containerd,SupportContainerdVersion"`
+	Crio          string `validate:"cri-o,SupportCrioVersion"`
 	DockerCompose string `validate:"docker-compose,SupportDockerComposeVersion"`
 	Crictl        string `validate:"crictl,SupportCrictlVersion"`
 	Etcd          string `validate:"etcd,SupportEtcdVersion"`
 	Helm          string `validate:"helm,SupportHelmVersion"`
 	CalicoCtl     string `validate:"calicoctl,SupportCalicoCtlVersion"`
-	ClusterCtl    string `validate:"clusterctl,SupportClusterCtlVersion"`
+	ClusterCtl    string `validate:"clusterctl,SupportClusterCtlVersion,optional"`
 }
 
 type ImageVersion struct {
@@ -18,16 +20,24 @@ type ImageVersion struct {
 	MetricsServer string `validate:"metrics-server,SupportMetricsServerVersion"`
 	Pause         string `validate:"pause,SupportPauseVersion"`
 	DnsUtils      string `validate:"dns-utils,SupportDnsUtilsVersion"`
+	Cilium        string `validate:"cilium,SupportCiliumVersion"`
+	Flannel       string `validate:"flannel,SupportFlannelVersion"`
 }
 
 type HelmChartVersion struct {
-	CsiDriverNfs string `validate:"csi-driver-nfs,ChartCsiDriverNfsVersion"`
-	Koreboard    string `validate:"koreboard,ChartKoreboardVersion"`
+	CsiDriverNfs        string `validate:"csi-driver-nfs,ChartCsiDriverNfsVersion"`
+	Koreboard           string `validate:"koreboard,ChartKoreboardVersion"`
+	IngressNginx        string `validate:"ingress-nginx,ChartIngressNginxVersion"`
+	Metallb             string `validate:"metallb,ChartMetallbVersion"`
+	CertManager         string `validate:"cert-manager,ChartCertManagerVersion"`
+	KubePrometheusStack string `validate:"kube-prometheus-stack,ChartKubePrometheusStackVersion"`
+	Longhorn            string `validate:"longhorn,ChartLonghornVersion,optional"`
 }
 
 // List Versions
 type ListPackageVersion struct {
 	Containerd    map[string][]string `validate:"containerd,SupportContainerdVersion"`
+	Crio          map[string][]string `validate:"cri-o,SupportCrioVersion"`
 	DockerCompose map[string][]string `validate:"docker-compose,SupportDockerComposeVersion"`
 	Crictl        map[string][]string `validate:"crictl,SupportCrictlVersion"`
 	Etcd          map[string][]string `validate:"etcd,SupportEtcdVersion"`
@@ -42,9 +52,16 @@ type ListImageVersion struct {
 	MetricsServer map[string][]string `validate:"metrics-server,SupportMetricsServerVersion"`
 	Pause         map[string][]string `validate:"pause,SupportPauseVersion"`
 	DnsUtils      map[string][]string `validate:"dns-utils,SupportDnsUtilsVersion"`
+	Cilium        map[string][]string `validate:"cilium,SupportCiliumVersion"`
+	Flannel       map[string][]string `validate:"flannel,SupportFlannelVersion"`
 }
 
 type ListHelmChartVersion struct {
-	CsiDriverNfs map[string][]string `validate:"csi-driver-nfs,ChartCsiDriverNfsVersion"`
-	Koreboard    map[string][]string `validate:"koreboard,ChartKoreboardVersion"`
+	CsiDriverNfs        map[string][]string `validate:"csi-driver-nfs,ChartCsiDriverNfsVersion"`
+	Koreboard           map[string][]string `validate:"koreboard,ChartKoreboardVersion"`
+	IngressNginx        map[string][]string `validate:"ingress-nginx,ChartIngressNginxVersion"`
+	Metallb             map[string][]string `validate:"metallb,ChartMetallbVersion"`
+	CertManager         map[string][]string `validate:"cert-manager,ChartCertManagerVersion"`
+	KubePrometheusStack map[string][]string `validate:"kube-prometheus-stack,ChartKubePrometheusStackVersion"`
+	Longhorn            map[string][]string `validate:"longhorn,ChartLonghornVersion"`
 }
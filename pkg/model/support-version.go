@@ -0,0 +1,24 @@
+package model
+
+// ResolvedVersion - Component versions chosen for one kubernetes version
+type ResolvedVersion struct {
+	Kubernetes       string           `json:"kubernetes"`
+	Harbor           string           `json:"harbor"`
+	PackageVersion   PackageVersion   `json:"package"`
+	ImageVersion     ImageVersion     `json:"image"`
+	HelmChartVersion HelmChartVersion `json:"helm_chart"`
+}
+
+// SupportComponent - Supported versions of one component
+type SupportComponent struct {
+	Name    string          `json:"name"`
+	ConfKey string          `json:"conf_key"`
+	Latest  string          `json:"latest"`
+	Series  []SupportSeries `json:"series"`
+}
+
+// SupportSeries - Supported patch versions of one minor version
+type SupportSeries struct {
+	Minor    string   `json:"minor"`
+	Versions []string `json:"versions"`
+}
@@ -0,0 +1,259 @@
+// Package network - Address families of the cluster networks (IPv4 single stack, IPv4/IPv6 dual-stack)
+package network
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"kore-on/pkg/model"
+	"kore-on/pkg/version"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// IPv4 address family
+	IPv4 = "IPv4"
+	// IPv6 address family
+	IPv6 = "IPv6"
+
+	// DefaultPodCidr - pod_ip_range of basic.yaml when pod-cidr is not set
+	DefaultPodCidr = "10.4.0.0/20"
+	// DefaultServiceCidr - service_ip_range of basic.yaml when service-cidr is not set
+	DefaultServiceCidr = "10.96.0.0/20"
+
+	// dualStackVersion - IPv6DualStack GA
+	dualStackVersion = "v1.23"
+
+	// IPv6 pod cidr of a node (kube-controller-manager --node-cidr-mask-size-ipv6), the cluster cidr
+	// can be at most 16 bits larger than the node cidr
+	nodeCidrMaskSizeIPv6 = 64
+	maxNodeCidrBits      = 16
+
+	// largest service cidr of kube-apiserver (--service-cluster-ip-range)
+	minServiceMaskIPv4 = 12
+	minServiceMaskIPv6 = 108
+)
+
+var hostnameRegex = regexp.MustCompile(`^(\*\.)?[a-zA-Z0-9]([-a-zA-Z0-9]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([-a-zA-Z0-9]*[a-zA-Z0-9])?)*$`)
+
+// ===== [ Types ] =====
+
+// Cidrs - Cidrs of pod-cidr or service-cidr, one per address family
+type Cidrs struct {
+	IPv4 *net.IPNet
+	IPv6 *net.IPNet
+}
+
+// DualStack - IPv4 and IPv6 cidrs
+func (c Cidrs) DualStack() bool {
+	return c.IPv4 != nil && c.IPv6 != nil
+}
+
+// Get - Cidr of the address family
+func (c Cidrs) Get(family string) *net.IPNet {
+	if family == IPv6 {
+		return c.IPv6
+	}
+	return c.IPv4
+}
+
+// String - Comma-separated cidrs, IPv4 first (kubeadm podSubnet, serviceSubnet)
+func (c Cidrs) String() string {
+	cidrs := []string{}
+	for _, n := range []*net.IPNet{c.IPv4, c.IPv6} {
+		if n != nil {
+			cidrs = append(cidrs, n.String())
+		}
+	}
+	return strings.Join(cidrs, ",")
+}
+
+// ===== [ Private Functions ] =====
+
+// checkCidrs - Cidrs of the families. Only the IPv4 single stack and the IPv4 first dual-stack are supported,
+// the control plane (etcd, apiserver) uses the IPv4 addresses.
+func checkCidrs(name string, value string, minMaskIPv4 int, minMaskIPv6 int) (Cidrs, []error) {
+	cidrs := Cidrs{}
+	errs := []error{}
+
+	values := strings.Split(value, ",")
+	if len(values) > 2 {
+		return cidrs, []error{fmt.Errorf("%s %q: one cidr per address family (<IPv4 cidr>,<IPv6 cidr>)", name, value)}
+	}
+	for i, v := range values {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(v))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %q is not a cidr", name, strings.TrimSpace(v)))
+			continue
+		}
+
+		family := Family(ipNet.IP)
+		if cidrs.Get(family) != nil {
+			errs = append(errs, fmt.Errorf("%s %q: one cidr per address family", name, value))
+			continue
+		}
+		if family == IPv6 && i == 0 {
+			errs = append(errs, fmt.Errorf("%s %q: IPv6 single stack and IPv6 first dual-stack are not supported (<IPv4 cidr>,<IPv6 cidr>)", name, value))
+			continue
+		}
+
+		ones, _ := ipNet.Mask.Size()
+		if family == IPv4 && ones < minMaskIPv4 {
+			errs = append(errs, fmt.Errorf("%s %s: the IPv4 cidr can not be larger than /%d", name, ipNet, minMaskIPv4))
+		}
+		if family == IPv6 && ones < minMaskIPv6 {
+			errs = append(errs, fmt.Errorf("%s %s: the IPv6 cidr can not be larger than /%d", name, ipNet, minMaskIPv6))
+		}
+
+		if family == IPv6 {
+			cidrs.IPv6 = ipNet
+		} else {
+			cidrs.IPv4 = ipNet
+		}
+	}
+	return cidrs, errs
+}
+
+// checkNodeAddresses - Node addresses of a node pool: ip (or private-ip) is IPv4, private-ipv6 is the IPv6
+// address of the nodes in dual-stack. The addresses are not in the pod and service cidrs of the family.
+func checkNodeAddresses(name string, ips []string, privateIPs []string, privateIPv6s []string, pod Cidrs, service Cidrs, seen map[string]string) []error {
+	errs := []error{}
+	nodeIPs := ips
+	if len(privateIPs) > 0 {
+		nodeIPs = privateIPs
+	}
+
+	check := func(field string, i int, value string, family string) {
+		ip := net.ParseIP(value)
+		if ip == nil || Family(ip) != family {
+			errs = append(errs, fmt.Errorf("%s > %s[%d] %q is not an %s address", name, field, i, value, family))
+			return
+		}
+		for cidrName, cidr := range map[string]*net.IPNet{"pod-cidr": pod.Get(family), "service-cidr": service.Get(family)} {
+			if cidr != nil && cidr.Contains(ip) {
+				errs = append(errs, fmt.Errorf("%s > %s[%d] %s is in the %s %s", name, field, i, value, cidrName, cidr))
+			}
+		}
+		if prev, ok := seen[ip.String()]; ok {
+			errs = append(errs, fmt.Errorf("%s > %s[%d] %s is the address of %s", name, field, i, value, prev))
+		}
+		seen[ip.String()] = fmt.Sprintf("%s > %s[%d]", name, field, i)
+	}
+
+	field := "ip"
+	if len(privateIPs) > 0 {
+		field = "private-ip"
+	}
+	for i, v := range nodeIPs {
+		check(field, i, v, IPv4)
+	}
+
+	if !pod.DualStack() {
+		if len(privateIPv6s) > 0 {
+			errs = append(errs, fmt.Errorf("%s > private-ipv6 needs the dual-stack pod-cidr and service-cidr", name))
+		}
+		return errs
+	}
+	if len(privateIPv6s) != len(nodeIPs) {
+		errs = append(errs, fmt.Errorf("%s > private-ipv6 is required for the %d nodes in dual-stack (%d)", name, len(nodeIPs), len(privateIPv6s)))
+	}
+	for i, v := range privateIPv6s {
+		check("private-ipv6", i, v, IPv6)
+	}
+	return errs
+}
+
+// checkOverlap - Pod and service cidrs of the family do not overlap
+func checkOverlap(pod Cidrs, service Cidrs, family string) error {
+	p, s := pod.Get(family), service.Get(family)
+	if p != nil && s != nil && (p.Contains(s.IP) || s.Contains(p.IP)) {
+		return fmt.Errorf("kubernetes > the %s pod-cidr %s and service-cidr %s overlap", family, p, s)
+	}
+	return nil
+}
+
+// ===== [ Public Functions ] =====
+
+// Family - Address family of the ip
+func Family(ip net.IP) string {
+	if ip.To4() != nil {
+		return IPv4
+	}
+	return IPv6
+}
+
+// PodCidrs - Cidrs of pod-cidr (default: DefaultPodCidr)
+func PodCidrs(k *model.KoreOnToml) (Cidrs, []error) {
+	value := k.Kubernetes.PodCidr
+	if value == "" {
+		value = DefaultPodCidr
+	}
+	return checkCidrs("kubernetes > pod-cidr", value, 0, nodeCidrMaskSizeIPv6-maxNodeCidrBits)
+}
+
+// Validate - pod-cidr, service-cidr, the node addresses and api-sans of each address family.
+// pod-cidr and service-cidr are normalized ("<IPv4 cidr>,<IPv6 cidr>").
+func Validate(k *model.KoreOnToml) []error {
+	pod, errs := PodCidrs(k)
+
+	serviceValue := k.Kubernetes.ServiceCidr
+	if serviceValue == "" {
+		serviceValue = DefaultServiceCidr
+	}
+	service, serviceErrs := checkCidrs("kubernetes > service-cidr", serviceValue, minServiceMaskIPv4, minServiceMaskIPv6)
+	errs = append(errs, serviceErrs...)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// the node addresses are checked against the families of both cidrs
+	if pod.DualStack() != service.DualStack() {
+		return []error{fmt.Errorf("kubernetes > pod-cidr %s and service-cidr %s must have the same address families", pod, service)}
+	}
+	if pod.IPv6 != nil {
+		if ones, _ := pod.IPv6.Mask.Size(); ones >= nodeCidrMaskSizeIPv6 {
+			errs = append(errs, fmt.Errorf("kubernetes > pod-cidr %s: the IPv6 cidr must be larger than the /%d pod cidr of a node", pod.IPv6, nodeCidrMaskSizeIPv6))
+		}
+	}
+	for _, family := range []string{IPv4, IPv6} {
+		if err := checkOverlap(pod, service, family); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if pod.DualStack() {
+		if cur, err := version.Parse(k.Kubernetes.Version); err == nil {
+			if min, _ := version.Parse(dualStackVersion); cur.Compare(min) < 0 {
+				errs = append(errs, fmt.Errorf("kubernetes > dual-stack needs kubernetes %s or later (%s)", dualStackVersion, k.Kubernetes.Version))
+			}
+		}
+		if k.Kubernetes.Cni != "" && k.Kubernetes.Cni != "calico" {
+			errs = append(errs, fmt.Errorf("kubernetes > dual-stack is supported with the calico cni (%s)", k.Kubernetes.Cni))
+		}
+	}
+
+	seen := map[string]string{}
+	master := k.NodePool.Master
+	errs = append(errs, checkNodeAddresses("node-pool.master", master.IP, master.PrivateIP, master.PrivateIPv6, pod, service, seen)...)
+	node := k.NodePool.Node
+	errs = append(errs, checkNodeAddresses("node-pool.node", node.IP, node.PrivateIP, node.PrivateIPv6, pod, service, seen)...)
+
+	for i, san := range k.Kubernetes.ApiSans {
+		if net.ParseIP(san) == nil && !hostnameRegex.MatchString(san) {
+			errs = append(errs, fmt.Errorf("kubernetes > api-sans[%d] %q is not an ip address or a domain name", i, san))
+		}
+	}
+
+	if len(errs) == 0 {
+		if k.Kubernetes.PodCidr != "" {
+			k.Kubernetes.PodCidr = pod.String()
+		}
+		if k.Kubernetes.ServiceCidr != "" {
+			k.Kubernetes.ServiceCidr = service.String()
+		}
+	}
+	return errs
+}
@@ -0,0 +1,210 @@
+package network
+
+import (
+	"strings"
+	"testing"
+
+	"kore-on/pkg/model"
+)
+
+func testToml(podCidr string, serviceCidr string) *model.KoreOnToml {
+	k := &model.KoreOnToml{}
+	k.Kubernetes.Version = "v1.24.10"
+	k.Kubernetes.PodCidr = podCidr
+	k.Kubernetes.ServiceCidr = serviceCidr
+	k.NodePool.Master.IP = []string{"192.168.77.11"}
+	k.NodePool.Node.IP = []string{"192.168.77.21"}
+	return k
+}
+
+func TestPodCidrs(t *testing.T) {
+	cases := []struct {
+		value     string
+		cidrs     string
+		dualStack bool
+		err       string
+	}{
+		{value: "", cidrs: DefaultPodCidr},
+		{value: "10.4.0.0/16", cidrs: "10.4.0.0/16"},
+		{value: "10.4.0.1/16", cidrs: "10.4.0.0/16"},
+		{value: "10.4.0.0/16, fd00:10:4::/56", cidrs: "10.4.0.0/16,fd00:10:4::/56", dualStack: true},
+		{value: "fd00:10:4::/56", err: "IPv6 single stack and IPv6 first dual-stack are not supported"},
+		{value: "fd00:10:4::/56,10.4.0.0/16", err: "IPv6 single stack and IPv6 first dual-stack are not supported"},
+		{value: "10.4.0.0/16,10.5.0.0/16", err: "one cidr per address family"},
+		{value: "10.4.0.0/16,fd00::/56,fd01::/56", err: "one cidr per address family (<IPv4 cidr>,<IPv6 cidr>)"},
+		{value: "10.4.0.0/16,fd00:10:4::/40", err: "the IPv6 cidr can not be larger than /48"},
+		{value: "10.4.0.0", err: `kubernetes > pod-cidr "10.4.0.0" is not a cidr`},
+	}
+	for _, c := range cases {
+		cidrs, errs := PodCidrs(testToml(c.value, ""))
+		switch {
+		case c.err != "":
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), c.err) {
+				t.Errorf("PodCidrs(%q) = %v, want one error with %q", c.value, errs, c.err)
+			}
+		case len(errs) > 0:
+			t.Errorf("PodCidrs(%q) = %v", c.value, errs)
+		case cidrs.String() != c.cidrs || cidrs.DualStack() != c.dualStack:
+			t.Errorf("PodCidrs(%q) = %s (dual-stack %v), want %s", c.value, cidrs, cidrs.DualStack(), c.cidrs)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		pod     string
+		service string
+		change  func(k *model.KoreOnToml)
+		err     string
+	}{
+		{name: "defaults"},
+		{
+			name:    "dual-stack",
+			pod:     "10.4.0.0/16,fd00:10:4::/56",
+			service: "10.96.0.0/16,fd00:10:96::/112",
+			change: func(k *model.KoreOnToml) {
+				k.NodePool.Master.PrivateIPv6 = []string{"fd00:77::11"}
+				k.NodePool.Node.PrivateIPv6 = []string{"fd00:77::21"}
+			},
+		},
+		{
+			name:    "address families",
+			pod:     "10.4.0.0/16,fd00:10:4::/56",
+			service: "10.96.0.0/16",
+			err:     "must have the same address families",
+		},
+		{
+			name:    "IPv4 overlap",
+			pod:     "10.96.0.0/16",
+			service: "10.96.0.0/20",
+			err:     "the IPv4 pod-cidr 10.96.0.0/16 and service-cidr 10.96.0.0/20 overlap",
+		},
+		{
+			name:    "IPv6 overlap",
+			pod:     "10.4.0.0/16,fd00:10::/56",
+			service: "10.96.0.0/16,fd00:10::/112",
+			change: func(k *model.KoreOnToml) {
+				k.NodePool.Master.PrivateIPv6 = []string{"fd00:77::11"}
+				k.NodePool.Node.PrivateIPv6 = []string{"fd00:77::21"}
+			},
+			err: "the IPv6 pod-cidr fd00:10::/56 and service-cidr fd00:10::/112 overlap",
+		},
+		{
+			name:    "IPv6 service cidr",
+			pod:     "10.4.0.0/16,fd00:10:4::/56",
+			service: "10.96.0.0/16,fd00:10:96::/96",
+			err:     "the IPv6 cidr can not be larger than /108",
+		},
+		{
+			name:    "IPv6 node cidr",
+			pod:     "10.4.0.0/16,fd00:10:4::/64",
+			service: "10.96.0.0/16,fd00:10:96::/112",
+			change: func(k *model.KoreOnToml) {
+				k.NodePool.Master.PrivateIPv6 = []string{"fd00:77::11"}
+				k.NodePool.Node.PrivateIPv6 = []string{"fd00:77::21"}
+			},
+			err: "the IPv6 cidr must be larger than the /64 pod cidr of a node",
+		},
+		{
+			name:    "private-ipv6 required",
+			pod:     "10.4.0.0/16,fd00:10:4::/56",
+			service: "10.96.0.0/16,fd00:10:96::/112",
+			change:  func(k *model.KoreOnToml) { k.NodePool.Master.PrivateIPv6 = []string{"fd00:77::11"} },
+			err:     "node-pool.node > private-ipv6 is required for the 1 nodes in dual-stack (0)",
+		},
+		{
+			name:    "private-ipv6 family",
+			pod:     "10.4.0.0/16,fd00:10:4::/56",
+			service: "10.96.0.0/16,fd00:10:96::/112",
+			change: func(k *model.KoreOnToml) {
+				k.NodePool.Master.PrivateIPv6 = []string{"fd00:77::11"}
+				k.NodePool.Node.PrivateIPv6 = []string{"192.168.77.21"}
+			},
+			err: `node-pool.node > private-ipv6[0] "192.168.77.21" is not an IPv6 address`,
+		},
+		{
+			name:   "private-ipv6 of single stack",
+			change: func(k *model.KoreOnToml) { k.NodePool.Node.PrivateIPv6 = []string{"fd00:77::21"} },
+			err:    "node-pool.node > private-ipv6 needs the dual-stack pod-cidr and service-cidr",
+		},
+		{
+			name:   "node in the pod cidr",
+			pod:    "192.168.0.0/16",
+			err:    "node-pool.master > ip[0] 192.168.77.11 is in the pod-cidr 192.168.0.0/16",
+			change: func(k *model.KoreOnToml) { k.NodePool.Node.IP = []string{"10.10.10.21"} },
+		},
+		{
+			name:   "duplicated node address",
+			change: func(k *model.KoreOnToml) { k.NodePool.Node.IP = []string{"192.168.77.11"} },
+			err:    "node-pool.node > ip[0] 192.168.77.11 is the address of node-pool.master > ip[0]",
+		},
+		{
+			name:    "dual-stack version",
+			pod:     "10.4.0.0/16,fd00:10:4::/56",
+			service: "10.96.0.0/16,fd00:10:96::/112",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Version = "v1.22.15"
+				k.NodePool.Master.PrivateIPv6 = []string{"fd00:77::11"}
+				k.NodePool.Node.PrivateIPv6 = []string{"fd00:77::21"}
+			},
+			err: "kubernetes > dual-stack needs kubernetes v1.23 or later (v1.22.15)",
+		},
+		{
+			name:    "dual-stack cni",
+			pod:     "10.4.0.0/16,fd00:10:4::/56",
+			service: "10.96.0.0/16,fd00:10:96::/112",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.Cni = "cilium"
+				k.NodePool.Master.PrivateIPv6 = []string{"fd00:77::11"}
+				k.NodePool.Node.PrivateIPv6 = []string{"fd00:77::21"}
+			},
+			err: "kubernetes > dual-stack is supported with the calico cni (cilium)",
+		},
+		{
+			name: "api-sans",
+			change: func(k *model.KoreOnToml) {
+				k.Kubernetes.ApiSans = []string{"k8s.example.com", "192.168.77.10", "k8s_api"}
+			},
+			err: `kubernetes > api-sans[2] "k8s_api" is not an ip address or a domain name`,
+		},
+	}
+	for _, c := range cases {
+		k := testToml(c.pod, c.service)
+		if c.change != nil {
+			c.change(k)
+		}
+
+		errs := []string{}
+		for _, err := range Validate(k) {
+			errs = append(errs, err.Error())
+		}
+		switch {
+		case c.err == "" && len(errs) > 0:
+			t.Errorf("%s: Validate() = %v, want no errors", c.name, errs)
+		case c.err != "" && (len(errs) != 1 || !strings.Contains(errs[0], c.err)):
+			t.Errorf("%s: Validate() = %v, want one error with %q", c.name, errs, c.err)
+		}
+	}
+}
+
+func TestValidateNormalizes(t *testing.T) {
+	k := testToml("10.4.0.1/16, fd00:10:4::1/56", "10.96.0.0/16 ,fd00:10:96::/112")
+	k.NodePool.Master.PrivateIPv6 = []string{"fd00:77::11"}
+	k.NodePool.Node.PrivateIPv6 = []string{"fd00:77::21"}
+	if errs := Validate(k); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if k.Kubernetes.PodCidr != "10.4.0.0/16,fd00:10:4::/56" || k.Kubernetes.ServiceCidr != "10.96.0.0/16,fd00:10:96::/112" {
+		t.Errorf("pod-cidr = %s, service-cidr = %s, want the normalized cidrs", k.Kubernetes.PodCidr, k.Kubernetes.ServiceCidr)
+	}
+
+	// the default cidrs are not written to koreon.toml
+	k = testToml("", "")
+	if errs := Validate(k); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if k.Kubernetes.PodCidr != "" || k.Kubernetes.ServiceCidr != "" {
+		t.Errorf("pod-cidr = %s, service-cidr = %s, want empty", k.Kubernetes.PodCidr, k.Kubernetes.ServiceCidr)
+	}
+}
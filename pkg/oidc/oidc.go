@@ -0,0 +1,219 @@
+// Package oidc - OpenID Connect authentication of the apiserver ([kubernetes.oidc])
+package oidc
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"kore-on/pkg/model"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// DefaultUsernameClaim - --oidc-username-claim default of the apiserver
+	DefaultUsernameClaim = "sub"
+	// NoPrefix - username-prefix without a prefix
+	NoPrefix = "-"
+	// SigningAlg - --oidc-signing-algs default of the apiserver
+	SigningAlg = "RS256"
+)
+
+var (
+	// claim names and prefixes are rendered as apiserver flags and kubeconfig arguments
+	claimRegex = regexp.MustCompile(`^[A-Za-z0-9_.:/-]+$`)
+	scopeRegex = regexp.MustCompile(`^[\x21\x23-\x5B\x5D-\x7E]+$`)
+)
+
+// ===== [ Types ] =====
+
+// User - Username and groups of an id token as the apiserver authenticates them
+type User struct {
+	Name   string
+	Groups []string
+}
+
+// ===== [ Private Functions ] =====
+
+// checkCAFile - PEM certificates of the issuer CA
+func checkCAFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("ca-file %s is not found. Copy it to the config directory", filepath.Base(path))
+	}
+	found := false
+	for block, rest := pem.Decode(b); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("ca-file %s: %s", filepath.Base(path), err.Error())
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("ca-file %s: no certificate found", filepath.Base(path))
+	}
+	return nil
+}
+
+// claimString - String value of a claim
+func claimString(claims map[string]interface{}, name string) (string, error) {
+	v, ok := claims[name]
+	if !ok {
+		return "", fmt.Errorf("claim %q is not present", name)
+	}
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return "", fmt.Errorf("claim %q is not a string", name)
+	}
+	return s, nil
+}
+
+// claimStrings - A string or an array of strings (aud, groups)
+func claimStrings(claims map[string]interface{}, name string) ([]string, error) {
+	switch v := claims[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		values := []string{}
+		for _, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("claim %q is not an array of strings", name)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("claim %q is not a string or an array of strings", name)
+	}
+}
+
+// ===== [ Public Functions ] =====
+
+// Enabled - [kubernetes.oidc] is set
+func Enabled(k *model.KoreOnToml) bool {
+	return k.Kubernetes.Oidc.IssuerURL != ""
+}
+
+// UsernameClaim - username-claim or the apiserver default
+func UsernameClaim(cfg model.KubeOidc) string {
+	if cfg.UsernameClaim == "" {
+		return DefaultUsernameClaim
+	}
+	return cfg.UsernameClaim
+}
+
+// UsernamePrefix - Prefix of the usernames. Without username-prefix the apiserver prefixes
+// every claim except email with "<issuer-url>#".
+func UsernamePrefix(cfg model.KubeOidc) string {
+	switch {
+	case cfg.UsernamePrefix == NoPrefix:
+		return ""
+	case cfg.UsernamePrefix != "":
+		return cfg.UsernamePrefix
+	case UsernameClaim(cfg) == "email":
+		return ""
+	default:
+		return cfg.IssuerURL + "#"
+	}
+}
+
+// Validate - [kubernetes.oidc] of koreon.toml. caDir is the directory of the ca-file.
+func Validate(k *model.KoreOnToml, caDir string) []error {
+	errs := []error{}
+	cfg := k.Kubernetes.Oidc
+	if cfg.IssuerURL == "" {
+		if cfg.ClientID != "" || cfg.UsernameClaim != "" || cfg.GroupsClaim != "" || cfg.CAFile != "" {
+			errs = append(errs, fmt.Errorf("issuer-url is required"))
+		}
+		return errs
+	}
+
+	u, err := url.Parse(cfg.IssuerURL)
+	switch {
+	case err != nil || u.Host == "":
+		errs = append(errs, fmt.Errorf("issuer-url %q is not a url", cfg.IssuerURL))
+	case u.Scheme != "https":
+		errs = append(errs, fmt.Errorf("issuer-url %q must be https", cfg.IssuerURL))
+	case u.User != nil || u.RawQuery != "" || u.Fragment != "" || strings.HasSuffix(cfg.IssuerURL, "?") || strings.HasSuffix(cfg.IssuerURL, "#"):
+		errs = append(errs, fmt.Errorf("issuer-url %q must not have a user, query or fragment", cfg.IssuerURL))
+	}
+
+	if cfg.ClientID == "" {
+		errs = append(errs, fmt.Errorf("client-id is required"))
+	} else if !claimRegex.MatchString(cfg.ClientID) {
+		errs = append(errs, fmt.Errorf("client-id %q has invalid characters", cfg.ClientID))
+	}
+
+	for _, c := range []struct{ name, value string }{
+		{"username-claim", cfg.UsernameClaim},
+		{"username-prefix", cfg.UsernamePrefix},
+		{"groups-claim", cfg.GroupsClaim},
+		{"groups-prefix", cfg.GroupsPrefix},
+	} {
+		if c.value != "" && !claimRegex.MatchString(c.value) {
+			errs = append(errs, fmt.Errorf("%s %q has invalid characters", c.name, c.value))
+		}
+	}
+	if cfg.GroupsPrefix != "" && cfg.GroupsClaim == "" {
+		errs = append(errs, fmt.Errorf("groups-prefix is set without groups-claim"))
+	}
+	if strings.HasPrefix(UsernamePrefix(cfg), "system:") || strings.HasPrefix(cfg.GroupsPrefix, "system:") {
+		errs = append(errs, fmt.Errorf("username-prefix and groups-prefix must not start with \"system:\""))
+	}
+
+	for _, scope := range cfg.ExtraScopes {
+		if !scopeRegex.MatchString(scope) {
+			errs = append(errs, fmt.Errorf("extra-scopes > %q is not a scope", scope))
+		} else if scope == "openid" {
+			errs = append(errs, fmt.Errorf("extra-scopes > \"openid\" is always requested"))
+		}
+	}
+
+	if cfg.CAFile != "" {
+		if err := checkCAFile(filepath.Join(caDir, filepath.Base(cfg.CAFile))); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// MapUser - Username and groups of the verified claims, the same way as the apiserver
+func MapUser(cfg model.KubeOidc, claims map[string]interface{}) (*User, error) {
+	claim := UsernameClaim(cfg)
+	name, err := claimString(claims, claim)
+	if err != nil {
+		return nil, err
+	}
+	if claim == "email" {
+		// the apiserver rejects unverified emails
+		if verified, ok := claims["email_verified"]; ok {
+			if b, ok := verified.(bool); !ok || !b {
+				return nil, fmt.Errorf("email %q is not verified (email_verified)", name)
+			}
+		}
+	}
+
+	user := &User{Name: UsernamePrefix(cfg) + name}
+	if cfg.GroupsClaim != "" {
+		groups, err := claimStrings(claims, cfg.GroupsClaim)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			user.Groups = append(user.Groups, cfg.GroupsPrefix+g)
+		}
+	}
+	return user, nil
+}
@@ -0,0 +1,181 @@
+package oidc
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"kore-on/pkg/model"
+)
+
+const testIssuer = "https://keycloak.example.com/realms/k8s"
+
+func testConfig() model.KubeOidc {
+	return model.KubeOidc{
+		IssuerURL:     testIssuer,
+		ClientID:      "kubernetes",
+		UsernameClaim: "email",
+		GroupsClaim:   "groups",
+		GroupsPrefix:  "oidc:",
+	}
+}
+
+// standIn - Stand-in of the issuer and its provider from the discovery
+func standIn(t *testing.T, issuerURL string) (*StandIn, *Provider) {
+	t.Helper()
+	s, err := NewStandIn(issuerURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	p, err := Discover(context.Background(), s.Client(), issuerURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Keys() != 1 {
+		t.Fatalf("Keys() = %d, want 1", p.Keys())
+	}
+	return s, p
+}
+
+func token(t *testing.T, s *StandIn, claims map[string]interface{}) string {
+	t.Helper()
+	token, err := s.Token(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyMapUser(t *testing.T) {
+	cfg := testConfig()
+	s, p := standIn(t, cfg.IssuerURL)
+
+	now := time.Now()
+	claims, err := p.Verify(token(t, s, s.SampleClaims(cfg, now)), cfg.ClientID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := MapUser(cfg, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the email claim has no username prefix
+	want := &User{Name: "developer@koreon.local", Groups: []string{"oidc:developers"}}
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("MapUser() = %+v, want %+v", user, want)
+	}
+
+	user, err = CheckStandIn(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("CheckStandIn() = %+v, want %+v", user, want)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	cfg := testConfig()
+	s, p := standIn(t, cfg.IssuerURL)
+	other, _ := standIn(t, cfg.IssuerURL)
+
+	now := time.Now()
+	cases := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{"wrong audience", token(t, s, func() map[string]interface{} {
+			claims := s.SampleClaims(cfg, now)
+			claims["aud"] = []string{"dashboard"}
+			return claims
+		}()), `does not contain client-id "kubernetes"`},
+		{"expired", token(t, s, s.SampleClaims(cfg, now.Add(-10*time.Minute))), "token is expired at"},
+		{"not yet valid", token(t, s, func() map[string]interface{} {
+			claims := s.SampleClaims(cfg, now)
+			claims["nbf"] = now.Add(time.Minute).Unix()
+			return claims
+		}()), "token is not valid before"},
+		{"other issuer", token(t, s, func() map[string]interface{} {
+			claims := s.SampleClaims(cfg, now)
+			claims["iss"] = "https://other.example.com"
+			return claims
+		}()), "token issuer"},
+		{"other key", token(t, other, other.SampleClaims(cfg, now)), "token signature is not verified"},
+		{"not a jwt", "abc.def", "token is not a JWT"},
+	}
+	for _, c := range cases {
+		_, err := p.Verify(c.token, cfg.ClientID, now)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: Verify() = %v, want an error with %q", c.name, err, c.err)
+		}
+	}
+}
+
+func TestMapUser(t *testing.T) {
+	cases := []struct {
+		name   string
+		cfg    func(*model.KubeOidc)
+		claims map[string]interface{}
+		user   *User
+		err    string
+	}{
+		{
+			name:   "unverified email",
+			claims: map[string]interface{}{"email": "developer@koreon.local", "email_verified": false},
+			err:    `email "developer@koreon.local" is not verified (email_verified)`,
+		},
+		{
+			name:   "groups prefix",
+			claims: map[string]interface{}{"email": "developer@koreon.local", "groups": []interface{}{"developers", "admins"}},
+			user:   &User{Name: "developer@koreon.local", Groups: []string{"oidc:developers", "oidc:admins"}},
+		},
+		{
+			name:   "groups string",
+			claims: map[string]interface{}{"email": "developer@koreon.local", "groups": "developers"},
+			user:   &User{Name: "developer@koreon.local", Groups: []string{"oidc:developers"}},
+		},
+		{
+			name:   "issuer prefix",
+			cfg:    func(cfg *model.KubeOidc) { cfg.UsernameClaim = "preferred_username" },
+			claims: map[string]interface{}{"preferred_username": "developer"},
+			user:   &User{Name: testIssuer + "#developer"},
+		},
+		{
+			name: "no prefix",
+			cfg: func(cfg *model.KubeOidc) {
+				cfg.UsernameClaim = "sub"
+				cfg.UsernamePrefix = NoPrefix
+				cfg.GroupsClaim = ""
+			},
+			claims: map[string]interface{}{"sub": "1234", "groups": []interface{}{"developers"}},
+			user:   &User{Name: "1234"},
+		},
+		{
+			name:   "missing claim",
+			claims: map[string]interface{}{"sub": "1234"},
+			err:    `claim "email" is not present`,
+		},
+	}
+	for _, c := range cases {
+		cfg := testConfig()
+		if c.cfg != nil {
+			c.cfg(&cfg)
+		}
+		user, err := MapUser(cfg, c.claims)
+		switch {
+		case c.err != "":
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: MapUser() = %v, want an error with %q", c.name, err, c.err)
+			}
+		case err != nil:
+			t.Errorf("%s: MapUser() = %v", c.name, err)
+		case !reflect.DeepEqual(user, c.user):
+			t.Errorf("%s: MapUser() = %+v, want %+v", c.name, user, c.user)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	// maxResponseSize - discovery document and key set
+	maxResponseSize = 1 << 20
+)
+
+// ===== [ Types ] =====
+
+// Provider - Discovery document and signing keys of an issuer
+type Provider struct {
+	Issuer  string
+	JwksURI string
+	Algs    []string
+	keys    map[string]*rsa.PublicKey
+}
+
+type discovery struct {
+	Issuer  string   `json:"issuer"`
+	JwksURI string   `json:"jwks_uri"`
+	Algs    []string `json:"id_token_signing_alg_values_supported"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// ===== [ Implementations ] =====
+
+// Keys - Number of the RSA signing keys
+func (p *Provider) Keys() int {
+	return len(p.keys)
+}
+
+// Verify - Signature, issuer, audience and expiry of an id token. The claims of the token are returned.
+func (p *Provider) Verify(token string, clientID string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token is not a JWT")
+	}
+
+	header := jwtHeader{}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("token header: %s", err.Error())
+	}
+	if header.Alg != SigningAlg {
+		return nil, fmt.Errorf("token is signed with %q, the apiserver accepts %s", header.Alg, SigningAlg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("token signature: %s", err.Error())
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	verified := false
+	for kid, key := range p.keys {
+		if header.Kid != "" && kid != header.Kid {
+			continue
+		}
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("token signature is not verified by the keys of %s", p.JwksURI)
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("token claims: %s", err.Error())
+	}
+	if iss, _ := claims["iss"].(string); iss != p.Issuer {
+		return nil, fmt.Errorf("token issuer %q is not %q", iss, p.Issuer)
+	}
+	aud, err := claimStrings(claims, "aud")
+	if err != nil {
+		return nil, err
+	}
+	if !contains(aud, clientID) {
+		return nil, fmt.Errorf("token audience %v does not contain client-id %q", aud, clientID)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("token has no expiry (exp)")
+	}
+	if now.After(time.Unix(int64(exp), 0)) {
+		return nil, fmt.Errorf("token is expired at %s", time.Unix(int64(exp), 0).Format(time.RFC3339))
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("token is not valid before %s", time.Unix(int64(nbf), 0).Format(time.RFC3339))
+	}
+
+	return claims, nil
+}
+
+// ===== [ Private Functions ] =====
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("%s: %s", url, err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %s", url, err.Error())
+	}
+	return nil
+}
+
+// rsaKey - Public key of a RSA jwk
+func rsaKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: n: %s", k.Kid, err.Error())
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("key %q: invalid exponent", k.Kid)
+	}
+	exponent := 0
+	for _, b := range e {
+		exponent = exponent<<8 | int(b)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ===== [ Public Functions ] =====
+
+// HTTPClient - Client trusting the system roots and the CA certificates of caFile
+func HTTPClient(caFile string) (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificate found", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}, nil
+}
+
+// Discover - Discovery document and signing keys of the issuer. The issuer of the document must be
+// the issuer url exactly, as the apiserver checks it.
+func Discover(ctx context.Context, client *http.Client, issuerURL string) (*Provider, error) {
+	doc := discovery{}
+	if err := getJSON(ctx, client, strings.TrimSuffix(issuerURL, "/")+discoveryPath, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Issuer != issuerURL {
+		return nil, fmt.Errorf("issuer of the discovery document %q is not issuer-url %q", doc.Issuer, issuerURL)
+	}
+	if doc.JwksURI == "" {
+		return nil, fmt.Errorf("discovery document has no jwks_uri")
+	}
+	if len(doc.Algs) > 0 && !contains(doc.Algs, SigningAlg) {
+		return nil, fmt.Errorf("issuer signs id tokens with %v, the apiserver accepts %s", doc.Algs, SigningAlg)
+	}
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	if err := getJSON(ctx, client, doc.JwksURI, &set); err != nil {
+		return nil, err
+	}
+
+	p := &Provider{Issuer: doc.Issuer, JwksURI: doc.JwksURI, Algs: doc.Algs, keys: map[string]*rsa.PublicKey{}}
+	for i, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != SigningAlg) {
+			continue
+		}
+		key, err := rsaKey(k)
+		if err != nil {
+			return nil, err
+		}
+		kid := k.Kid
+		if kid == "" {
+			kid = fmt.Sprintf("#%d", i)
+		}
+		p.keys[kid] = key
+	}
+	if len(p.keys) == 0 {
+		return nil, fmt.Errorf("%s has no %s signing key", doc.JwksURI, SigningAlg)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,250 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"kore-on/pkg/model"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	standInKid  = "koreon-stand-in"
+	standInSub  = "koreon-oidc-check"
+	standInUser = "developer@koreon.local"
+	// standInGroup - group of the sample token
+	standInGroup = "developers"
+)
+
+// ===== [ Types ] =====
+
+// StandIn - Local issuer answering for the issuer url of koreon.toml. Discovery and the key set are
+// served with a generated CA on 127.0.0.1, so the configuration is checked without network.
+type StandIn struct {
+	Issuer   string
+	CA       []byte // PEM
+	key      *rsa.PrivateKey
+	pool     *x509.CertPool
+	listener net.Listener
+	server   *http.Server
+}
+
+// ===== [ Implementations ] =====
+
+// Client - Client of the stand-in. Every address is dialed to the stand-in and only its CA is trusted.
+func (s *StandIn) Client() *http.Client {
+	addr := s.listener.Addr().String()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.TLSClientConfig = &tls.Config{RootCAs: s.pool, MinVersion: tls.VersionTLS12}
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, network, addr)
+	}
+	return &http.Client{Transport: transport, Timeout: 10 * time.Second}
+}
+
+// Token - RS256 id token of the claims signed by the stand-in
+func (s *StandIn) Token(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: SigningAlg, Kid: standInKid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// SampleClaims - Claims of an id token issued to the client, with the username and groups claims of the configuration
+func (s *StandIn) SampleClaims(cfg model.KubeOidc, now time.Time) map[string]interface{} {
+	claims := map[string]interface{}{
+		"iss": s.Issuer,
+		"aud": cfg.ClientID,
+		"sub": standInSub,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	switch claim := UsernameClaim(cfg); claim {
+	case DefaultUsernameClaim:
+	case "email":
+		claims["email"] = standInUser
+		claims["email_verified"] = true
+	default:
+		claims[claim] = standInUser
+	}
+	if cfg.GroupsClaim != "" {
+		claims[cfg.GroupsClaim] = []string{standInGroup}
+	}
+	return claims
+}
+
+// Close - Stop the stand-in
+func (s *StandIn) Close() error {
+	return s.server.Close()
+}
+
+func (s *StandIn) serve(path string) {
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []jwk{{
+			Kty: "RSA",
+			Kid: standInKid,
+			Use: "sig",
+			Alg: SigningAlg,
+			N:   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+	doc, _ := json.Marshal(discovery{
+		Issuer:  s.Issuer,
+		JwksURI: strings.TrimSuffix(s.Issuer, "/") + "/keys",
+		Algs:    []string{SigningAlg},
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path+discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	})
+	mux.HandleFunc(path+"/keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jwks)
+	})
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go s.server.Serve(s.listener)
+}
+
+// ===== [ Private Functions ] =====
+
+// standInCerts - CA and the server certificate of the issuer host
+func standInCerts(host string) ([]byte, tls.Certificate, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	now := time.Now()
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "koreon oidc stand-in ca"},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	ca, err = x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	server := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		server.IPAddresses = []net.IP{ip}
+	} else {
+		server.DNSNames = []string{host}
+	}
+	serverDER, err := x509.CreateCertificate(rand.Reader, server, ca, &serverKey.PublicKey, caKey)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	return caPEM, tls.Certificate{Certificate: [][]byte{serverDER}, PrivateKey: serverKey}, nil
+}
+
+// ===== [ Public Functions ] =====
+
+// NewStandIn - Start a stand-in of the issuer url
+func NewStandIn(issuerURL string) (*StandIn, error) {
+	u, err := url.Parse(issuerURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("issuer-url %q is not a url", issuerURL)
+	}
+
+	caPEM, cert, err := standInCerts(u.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caPEM)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &StandIn{Issuer: issuerURL, CA: caPEM, key: key, pool: pool, listener: listener}
+	s.serve(strings.TrimSuffix(u.EscapedPath(), "/"))
+	return s, nil
+}
+
+// CheckStandIn - Discovery, id token verification and the username and groups mapping of the
+// configuration against a stand-in of the issuer. The user of a sample token is returned.
+func CheckStandIn(ctx context.Context, cfg model.KubeOidc) (*User, error) {
+	s, err := NewStandIn(cfg.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	provider, err := Discover(ctx, s.Client(), cfg.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	token, err := s.Token(s.SampleClaims(cfg, now))
+	if err != nil {
+		return nil, err
+	}
+	claims, err := provider.Verify(token, cfg.ClientID, now)
+	if err != nil {
+		return nil, err
+	}
+	return MapUser(cfg, claims)
+}
@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/apenella/go-ansible/pkg/adhoc"
+	"github.com/apenella/go-ansible/pkg/execute"
+	"github.com/apenella/go-ansible/pkg/options"
+	"github.com/apenella/go-ansible/pkg/playbook"
+	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
+)
+
+// ===== [ Types ] =====
+
+// AnsibleRunner - Runs tasks with ansible-playbook through go-ansible
+type AnsibleRunner struct {
+	handlers
+}
+
+// ===== [ Implementations ] =====
+
+// Plan - Build the ansible-playbook command line of the task
+func (r *AnsibleRunner) Plan(task *Task) (*Plan, error) {
+	plan, err := planTask(task)
+	if err != nil {
+		r.emit(EventFailed, plan, err)
+		return nil, err
+	}
+	r.emit(EventPlanned, plan, nil)
+	return plan, nil
+}
+
+// Run - Execute the task with ansible-playbook
+func (r *AnsibleRunner) Run(ctx context.Context, task *Task) error {
+	plan, err := r.Plan(task)
+	if err != nil {
+		return err
+	}
+
+	transformers := append([]results.TransformerFunc{}, task.Transformers...)
+	transformers = append(transformers, results.Prepend(task.Name))
+
+	executeOptions := []execute.ExecuteOptions{execute.WithTransformers(transformers...)}
+	if task.Output != nil {
+		executeOptions = append(executeOptions, execute.WithWrite(task.Output))
+	} else {
+		options.AnsibleForceColor()
+	}
+
+	var run func(context.Context) error
+	if task.Module != "" {
+		cmd := adhocCmd(task)
+		cmd.Exec = execute.NewDefaultExecute(executeOptions...)
+		run = cmd.Run
+	} else {
+		cmd := playbookCmd(task)
+		cmd.Exec = execute.NewDefaultExecute(executeOptions...)
+		run = cmd.Run
+	}
+
+	r.emit(EventStarted, plan, nil)
+	if err := run(ctx); err != nil {
+		r.emit(EventFailed, plan, err)
+		return err
+	}
+	r.emit(EventFinished, plan, nil)
+
+	return nil
+}
+
+// ===== [ Private Functions ] =====
+
+func playbookCmd(task *Task) *playbook.AnsiblePlaybookCmd {
+	ansiblePlaybookConnectionOptions := &options.AnsibleConnectionOptions{
+		PrivateKey: task.PrivateKey,
+		User:       task.User,
+		Connection: task.Connection,
+	}
+
+	ansiblePlaybookOptions := &playbook.AnsiblePlaybookOptions{
+		Inventory:     task.Inventory,
+		Verbose:       task.Verbose,
+		Tags:          task.Tags,
+		ExtraVars:     task.ExtraVars,
+		ExtraVarsFile: task.ExtraVarsFile,
+	}
+
+	return &playbook.AnsiblePlaybookCmd{
+		Playbooks:         task.Playbooks,
+		ConnectionOptions: ansiblePlaybookConnectionOptions,
+		Options:           ansiblePlaybookOptions,
+		StdoutCallback:    task.StdoutCallback,
+	}
+}
+
+func adhocCmd(task *Task) *adhoc.AnsibleAdhocCmd {
+	ansibleAdhocConnectionOptions := &options.AnsibleConnectionOptions{
+		PrivateKey: task.PrivateKey,
+		User:       task.User,
+		Connection: task.Connection,
+	}
+
+	ansibleAdhocOptions := &adhoc.AnsibleAdhocOptions{
+		Inventory:     task.Inventory,
+		Verbose:       task.Verbose,
+		ModuleName:    task.Module,
+		Args:          task.ModuleArgs,
+		ExtraVars:     task.ExtraVars,
+		ExtraVarsFile: task.ExtraVarsFile,
+	}
+
+	return &adhoc.AnsibleAdhocCmd{
+		Pattern:           task.Pattern,
+		ConnectionOptions: ansibleAdhocConnectionOptions,
+		Options:           ansibleAdhocOptions,
+		StdoutCallback:    task.StdoutCallback,
+	}
+}
+
+func planTask(task *Task) (*Plan, error) {
+	plan := &Plan{Task: task}
+	if err := task.Validate(); err != nil {
+		return plan, err
+	}
+
+	var command []string
+	var err error
+	if task.Module != "" {
+		command, err = adhocCmd(task).Command()
+	} else {
+		command, err = playbookCmd(task).Command()
+	}
+	if err != nil {
+		return plan, err
+	}
+	plan.Command = command
+
+	return plan, nil
+}
+
+// ===== [ Public Functions ] =====
+
+// NewAnsibleRunner - Create the go-ansible runner
+func NewAnsibleRunner() *AnsibleRunner {
+	return &AnsibleRunner{}
+}
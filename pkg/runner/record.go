@@ -0,0 +1,215 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ===== [ Types ] =====
+
+// RecordRunner - Appends every planned task to a JSON lines file instead of running it
+type RecordRunner struct {
+	handlers
+	file string
+	mu   sync.Mutex
+}
+
+// ReplayRunner - Checks every task against a recording made by RecordRunner.
+// Tasks must arrive in the recorded order; nothing is executed.
+type ReplayRunner struct {
+	handlers
+	file     string
+	recorded []Plan
+	next     int
+	mu       sync.Mutex
+}
+
+// ===== [ Implementations ] =====
+
+// Plan - Build the command line of the task
+func (r *RecordRunner) Plan(task *Task) (*Plan, error) {
+	plan, err := planTask(task)
+	if err != nil {
+		r.emit(EventFailed, plan, err)
+		return nil, err
+	}
+	r.emit(EventPlanned, plan, nil)
+	return plan, nil
+}
+
+// Run - Append the plan of the task to the recording file
+func (r *RecordRunner) Run(ctx context.Context, task *Task) error {
+	plan, err := r.Plan(task)
+	if err != nil {
+		return err
+	}
+	r.emit(EventStarted, plan, nil)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, err := json.Marshal(plan)
+	if err != nil {
+		r.emit(EventFailed, plan, err)
+		return err
+	}
+
+	f, err := os.OpenFile(r.file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		r.emit(EventFailed, plan, err)
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		r.emit(EventFailed, plan, err)
+		return err
+	}
+	r.emit(EventFinished, plan, nil)
+
+	return nil
+}
+
+// Plan - Build the command line of the task
+func (r *ReplayRunner) Plan(task *Task) (*Plan, error) {
+	plan, err := planTask(task)
+	if err != nil {
+		r.emit(EventFailed, plan, err)
+		return nil, err
+	}
+	r.emit(EventPlanned, plan, nil)
+	return plan, nil
+}
+
+// Run - Compare the task with the next recorded task
+func (r *ReplayRunner) Run(ctx context.Context, task *Task) error {
+	plan, err := r.Plan(task)
+	if err != nil {
+		return err
+	}
+	r.emit(EventStarted, plan, nil)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next >= len(r.recorded) {
+		err := fmt.Errorf("replay %s: unexpected task %q, all %d recorded tasks were already replayed", r.file, task.Name, len(r.recorded))
+		r.emit(EventFailed, plan, err)
+		return err
+	}
+
+	expected := r.recorded[r.next]
+	r.next++
+
+	if diff := diffPlan(&expected, plan); len(diff) > 0 {
+		err := fmt.Errorf("replay %s: task %d (%q) does not match the recording: %v", r.file, r.next, task.Name, diff)
+		r.emit(EventFailed, plan, err)
+		return err
+	}
+	r.emit(EventFinished, plan, nil)
+
+	return nil
+}
+
+// Remaining - Number of recorded tasks that have not been replayed yet
+func (r *ReplayRunner) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.recorded) - r.next
+}
+
+// Done - Error when recorded tasks have not been replayed (the command ran fewer tasks than the recording)
+func (r *ReplayRunner) Done() error {
+	if remaining := r.Remaining(); remaining > 0 {
+		return fmt.Errorf("replay %s: %d of %d recorded tasks were not run", r.file, remaining, len(r.recorded))
+	}
+	return nil
+}
+
+// ===== [ Private Functions ] =====
+
+// diffPlan - Names of the task fields that differ between two plans
+func diffPlan(expected *Plan, actual *Plan) []string {
+	want, err := normalize(expected.Task)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	got, err := normalize(actual.Task)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	keys := map[string]bool{}
+	for k := range want {
+		keys[k] = true
+	}
+	for k := range got {
+		keys[k] = true
+	}
+
+	diff := []string{}
+	for k := range keys {
+		if !reflect.DeepEqual(want[k], got[k]) {
+			diff = append(diff, k)
+		}
+	}
+	sort.Strings(diff)
+
+	return diff
+}
+
+// normalize - Convert a task into its JSON representation so that recorded and live values compare equal
+func normalize(task *Task) (map[string]interface{}, error) {
+	var data map[string]interface{}
+	b, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ===== [ Public Functions ] =====
+
+// NewRecordRunner - Create a runner that records tasks to file
+func NewRecordRunner(file string) *RecordRunner {
+	return &RecordRunner{file: file}
+}
+
+// NewReplayRunner - Create a runner that replays the tasks recorded in file
+func NewReplayRunner(file string) (*ReplayRunner, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	runner := &ReplayRunner{file: file}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var plan Plan
+		if err := json.Unmarshal(line, &plan); err != nil {
+			return nil, fmt.Errorf("replay %s: %s", file, err.Error())
+		}
+		runner.recorded = append(runner.recorded, plan)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return runner, nil
+}
@@ -0,0 +1,190 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testTask() *Task {
+	return &Task{
+		Name:       "Create Cluster",
+		Playbooks:  []string{"./internal/playbooks/koreon-playbook/cluster.yaml"},
+		Inventory:  "./internal/playbooks/koreon-playbook/inventory/inventory.ini",
+		Tags:       "prepare-airgap",
+		PrivateKey: "/home/koreon/.ssh/id_rsa",
+		User:       "koreon",
+		ExtraVars: map[string]interface{}{
+			"KoreOn":     map[string]interface{}{"ClusterName": "test", "ClosedNetwork": true},
+			"Kubernetes": map[string]interface{}{"Version": "v1.24.10", "PodCidr": "10.4.0.0/20"},
+		},
+	}
+}
+
+func record(t *testing.T, tasks ...*Task) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "record.jsonl")
+	r := NewRecordRunner(file)
+	for _, task := range tasks {
+		if err := r.Run(context.Background(), task); err != nil {
+			t.Fatalf("record %q: %v", task.Name, err)
+		}
+	}
+	return file
+}
+
+func replay(t *testing.T, file string) *ReplayRunner {
+	t.Helper()
+	r, err := NewReplayRunner(file)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	return r
+}
+
+func TestRecordWritesTask(t *testing.T) {
+	task := testTask()
+	file := record(t, task)
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	plans := []Plan{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var plan Plan
+		if err := json.Unmarshal(scanner.Bytes(), &plan); err != nil {
+			t.Fatal(err)
+		}
+		plans = append(plans, plan)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("recorded %d tasks, want 1", len(plans))
+	}
+
+	got := plans[0].Task
+	if !reflect.DeepEqual(got.Playbooks, task.Playbooks) {
+		t.Errorf("playbooks = %v, want %v", got.Playbooks, task.Playbooks)
+	}
+	if got.Inventory != task.Inventory {
+		t.Errorf("inventory = %q, want %q", got.Inventory, task.Inventory)
+	}
+	if got.Tags != task.Tags {
+		t.Errorf("tags = %q, want %q", got.Tags, task.Tags)
+	}
+	kubernetes, _ := got.ExtraVars["Kubernetes"].(map[string]interface{})
+	if kubernetes["Version"] != "v1.24.10" {
+		t.Errorf("extravars Kubernetes.Version = %v, want v1.24.10", kubernetes["Version"])
+	}
+
+	command := strings.Join(plans[0].Command, " ")
+	for _, want := range []string{"ansible-playbook", "--inventory " + task.Inventory, "--tags " + task.Tags, "--user " + task.User, task.Playbooks[0]} {
+		if !strings.Contains(command, want) {
+			t.Errorf("command %q does not contain %q", command, want)
+		}
+	}
+}
+
+func TestReplayMatches(t *testing.T) {
+	login := &Task{
+		Name:       "Helm Repo Login",
+		Pattern:    "all",
+		Inventory:  " 127.0.0.1,",
+		Connection: "local",
+		Module:     "command",
+		ModuleArgs: "helm registry login harbor.local",
+	}
+	file := record(t, login, testTask())
+
+	r := replay(t, file)
+	for _, task := range []*Task{login, testTask()} {
+		if err := r.Run(context.Background(), task); err != nil {
+			t.Fatalf("replay %q: %v", task.Name, err)
+		}
+	}
+	if err := r.Done(); err != nil {
+		t.Errorf("Done() = %v, want nil", err)
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	file := record(t, testTask())
+
+	cases := map[string]func(*Task){
+		"playbooks": func(task *Task) { task.Playbooks = []string{"./internal/playbooks/koreon-playbook/reset.yaml"} },
+		"inventory": func(task *Task) { task.Inventory = "./inventory.ini" },
+		"tags":      func(task *Task) { task.Tags = "" },
+		"extra_vars": func(task *Task) {
+			task.ExtraVars["Kubernetes"] = map[string]interface{}{"Version": "v1.25.6", "PodCidr": "10.4.0.0/20"}
+		},
+	}
+	for field, change := range cases {
+		t.Run(field, func(t *testing.T) {
+			task := testTask()
+			change(task)
+
+			err := replay(t, file).Run(context.Background(), task)
+			if err == nil {
+				t.Fatal("replay of a changed task succeeded")
+			}
+			if !strings.Contains(err.Error(), field) {
+				t.Errorf("error %q does not name the field %q", err.Error(), field)
+			}
+		})
+	}
+}
+
+func TestReplayLeftover(t *testing.T) {
+	file := record(t, testTask(), testTask())
+
+	r := replay(t, file)
+	if err := r.Run(context.Background(), testTask()); err != nil {
+		t.Fatal(err)
+	}
+	if r.Remaining() != 1 {
+		t.Errorf("Remaining() = %d, want 1", r.Remaining())
+	}
+	if err := r.Done(); err == nil {
+		t.Error("Done() with a recorded task left succeeded")
+	}
+}
+
+func TestReplayUnexpectedTask(t *testing.T) {
+	file := record(t, testTask())
+
+	r := replay(t, file)
+	if err := r.Run(context.Background(), testTask()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(context.Background(), testTask()); err == nil {
+		t.Error("replay of a task that was not recorded succeeded")
+	}
+}
+
+func TestTaskValidate(t *testing.T) {
+	cases := []struct {
+		name  string
+		task  Task
+		valid bool
+	}{
+		{"playbook", *testTask(), true},
+		{"no playbook", Task{Inventory: "inventory.ini", PrivateKey: "id_rsa", User: "koreon"}, false},
+		{"no private key", Task{Playbooks: []string{"cluster.yaml"}, Inventory: "inventory.ini", User: "koreon"}, false},
+		{"local", Task{Playbooks: []string{"cluster.yaml"}, Inventory: "inventory.ini", Connection: "local"}, true},
+		{"ad-hoc", Task{Pattern: "all", Module: "command", Inventory: " 127.0.0.1,", Connection: "local"}, true},
+		{"ad-hoc without pattern", Task{Module: "command", Inventory: " 127.0.0.1,", Connection: "local"}, false},
+	}
+	for _, c := range cases {
+		if err := c.task.Validate(); (err == nil) != c.valid {
+			t.Errorf("%s: Validate() = %v, valid %v", c.name, err, c.valid)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package runner - Execution engine used by every provisioning command
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// EnvRunner selects the execution engine (ansible, record, replay)
+	EnvRunner = "KOREON_RUNNER"
+	// EnvRunnerFile is the recording file used by the record and replay engines
+	EnvRunnerFile = "KOREON_RUNNER_FILE"
+
+	// EngineAnsible runs the playbooks with ansible-playbook (default)
+	EngineAnsible = "ansible"
+	// EngineRecord writes every task to a recording file without running it
+	EngineRecord = "record"
+	// EngineReplay checks every task against a recording file without running it
+	EngineReplay = "replay"
+
+	// EventPlanned is emitted after the command line of a task has been built
+	EventPlanned EventType = "planned"
+	// EventStarted is emitted just before a task is executed
+	EventStarted EventType = "started"
+	// EventFinished is emitted when a task has been executed successfully
+	EventFinished EventType = "finished"
+	// EventFailed is emitted when a task could not be planned or executed
+	EventFailed EventType = "failed"
+)
+
+// ===== [ Types ] =====
+
+// Task - Everything that is needed to run one provisioning step.
+// With Module the task is an ad-hoc command (ansible) on the hosts of Pattern instead of playbooks.
+type Task struct {
+	Name          string                 `json:"name"`
+	Playbooks     []string               `json:"playbooks"`
+	Pattern       string                 `json:"pattern,omitempty"`
+	Module        string                 `json:"module,omitempty"`
+	ModuleArgs    string                 `json:"module_args,omitempty"`
+	Inventory     string                 `json:"inventory"`
+	Tags          string                 `json:"tags,omitempty"`
+	Verbose       bool                   `json:"verbose,omitempty"`
+	PrivateKey    string                 `json:"private_key"`
+	User          string                 `json:"user"`
+	Connection    string                 `json:"connection,omitempty"`
+	ExtraVars     map[string]interface{} `json:"extra_vars,omitempty"`
+	ExtraVarsFile []string               `json:"extra_vars_file,omitempty"`
+	// StdoutCallback of ansible (e.g. json), Output receives the output instead of the console
+	StdoutCallback string                    `json:"stdout_callback,omitempty"`
+	Output         io.Writer                 `json:"-"`
+	Transformers   []results.TransformerFunc `json:"-"`
+}
+
+// Plan - The command line that a runner would execute for a task
+type Plan struct {
+	Task    *Task    `json:"task"`
+	Command []string `json:"command"`
+}
+
+// EventType - Kind of runner event
+type EventType string
+
+// Event - Notification sent to the registered event handlers
+type Event struct {
+	Type EventType
+	Plan *Plan
+	Err  error
+	Time time.Time
+}
+
+// EventHandler - Callback for runner events
+type EventHandler func(Event)
+
+// Runner - Interface implemented by the execution engines
+type Runner interface {
+	// Plan validates the task and returns the command line without running it
+	Plan(task *Task) (*Plan, error)
+	// Run plans and executes the task
+	Run(ctx context.Context, task *Task) error
+	// OnEvent registers a callback for the runner events
+	OnEvent(handler EventHandler)
+}
+
+// handlers - Event handler registry shared by the engines
+type handlers struct {
+	list []EventHandler
+}
+
+// ===== [ Implementations ] =====
+
+// Validate - Check the required task fields
+func (t *Task) Validate() error {
+	if t.Module != "" {
+		if len(t.Pattern) < 1 {
+			return fmt.Errorf("[ERROR]: %s", "To run an ansible ad-hoc command a host pattern must be specified")
+		}
+	} else if len(t.Playbooks) < 1 {
+		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook playbook file path must be specified")
+	}
+
+	if len(t.Inventory) < 1 {
+		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an inventory must be specified")
+	}
+
+	// local connection runs the playbook in this host without ssh
+	if t.Connection == "local" {
+		return nil
+	}
+
+	if len(t.PrivateKey) < 1 {
+		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an privateKey must be specified")
+	}
+
+	if len(t.User) < 1 {
+		return fmt.Errorf("[ERROR]: %s", "To run ansible-playbook an ssh login user must be specified")
+	}
+
+	return nil
+}
+
+// OnEvent - Register an event handler
+func (h *handlers) OnEvent(handler EventHandler) {
+	if handler != nil {
+		h.list = append(h.list, handler)
+	}
+}
+
+func (h *handlers) emit(eventType EventType, plan *Plan, err error) {
+	event := Event{
+		Type: eventType,
+		Plan: plan,
+		Err:  err,
+		Time: time.Now(),
+	}
+	for _, handler := range h.list {
+		handler(event)
+	}
+}
+
+// ===== [ Public Functions ] =====
+
+// New - Create the runner for the engine name
+func New(engine string, file string) (Runner, error) {
+	switch engine {
+	case "", EngineAnsible:
+		return NewAnsibleRunner(), nil
+	case EngineRecord:
+		if file == "" {
+			return nil, fmt.Errorf("%s engine requires %s", engine, EnvRunnerFile)
+		}
+		return NewRecordRunner(file), nil
+	case EngineReplay:
+		if file == "" {
+			return nil, fmt.Errorf("%s engine requires %s", engine, EnvRunnerFile)
+		}
+		return NewReplayRunner(file)
+	default:
+		return nil, fmt.Errorf("unknown runner engine %q (ansible, record, replay)", engine)
+	}
+}
+
+// Default - Create the runner selected by the KOREON_RUNNER environment variable
+func Default() (Runner, error) {
+	return New(os.Getenv(EnvRunner), os.Getenv(EnvRunnerFile))
+}
@@ -0,0 +1,148 @@
+// Package storage - Shared storage backends of [shared-storage] storage-type
+package storage
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"kore-on/pkg/model"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// TypeNFS installs an NFS server on storage-ip (default)
+	TypeNFS = "nfs"
+	// TypeExternalNFS uses an existing NFS export (storage-ip:volume-dir)
+	TypeExternalNFS = "external-nfs"
+	// TypeLocalPath provisions volumes in volume-dir of every node
+	TypeLocalPath = "local-path"
+	// TypeLonghorn replicates volumes over the disks of the nodes (volume-dir)
+	TypeLonghorn = "longhorn"
+)
+
+// ===== [ Types ] =====
+
+// Backend - Shared storage backend. Install and destroy are the phases of the ansible role
+// storage/backend/<type> (install.yaml, destroy.yaml), each creates the default StorageClass.
+type Backend interface {
+	// Server - An NFS server is installed on storage-ip (node-storage of the inventory)
+	Server() bool
+	// Validate - [shared-storage] of the backend. The defaults of the backend are set.
+	Validate(k *model.KoreOnToml) []error
+}
+
+type nfsServer struct{}
+
+type externalNFS struct{}
+
+type localPath struct{}
+
+type longhorn struct{}
+
+// ===== [ Implements ] =====
+
+func (nfsServer) Server() bool { return true }
+
+func (nfsServer) Validate(k *model.KoreOnToml) []error {
+	errs := []error{}
+	s := &k.SharedStorage
+	if s.StorageIP == "" {
+		errs = append(errs, fmt.Errorf("storage-ip is required to install the nfs server"))
+	}
+	return append(errs, checkExports(s, "/data/storage", true)...)
+}
+
+func (externalNFS) Server() bool { return false }
+
+func (externalNFS) Validate(k *model.KoreOnToml) []error {
+	errs := []error{}
+	s := &k.SharedStorage
+	if s.StorageIP == "" {
+		errs = append(errs, fmt.Errorf("storage-ip of the nfs server is required"))
+	} else if net.ParseIP(s.StorageIP) == nil && strings.ContainsAny(s.StorageIP, "/: ") {
+		errs = append(errs, fmt.Errorf("storage-ip %q is not an ip address or a host name", s.StorageIP))
+	}
+	return append(errs, checkExports(s, "", false)...)
+}
+
+func (localPath) Server() bool { return false }
+
+func (localPath) Validate(k *model.KoreOnToml) []error {
+	s := &k.SharedStorage
+	errs := checkNoServer(s)
+	if s.VolumeDir == "" {
+		s.VolumeDir = "/opt/local-path-provisioner"
+	}
+	return append(errs, checkAbsPath("volume-dir", s.VolumeDir)...)
+}
+
+func (longhorn) Server() bool { return false }
+
+func (longhorn) Validate(k *model.KoreOnToml) []error {
+	s := &k.SharedStorage
+	errs := checkNoServer(s)
+	if s.VolumeDir == "" {
+		s.VolumeDir = "/var/lib/longhorn"
+	}
+	errs = append(errs, checkAbsPath("volume-dir", s.VolumeDir)...)
+
+	if k.SupportVersion.HelmChartVersion.Longhorn == "" {
+		errs = append(errs, fmt.Errorf("longhorn is not supported on kubernetes %s", k.Kubernetes.Version))
+	}
+
+	// volumes are scheduled on the worker nodes and on the masters that are not isolated
+	nodes := len(k.NodePool.Node.IP)
+	if !k.NodePool.Master.Isolated {
+		nodes += len(k.NodePool.Master.IP)
+	}
+	if s.ReplicaCount == 0 {
+		s.ReplicaCount = 3
+		if nodes < s.ReplicaCount {
+			s.ReplicaCount = nodes
+		}
+	}
+	if s.ReplicaCount < 1 || s.ReplicaCount > nodes {
+		errs = append(errs, fmt.Errorf("replica-count %d must be between 1 and the number of storage nodes (%d)", s.ReplicaCount, nodes))
+	}
+	return errs
+}
+
+// ===== [ Private Functions ] =====
+
+func checkAbsPath(name string, path string) []error {
+	if path != "" && !strings.HasPrefix(path, "/") {
+		return []error{fmt.Errorf("%s %q must be an absolute path", name, path)}
+	}
+	return nil
+}
+
+// checkNoServer - Backends on the cluster nodes do not use the storage node
+func checkNoServer(s *model.SharedStorage) []error {
+	errs := []error{}
+	if s.StorageIP != "" || s.PrivateIP != "" {
+		errs = append(errs, fmt.Errorf("storage-ip and private-ip are not used by %s, the volumes are on the cluster nodes", s.StorageType))
+	}
+	if len(s.Exports) > 0 {
+		errs = append(errs, fmt.Errorf("exports are used by %s and %s, not by %s", TypeNFS, TypeExternalNFS, s.StorageType))
+	}
+	return errs
+}
+
+// ===== [ Public Functions ] =====
+
+// Get - Backend of the storage type ("" is nfs)
+func Get(storageType string) (Backend, error) {
+	switch storageType {
+	case "", TypeNFS:
+		return nfsServer{}, nil
+	case TypeExternalNFS:
+		return externalNFS{}, nil
+	case TypeLocalPath:
+		return localPath{}, nil
+	case TypeLonghorn:
+		return longhorn{}, nil
+	}
+	return nil, fmt.Errorf("storage-type %q is not supported (%s, %s, %s, %s)", storageType, TypeNFS, TypeExternalNFS, TypeLocalPath, TypeLonghorn)
+}
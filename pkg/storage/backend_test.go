@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"kore-on/pkg/model"
+)
+
+func testToml(storageType string) *model.KoreOnToml {
+	k := &model.KoreOnToml{}
+	k.Kubernetes.Version = "v1.24.10"
+	k.NodePool.Master.IP = []string{"192.168.77.11"}
+	k.NodePool.Node.IP = []string{"192.168.77.21", "192.168.77.22"}
+	k.SupportVersion.HelmChartVersion.Longhorn = "1.4.1"
+	k.SharedStorage.StorageType = storageType
+	return k
+}
+
+// validate - Errors of the backend as strings
+func validate(t *testing.T, k *model.KoreOnToml) []string {
+	t.Helper()
+	backend, err := Get(k.SharedStorage.StorageType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := []string{}
+	for _, err := range backend.Validate(k) {
+		errs = append(errs, err.Error())
+	}
+	return errs
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name        string
+		storageType string
+		change      func(s *model.SharedStorage)
+		err         string
+	}{
+		{name: "nfs", storageType: TypeNFS, change: func(s *model.SharedStorage) { s.StorageIP = "192.168.77.31" }},
+		{name: "nfs without storage-ip", storageType: "", err: "storage-ip is required to install the nfs server"},
+		{
+			name:        "external-nfs",
+			storageType: TypeExternalNFS,
+			change: func(s *model.SharedStorage) {
+				s.StorageIP = "nfs.example.com"
+				s.VolumeDir = "/export/k8s"
+			},
+		},
+		{
+			name:        "external-nfs storage-ip",
+			storageType: TypeExternalNFS,
+			change: func(s *model.SharedStorage) {
+				s.StorageIP = "nfs.example.com:/export"
+				s.VolumeDir = "/export/k8s"
+			},
+			err: `storage-ip "nfs.example.com:/export" is not an ip address or a host name`,
+		},
+		{
+			name:        "external-nfs without volume-dir",
+			storageType: TypeExternalNFS,
+			change:      func(s *model.SharedStorage) { s.StorageIP = "192.168.77.31" },
+			err:         "volume-dir of the nfs export is required",
+		},
+		{
+			name:        "external-nfs export size",
+			storageType: TypeExternalNFS,
+			change: func(s *model.SharedStorage) {
+				s.StorageIP = "192.168.77.31"
+				s.Exports = []model.StorageExport{{Path: "/export/k8s", Size: 100}}
+			},
+			err: "exports[0]: size and allowed-cidrs are set on the nfs server",
+		},
+		{
+			name:        "exports with volume-dir",
+			storageType: TypeNFS,
+			change: func(s *model.SharedStorage) {
+				s.StorageIP = "192.168.77.31"
+				s.VolumeDir = "/data/storage"
+				s.Exports = []model.StorageExport{{Path: "/data/fast"}}
+			},
+			err: "volume-dir and volume-size can not be used with exports",
+		},
+		{
+			name:        "exports without storage-class",
+			storageType: TypeNFS,
+			change: func(s *model.SharedStorage) {
+				s.StorageIP = "192.168.77.31"
+				s.Exports = []model.StorageExport{{Path: "/data/fast", StorageClass: "fast"}, {Path: "/data/slow"}}
+			},
+			err: "exports[1]: storage-class is required with more than one export",
+		},
+		{
+			name:        "exports with two defaults",
+			storageType: TypeNFS,
+			change: func(s *model.SharedStorage) {
+				s.StorageIP = "192.168.77.31"
+				s.Exports = []model.StorageExport{
+					{Path: "/data/fast", StorageClass: "fast", Default: true},
+					{Path: "/data/slow", StorageClass: "slow", Default: true},
+				}
+			},
+			err: "only one export can be the default StorageClass (exports[0], exports[1])",
+		},
+		{
+			name:        "export reclaim-policy",
+			storageType: TypeNFS,
+			change: func(s *model.SharedStorage) {
+				s.StorageIP = "192.168.77.31"
+				s.Exports = []model.StorageExport{{Path: "/data/fast", ReclaimPolicy: "recycle"}}
+			},
+			err: `exports[0]: reclaim-policy "recycle" is not supported`,
+		},
+		{name: "local-path", storageType: TypeLocalPath},
+		{
+			name:        "local-path storage-ip",
+			storageType: TypeLocalPath,
+			change:      func(s *model.SharedStorage) { s.StorageIP = "192.168.77.31" },
+			err:         "storage-ip and private-ip are not used by local-path",
+		},
+		{
+			name:        "local-path volume-dir",
+			storageType: TypeLocalPath,
+			change:      func(s *model.SharedStorage) { s.VolumeDir = "data/local-path" },
+			err:         `volume-dir "data/local-path" must be an absolute path`,
+		},
+		{name: "longhorn", storageType: TypeLonghorn},
+		{
+			name:        "longhorn replica-count",
+			storageType: TypeLonghorn,
+			change:      func(s *model.SharedStorage) { s.ReplicaCount = 4 },
+			err:         "replica-count 4 must be between 1 and the number of storage nodes (3)",
+		},
+		{
+			name:        "longhorn exports",
+			storageType: TypeLonghorn,
+			change:      func(s *model.SharedStorage) { s.Exports = []model.StorageExport{{Path: "/data/fast"}} },
+			err:         "exports are used by nfs and external-nfs, not by longhorn",
+		},
+	}
+	for _, c := range cases {
+		k := testToml(c.storageType)
+		if c.change != nil {
+			c.change(&k.SharedStorage)
+		}
+
+		errs := validate(t, k)
+		switch {
+		case c.err == "" && len(errs) > 0:
+			t.Errorf("%s: Validate() = %v, want no errors", c.name, errs)
+		case c.err != "" && (len(errs) != 1 || !strings.Contains(errs[0], c.err)):
+			t.Errorf("%s: Validate() = %v, want one error with %q", c.name, errs, c.err)
+		}
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	k := testToml(TypeNFS)
+	k.SharedStorage.StorageIP = "192.168.77.31"
+	k.SharedStorage.VolumeSize = 100
+	validate(t, k)
+	want := []model.StorageExport{{
+		Path:          "/data/storage",
+		Size:          100,
+		StorageClass:  DefaultStorageClass,
+		ReclaimPolicy: "Delete",
+		MountOptions:  defaultMountOptions,
+		Default:       true,
+	}}
+	if !reflect.DeepEqual(k.SharedStorage.Exports, want) {
+		t.Errorf("exports = %+v, want %+v", k.SharedStorage.Exports, want)
+	}
+
+	// the first export is the default StorageClass and volume-dir
+	k = testToml(TypeNFS)
+	k.SharedStorage.StorageIP = "192.168.77.31"
+	k.SharedStorage.Exports = []model.StorageExport{{Path: "/data/fast", StorageClass: "fast"}, {Path: "/data/slow", StorageClass: "slow"}}
+	validate(t, k)
+	if !k.SharedStorage.Exports[0].Default || k.SharedStorage.Exports[1].Default || k.SharedStorage.VolumeDir != "/data/fast" {
+		t.Errorf("exports = %+v, volume-dir = %s, want the default /data/fast", k.SharedStorage.Exports, k.SharedStorage.VolumeDir)
+	}
+
+	// the volumes are not scheduled on the isolated masters
+	k = testToml(TypeLonghorn)
+	k.NodePool.Master.Isolated = true
+	validate(t, k)
+	if k.SharedStorage.ReplicaCount != 2 || k.SharedStorage.VolumeDir != "/var/lib/longhorn" {
+		t.Errorf("replica-count = %d, volume-dir = %s, want 2 and /var/lib/longhorn", k.SharedStorage.ReplicaCount, k.SharedStorage.VolumeDir)
+	}
+
+	k = testToml(TypeLocalPath)
+	validate(t, k)
+	if k.SharedStorage.VolumeDir != "/opt/local-path-provisioner" {
+		t.Errorf("volume-dir = %s, want /opt/local-path-provisioner", k.SharedStorage.VolumeDir)
+	}
+}
+
+func TestGet(t *testing.T) {
+	cases := []struct {
+		storageType string
+		server      bool
+		err         bool
+	}{
+		{"", true, false},
+		{TypeNFS, true, false},
+		{TypeExternalNFS, false, false},
+		{TypeLocalPath, false, false},
+		{TypeLonghorn, false, false},
+		{"ceph", false, true},
+	}
+	for _, c := range cases {
+		backend, err := Get(c.storageType)
+		switch {
+		case c.err && err == nil:
+			t.Errorf("Get(%q) = %T, want an error", c.storageType, backend)
+		case !c.err && err != nil:
+			t.Errorf("Get(%q) = %v", c.storageType, err)
+		case !c.err && backend.Server() != c.server:
+			t.Errorf("Get(%q).Server() = %v, want %v", c.storageType, backend.Server(), c.server)
+		}
+	}
+}
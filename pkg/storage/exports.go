@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"kore-on/pkg/model"
+)
+
+// ===== [ Constants and Variables ] =====
+
+const (
+	// DefaultStorageClass - StorageClass of volume-dir (storage_class_name of expert.yaml)
+	DefaultStorageClass = "default-storage"
+)
+
+var (
+	defaultMountOptions = []string{"nfsvers=4.1"}
+	storageClassRegex   = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// ===== [ Private Functions ] =====
+
+// checkExports - [[shared-storage.exports]] of the nfs backends. Without exports, volume-dir and
+// volume-size are the one export of the default StorageClass. The defaults of the exports are set
+// and volume-dir is the path of the default export.
+func checkExports(s *model.SharedStorage, defaultDir string, server bool) []error {
+	errs := []error{}
+	if len(s.Exports) == 0 {
+		if s.VolumeDir == "" {
+			s.VolumeDir = defaultDir
+		}
+		if s.VolumeDir == "" {
+			return []error{fmt.Errorf("volume-dir of the nfs export is required")}
+		}
+		s.Exports = []model.StorageExport{{Path: s.VolumeDir, Size: s.VolumeSize, Default: true}}
+	} else if s.VolumeDir != "" || s.VolumeSize != 0 {
+		errs = append(errs, fmt.Errorf("volume-dir and volume-size can not be used with exports, set them in the exports"))
+	}
+
+	paths := map[string]bool{}
+	classes := map[string]bool{}
+	defaults := []string{}
+	for i := range s.Exports {
+		e := &s.Exports[i]
+		name := fmt.Sprintf("exports[%d]", i)
+
+		switch {
+		case e.Path == "":
+			errs = append(errs, fmt.Errorf("%s: path is required", name))
+		case !strings.HasPrefix(e.Path, "/") || e.Path == "/":
+			errs = append(errs, fmt.Errorf("%s: path %q must be an absolute path other than /", name, e.Path))
+		case paths[e.Path]:
+			errs = append(errs, fmt.Errorf("%s: path %q is exported twice", name, e.Path))
+		}
+		paths[e.Path] = true
+
+		if e.Size < 0 {
+			errs = append(errs, fmt.Errorf("%s: size %d must not be negative", name, e.Size))
+		}
+		for _, cidr := range e.AllowedCIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil && cidr != "*" && net.ParseIP(cidr) == nil {
+				errs = append(errs, fmt.Errorf("%s: allowed-cidrs %q is not a cidr or an ip address", name, cidr))
+			}
+		}
+		if !server && (e.Size != 0 || len(e.AllowedCIDRs) > 0) {
+			errs = append(errs, fmt.Errorf("%s: size and allowed-cidrs are set on the nfs server, they are not used by %s", name, s.StorageType))
+		}
+
+		if e.StorageClass == "" && len(s.Exports) == 1 {
+			e.StorageClass = DefaultStorageClass
+		}
+		switch {
+		case e.StorageClass == "":
+			errs = append(errs, fmt.Errorf("%s: storage-class is required with more than one export", name))
+		case len(e.StorageClass) > 253 || !storageClassRegex.MatchString(e.StorageClass):
+			errs = append(errs, fmt.Errorf("%s: storage-class %q is not a valid name (lower case alphanumeric, '-' or '.')", name, e.StorageClass))
+		case classes[e.StorageClass]:
+			errs = append(errs, fmt.Errorf("%s: storage-class %q is used twice", name, e.StorageClass))
+		}
+		classes[e.StorageClass] = true
+
+		switch strings.ToLower(e.ReclaimPolicy) {
+		case "", "delete":
+			e.ReclaimPolicy = "Delete"
+		case "retain":
+			e.ReclaimPolicy = "Retain"
+		default:
+			errs = append(errs, fmt.Errorf("%s: reclaim-policy %q is not supported (delete, retain)", name, e.ReclaimPolicy))
+		}
+
+		if len(e.MountOptions) == 0 {
+			e.MountOptions = defaultMountOptions
+		}
+		if e.Default {
+			defaults = append(defaults, name)
+		}
+	}
+
+	// exactly one default StorageClass, the first export when none is set
+	switch len(defaults) {
+	case 0:
+		s.Exports[0].Default = true
+	case 1:
+	default:
+		errs = append(errs, fmt.Errorf("only one export can be the default StorageClass (%s)", strings.Join(defaults, ", ")))
+	}
+	for _, e := range s.Exports {
+		if e.Default {
+			s.VolumeDir = e.Path
+			break
+		}
+	}
+	return errs
+}
@@ -5,12 +5,23 @@ import (
 	"fmt"
 	"io/ioutil"
 	"kore-on/cmd/koreonctl/conf"
+	"kore-on/pkg/cni"
+	"kore-on/pkg/component"
+	"kore-on/pkg/cri"
+	"kore-on/pkg/harbor"
 	"kore-on/pkg/logger"
+	"kore-on/pkg/mirror"
 	"kore-on/pkg/model"
-	"os"
+	"kore-on/pkg/network"
+	"kore-on/pkg/oidc"
+	"kore-on/pkg/storage"
+	"net/url"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/pelletier/go-toml"
 )
@@ -25,14 +36,12 @@ func GetKoreonTomlConfig(koreOnConfigFilePath string) (model.KoreOnToml, error)
 	var err error
 
 	if !FileExists(koreOnConfigFilePath) {
-		logger.Fatal(fmt.Sprintf("%s file is not found. Run koreonctl init first", koreOnConfigFilePath))
-		os.Exit(1)
+		return model.KoreOnToml{}, fmt.Errorf("%s file is not found. Run koreonctl init first", koreOnConfigFilePath)
 	}
 
 	c, err = ioutil.ReadFile(koreOnConfigFilePath)
 	if err != nil {
-		logger.Fatal(err.Error())
-		os.Exit(1)
+		return model.KoreOnToml{}, err
 	}
 
 	str := string(c)
@@ -50,11 +59,10 @@ func GetKoreonTomlConfig(koreOnConfigFilePath string) (model.KoreOnToml, error)
 
 	err = toml.Unmarshal(c, &koreonToml)
 	if err != nil {
-		logger.Fatal(err.Error())
-		errorCnt++
+		return koreonToml, fmt.Errorf("%s: %w", koreOnConfigFilePath, err)
 	}
 
-	return koreonToml, err
+	return koreonToml, nil
 }
 
 func GetAddonTomlConfig(path string) (model.AddonToml, error) {
@@ -66,14 +74,12 @@ func GetAddonTomlConfig(path string) (model.AddonToml, error) {
 	var err error
 
 	if !FileExists(path) {
-		logger.Fatal(fmt.Sprintf("%s file is not found. Run koreonctl addon init first", path))
-		os.Exit(1)
+		return model.AddonToml{}, fmt.Errorf("%s file is not found. Run koreonctl addon init first", path)
 	}
 
 	c, err = ioutil.ReadFile(path)
 	if err != nil {
-		logger.Fatal(err.Error())
-		os.Exit(1)
+		return model.AddonToml{}, err
 	}
 
 	str := string(c)
@@ -83,17 +89,100 @@ func GetAddonTomlConfig(path string) (model.AddonToml, error) {
 	var addonToml = model.AddonToml{}
 	err = toml.Unmarshal(c, &addonToml)
 	if err != nil {
-		logger.Fatal(err.Error())
-		errorCnt++
+		return addonToml, fmt.Errorf("%s: %w", path, err)
 	}
 
-	return addonToml, err
+	return addonToml, nil
 }
 
-func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.KoreOnToml, bool) {
+// AddonAppList - Names of the apps to install in [apps.<name>] in the deployment order.
+// An app comes after the apps of its depends_on, otherwise in name order.
+// The chart reference is required and depends_on must name another app to install.
+func AddonAppList(apps map[string]model.AddonApp) ([]string, error) {
+	names := []string{}
+	for name, app := range apps {
+		if !app.Install {
+			continue
+		}
+		if app.ChartRef == "" {
+			return nil, fmt.Errorf("apps.%s: chart_ref is required", name)
+		}
+		if app.ChartName == "" && !strings.HasSuffix(app.ChartRef, ".tgz") {
+			return nil, fmt.Errorf("apps.%s: chart_name is required for the chart repository %s", name, app.ChartRef)
+		}
+		if _, err := AddonWaitTimeout(app.Wait); err != nil {
+			return nil, fmt.Errorf("apps.%s: %w", name, err)
+		}
+		for _, dep := range app.DependsOn {
+			if dep == name {
+				return nil, fmt.Errorf("apps.%s: depends on itself", name)
+			}
+			if d, ok := apps[dep]; !ok || !d.Install {
+				return nil, fmt.Errorf("apps.%s: depends_on %q is not an app to install", name, dep)
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// topological sort
+	ordered := []string{}
+	placed := map[string]bool{}
+	for len(ordered) < len(names) {
+		next := ""
+		for _, name := range names {
+			if placed[name] {
+				continue
+			}
+			ready := true
+			for _, dep := range apps[name].DependsOn {
+				if !placed[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = name
+				break
+			}
+		}
+		if next == "" {
+			cycle := []string{}
+			for _, name := range names {
+				if !placed[name] {
+					cycle = append(cycle, name)
+				}
+			}
+			return nil, fmt.Errorf("apps: depends_on has a cycle in %s", strings.Join(cycle, ", "))
+		}
+		placed[next] = true
+		ordered = append(ordered, next)
+	}
+
+	return ordered, nil
+}
+
+// AddonWaitTimeout - Timeout of [apps.<name>.wait] (default: 5m)
+func AddonWaitTimeout(wait model.AddonAppWait) (time.Duration, error) {
+	if wait.Timeout == "" {
+		return 5 * time.Minute, nil
+	}
+	d, err := time.ParseDuration(wait.Timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("wait.timeout %q is not a duration (e.g. 300s, 5m)", wait.Timeout)
+	}
+	return d, nil
+}
+
+// ValidateKoreonTomlConfig - koreon.toml of the command with the defaults and the supported versions.
+// Every configuration error is logged and the command gets one error to return, it does not exit.
+func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.KoreOnToml, error) {
 	var koreon_toml model.KoreOnToml
+	koreonToml, err := GetKoreonTomlConfig(koreOnConfigFilePath)
+	if err != nil {
+		return koreonToml, err
+	}
 	errorCnt = 0
-	koreonToml, _ := GetKoreonTomlConfig(koreOnConfigFilePath)
 	subDir := conf.KoreOnArchiveFileDir
 
 	confK8sVersion := "SupportK8sVersion"
@@ -111,16 +200,35 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 		koreonToml.NodePool.SSHPort = 22
 	}
 
+	// network plugin (calico when not set)
+	if koreonToml.Kubernetes.Cni == "" {
+		koreonToml.Kubernetes.Cni = cni.TypeCalico
+	}
+
+	// storage backend of the inventory (node-storage only for the nfs server)
+	if koreonToml.SharedStorage.StorageType == "" {
+		koreonToml.SharedStorage.StorageType = storage.TypeNFS
+	}
+	if backend, err := storage.Get(koreonToml.SharedStorage.StorageType); err == nil {
+		koreonToml.SharedStorage.Server = koreonToml.SharedStorage.Install && backend.Server()
+	}
+
 	if cmd == "prepare-airgap" {
 		k8sVersion := koreonToml.PrepareAirgap.K8sVersion
 		registryIP := koreonToml.PrepareAirgap.RegistryIP
 		registryVersion := koreonToml.PrepareAirgap.RegistryVersion
 		koreon_toml.KoreOn.HelmCubeRepoUrl = conf.HelmCubeRepoUrl
 
-		supportK8sVersion := IsSupportVersion(k8sVersion, confK8sVersion)
-		supportHarborVersion := IsSupportVersion(registryVersion, confHarborVersion)
+		supportK8sVersion, err := IsSupportVersion(k8sVersion, confK8sVersion)
+		if err != nil {
+			return koreonToml, err
+		}
+		supportHarborVersion, err := IsSupportVersion(registryVersion, confHarborVersion)
+		if err != nil {
+			return koreonToml, err
+		}
 		if registryIP == "" {
-			logger.Fatal(fmt.Sprintln("Prepare Air Gap > Registry IP Address is required."))
+			logger.Error("Prepare Air Gap > Registry IP Address is required.")
 			errorCnt++
 		} else {
 			koreon_toml.PrepareAirgap.RegistryIP = registryIP
@@ -143,48 +251,43 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 		// Get image support version
 		supportK8sList := GetSupportVersion(supportK8sVersion, "k8s_support_image")
 		if supportK8sList == nil {
-			logger.Fatal("Prepare Air Gap > Support package and container image not found.:\n")
-			errorCnt++
+			return koreonToml, fmt.Errorf("support package and container image of kubernetes %s not found", supportK8sVersion)
 		}
 		// Get package support version
 		supportPackageList := GetSupportVersion(supportK8sVersion, "k8s_support_package")
 		if supportPackageList == nil {
-			logger.Fatal("Prepare Air Gap > Support package and container image not found.:\n")
-			errorCnt++
+			return koreonToml, fmt.Errorf("support package and container image of kubernetes %s not found", supportK8sVersion)
 		}
 		// Get helm chart package support version
 		supportHelmChartList := GetSupportVersion(supportK8sVersion, "helm_chart_package")
-		if supportPackageList == nil {
-			logger.Fatal("Prepare Air Gap > Support helm chart package version not found.:\n")
-			errorCnt++
+		if supportHelmChartList == nil {
+			return koreonToml, fmt.Errorf("support helm chart package version of kubernetes %s not found", supportK8sVersion)
 		}
 
 		// Set image support version
 		k8sSupportImagesVersion, err := setField(&koreon_toml.SupportVersion.ImageVersion, supportK8sList)
 		if err != nil {
-			logger.Fatal(err)
-			errorCnt++
+			return koreonToml, err
 		} else if err := json.Unmarshal(k8sSupportImagesVersion, &koreon_toml.ListVersion); err != nil {
-			logger.Fatal(err)
+			return koreonToml, err
 		}
 
 		// Set package support version
 		packageSupportVersion, err := setField(&koreon_toml.SupportVersion.PackageVersion, supportPackageList)
 		if err != nil {
-			logger.Fatal(err)
-			errorCnt++
+			return koreonToml, err
 		} else if err := json.Unmarshal(packageSupportVersion, &koreon_toml.ListVersion); err != nil {
-			logger.Fatal(err)
+			return koreonToml, err
 		}
 
 		// Set package support version
 		helmChartSupportVersion, err := setField(&koreon_toml.SupportVersion.HelmChartVersion, supportHelmChartList)
 		if err != nil {
-			logger.Fatal(err)
-			errorCnt++
+			return koreonToml, err
 		} else if err := json.Unmarshal(helmChartSupportVersion, &koreon_toml.ListVersion); err != nil {
-			logger.Fatal(err)
+			return koreonToml, err
 		}
+		koreon_toml.SupportVersion.ChartImages = mirror.ChartImages(koreon_toml.SupportVersion.HelmChartVersion)
 
 		koreonToml = koreon_toml
 		koreonToml.KoreOn.ArchiveFileDir = subDir
@@ -198,8 +301,6 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 		// koreonToml.SupportVersion.PackageVersion.DockerCompose = IsSupportVersion(fmt.Sprintf("%v", supportHarborList["docker-compose"]), confDockerComposeVersion)
 
 	} else if cmd == "create" {
-		kubernetesPodCidr := koreonToml.Kubernetes.PodCidr
-		kubernetesServiceCidr := koreonToml.Kubernetes.ServiceCidr
 		k8sVersion := koreonToml.Kubernetes.Version
 		etcdCnt := len(koreonToml.Kubernetes.Etcd.IP)
 		masterIP := koreonToml.NodePool.Master.IP
@@ -214,10 +315,13 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 		privateRegistryCrt := koreonToml.PrivateRegistry.CertFile.SslCert
 		privateRegistryKey := koreonToml.PrivateRegistry.CertFile.SslCertKey
 
-		supportK8sVersion := IsSupportVersion(k8sVersion, confK8sVersion)
+		supportK8sVersion, err := IsSupportVersion(k8sVersion, confK8sVersion)
+		if err != nil {
+			return koreonToml, err
+		}
 
 		if koreonToml.KoreOn.InstallDir != "" && !strings.HasPrefix(koreonToml.KoreOn.InstallDir, "/") {
-			logger.Fatal("koreon > install-dir is Only absolute paths are supported.")
+			logger.Error("koreon > install-dir is Only absolute paths are supported.")
 			errorCnt++
 		}
 
@@ -229,34 +333,28 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 		}
 
 		if len(masterIP) < 0 {
-			logger.Fatal("NodePool > K8s Control Plane node is required.")
+			logger.Error("NodePool > K8s Control Plane node is required.")
+			errorCnt++
 		} else {
 			//todo check masterIP
 			if len(workerIP) < 0 {
-				logger.Fatal("NodePool > K8s Worker node is required.")
+				logger.Error("NodePool > K8s Worker node is required.")
+				errorCnt++
 			}
 		}
 
-		if len(kubernetesPodCidr) > 0 {
-			//todo check cider
-		}
-
-		if len(kubernetesServiceCidr) > 0 {
-			//todo check cider
-		}
-
 		if koreonToml.Kubernetes.Etcd.ExternalEtcd {
 			if etcdPrivateIpCnt == 0 {
 				koreonToml.Kubernetes.Etcd.PrivateIP = koreonToml.Kubernetes.Etcd.IP
 			}
 			if etcdCnt != etcdPrivateIpCnt && etcdCnt > 0 && etcdPrivateIpCnt > 0 {
-				logger.Fatal("etcd nodes IP address and private ip address needs")
+				logger.Error("etcd nodes IP address and private ip address needs")
 				errorCnt++
 			}
 			switch etcdCnt {
 			case 1, 3, 5:
 			default:
-				logger.Fatal("Only odd number of etcd nodes are supported.(1, 3, 5)")
+				logger.Error("Only odd number of etcd nodes are supported.(1, 3, 5)")
 				errorCnt++
 			}
 		}
@@ -265,19 +363,24 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 			// todo node pool data dir check
 		}
 
-		//storage check
-		cnt := checkSharedStorage(koreonToml)
-		errorCnt += cnt
+		//external registry check
+		errorCnt += checkExternalRegistry(&koreonToml)
+
+		//registry projects check
+		errorCnt += checkRegistryProjects(koreonToml)
 
 		if privateRegistryInstall {
 
 			if privateRegistryRegistryIP == "" {
-				logger.Fatal("private-registry > registry-ip is required.")
+				logger.Error("private-registry > registry-ip is required.")
 				errorCnt++
 			}
 		}
 
-		supportHarborVersion := IsSupportVersion(privateRegistryRegistryVersion, confHarborVersion)
+		supportHarborVersion, err := IsSupportVersion(privateRegistryRegistryVersion, confHarborVersion)
+		if err != nil {
+			return koreonToml, err
+		}
 		if privateRegistryRegistryVersion == "" {
 			koreonToml.PrivateRegistry.RegistryVersion = supportHarborVersion
 			logger.Warn("Private Registry > Harbor version is required. Last version", koreonToml.PrivateRegistry.RegistryVersion, "applied automatically.")
@@ -287,11 +390,13 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 
 		if isPrivateRegistryPublicCert {
 			if privateRegistryCrt == "" {
-				logger.Fatal("private-registry.cert-file > ca-cert is required.")
+				logger.Error("private-registry.cert-file > ca-cert is required.")
+				errorCnt++
 			}
 
 			if privateRegistryKey == "" {
-				logger.Fatal("private-registry.cert-file > ssl-certificate-key is required.")
+				logger.Error("private-registry.cert-file > ssl-certificate-key is required.")
+				errorCnt++
 			}
 		}
 
@@ -300,16 +405,18 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 				localRepositoryArchiveFile, err := SearchOfDirectory(regexp.MustCompile("local"), subDir)
 
 				if err != nil {
-					logger.Fatal(err)
+					return koreonToml, err
 				}
 
 				koreonToml.KoreOn.LocalRepositoryArchiveFile = localRepositoryArchiveFile
 			} else {
 				if koreonToml.KoreOn.LocalRepositoryUrl == "" {
-					logger.Fatal("koreon> If you are not installing a local repository, the local-repository-url entry is required.")
+					logger.Error("koreon> If you are not installing a local repository, the local-repository-url entry is required.")
+					errorCnt++
 				}
 				if koreonToml.KoreOn.LocalRepositoryArchiveFile != "" {
-					logger.Fatal("koreon> If you are not installing a local repository, the local-repository-archive-file entry should be empty.")
+					logger.Error("koreon> If you are not installing a local repository, the local-repository-archive-file entry should be empty.")
+					errorCnt++
 				}
 			}
 
@@ -317,7 +424,7 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 				registryArchiveFile, err := SearchOfDirectory(regexp.MustCompile("harbor"), subDir)
 
 				if err != nil {
-					logger.Fatal(err)
+					return koreonToml, err
 				}
 
 				koreonToml.PrivateRegistry.RegistryArchiveFile = registryArchiveFile
@@ -327,38 +434,59 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 		// Get image support version
 		supportK8sList := GetSupportVersion(supportK8sVersion, "k8s_support_image")
 		if supportK8sList == nil {
-			logger.Fatal("Prepare Air Gap > Support package and container image not found.:\n")
-			errorCnt++
+			return koreonToml, fmt.Errorf("support package and container image of kubernetes %s not found", supportK8sVersion)
 		}
 		// Get package support version
 		supportPackageList := GetSupportVersion(supportK8sVersion, "k8s_support_package")
 		if supportPackageList == nil {
-			logger.Fatal("Prepare Air Gap > Support package and container image not found.:\n")
-			errorCnt++
+			return koreonToml, fmt.Errorf("support package and container image of kubernetes %s not found", supportK8sVersion)
 		}
 
 		// Set image support version
 		k8sSupportImagesVersion, err := setField(&koreonToml.SupportVersion.ImageVersion, supportK8sList)
 		if err != nil {
-			logger.Fatal(err)
-			errorCnt++
+			return koreonToml, err
 		} else if err := json.Unmarshal(k8sSupportImagesVersion, &koreon_toml.ListVersion); err != nil {
-			logger.Fatal(err)
+			return koreonToml, err
 		}
 
 		// Set package support version
 		packageSupportVersion, err := setField(&koreonToml.SupportVersion.PackageVersion, supportPackageList)
 		if err != nil {
-			logger.Fatal(err)
-			errorCnt++
+			return koreonToml, err
 		} else if err := json.Unmarshal(packageSupportVersion, &koreon_toml.ListVersion); err != nil {
-			logger.Fatal(err)
+			return koreonToml, err
 		}
 
+		// Set helm chart support version (charts of the shared storage)
+		if supportHelmChartList := GetSupportVersion(supportK8sVersion, "helm_chart_package"); supportHelmChartList != nil {
+			if _, err := setField(&koreonToml.SupportVersion.HelmChartVersion, supportHelmChartList); err != nil {
+				return koreonToml, err
+			}
+			koreonToml.SupportVersion.ChartImages = mirror.ChartImages(koreonToml.SupportVersion.HelmChartVersion)
+		}
+
+		//storage check
+		errorCnt += checkSharedStorage(&koreonToml)
+
+		//cni check
+		errorCnt += checkNetwork(&koreonToml)
+		errorCnt += checkCni(&koreonToml)
+
+		//container runtime check
+		errorCnt += checkCri(&koreonToml)
+
+		//kubernetes components check
+		errorCnt += checkComponents(&koreonToml)
+
+		//oidc check
+		errorCnt += checkOidc(&koreonToml)
+
 		koreonToml.PrepareAirgap = koreon_toml.PrepareAirgap
 	} else if cmd == "cluster-update" {
-		kubernetesPodCidr := koreonToml.Kubernetes.PodCidr
-		kubernetesServiceCidr := koreonToml.Kubernetes.ServiceCidr
+		//external registry check
+		errorCnt += checkExternalRegistry(&koreonToml)
+
 		k8sVersion := koreonToml.Kubernetes.Version
 		workerIP := koreonToml.NodePool.Node.IP
 		nodePoolDataDir := koreonToml.NodePool.DataDir
@@ -371,7 +499,10 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 		// privateRegistryCrt := koreonToml.PrivateRegistry.CertFile.CaCert
 		// privateRegistryKey := koreonToml.PrivateRegistry.CertFile.CaCertKey
 
-		supportK8sVersion := IsSupportVersion(k8sVersion, confK8sVersion)
+		supportK8sVersion, err := IsSupportVersion(k8sVersion, confK8sVersion)
+		if err != nil {
+			return koreonToml, err
+		}
 
 		// if koreonToml.KoreOn.InstallDir != "" && !strings.HasPrefix(koreonToml.KoreOn.InstallDir, "/") {
 		// 	logger.Fatal("koreon > install-dir is Only absolute paths are supported.")
@@ -386,15 +517,8 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 		}
 
 		if len(workerIP) < 0 {
-			logger.Fatal("NodePool > K8s Worker node is required.")
-		}
-
-		if len(kubernetesPodCidr) > 0 {
-			//todo check cider
-		}
-
-		if len(kubernetesServiceCidr) > 0 {
-			//todo check cider
+			logger.Error("NodePool > K8s Worker node is required.")
+			errorCnt++
 		}
 
 		if len(nodePoolDataDir) > 0 {
@@ -406,16 +530,18 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 				localRepositoryArchiveFile, err := SearchOfDirectory(regexp.MustCompile("local"), subDir)
 
 				if err != nil {
-					logger.Fatal(err)
+					return koreonToml, err
 				}
 
 				koreonToml.KoreOn.LocalRepositoryArchiveFile = localRepositoryArchiveFile
 			} else {
 				if koreonToml.KoreOn.LocalRepositoryUrl == "" {
-					logger.Fatal("koreon> If you are not installing a local repository, the local-repository-url entry is required.")
+					logger.Error("koreon> If you are not installing a local repository, the local-repository-url entry is required.")
+					errorCnt++
 				}
 				if koreonToml.KoreOn.LocalRepositoryArchiveFile != "" {
-					logger.Fatal("koreon> If you are not installing a local repository, the local-repository-archive-file entry should be empty.")
+					logger.Error("koreon> If you are not installing a local repository, the local-repository-archive-file entry should be empty.")
+					errorCnt++
 				}
 			}
 		}
@@ -423,40 +549,77 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 		// Get image support version
 		supportK8sList := GetSupportVersion(supportK8sVersion, "k8s_support_image")
 		if supportK8sList == nil {
-			logger.Fatal("Prepare Air Gap > Support package and container image not found.:\n")
-			errorCnt++
+			return koreonToml, fmt.Errorf("support package and container image of kubernetes %s not found", supportK8sVersion)
 		}
 		// Get package support version
 		supportPackageList := GetSupportVersion(supportK8sVersion, "k8s_support_package")
 		if supportPackageList == nil {
-			logger.Fatal("Prepare Air Gap > Support package and container image not found.:\n")
-			errorCnt++
+			return koreonToml, fmt.Errorf("support package and container image of kubernetes %s not found", supportK8sVersion)
 		}
 
 		// Set image support version
 		k8sSupportImagesVersion, err := setField(&koreonToml.SupportVersion.ImageVersion, supportK8sList)
 		if err != nil {
-			logger.Fatal(err)
-			errorCnt++
+			return koreonToml, err
 		} else if err := json.Unmarshal(k8sSupportImagesVersion, &koreon_toml.ListVersion); err != nil {
-			logger.Fatal(err)
+			return koreonToml, err
 		}
 
 		// Set package support version
 		packageSupportVersion, err := setField(&koreonToml.SupportVersion.PackageVersion, supportPackageList)
 		if err != nil {
-			logger.Fatal(err)
-			errorCnt++
+			return koreonToml, err
 		} else if err := json.Unmarshal(packageSupportVersion, &koreon_toml.ListVersion); err != nil {
-			logger.Fatal(err)
+			return koreonToml, err
 		}
 
+		//cni check
+		errorCnt += checkNetwork(&koreonToml)
+		errorCnt += checkCni(&koreonToml)
+
+		//container runtime check
+		errorCnt += checkCri(&koreonToml)
+
+		//kubernetes components check
+		errorCnt += checkComponents(&koreonToml)
+
+		//oidc check
+		errorCnt += checkOidc(&koreonToml)
+
 		koreonToml.PrepareAirgap = koreon_toml.PrepareAirgap
+	} else if cmd == "registry-manager" {
+		errorCnt += checkExternalRegistry(&koreonToml)
+		if koreonToml.PrivateRegistry.RegistryIP == "" && koreonToml.PrivateRegistry.RegistryDomain == "" {
+			logger.Error("private-registry > registry-ip is required.")
+			errorCnt++
+		}
+		if len(koreonToml.PrivateRegistry.Projects) == 0 {
+			logger.Error("private-registry.projects > At least one project is required.")
+			errorCnt++
+		}
+		errorCnt += checkRegistryProjects(koreonToml)
+	} else if cmd == "backup-registry" || cmd == "restore-registry" || cmd == "upgrade-registry" {
+		if koreonToml.PrivateRegistry.External.URL != "" {
+			logger.Error("private-registry.external > The external registry is not managed by kore-on.")
+			errorCnt++
+		}
+		if !koreonToml.PrivateRegistry.Install {
+			logger.Error("private-registry > install must be true. Only the registry installed by kore-on can be backed up, restored and upgraded.")
+			errorCnt++
+		}
+		if koreonToml.PrivateRegistry.RegistryIP == "" {
+			logger.Error("private-registry > registry-ip is required.")
+			errorCnt++
+		}
+		if koreonToml.PrivateRegistry.RegistryVersion, err = IsSupportVersion(koreonToml.PrivateRegistry.RegistryVersion, confHarborVersion); err != nil {
+			return koreonToml, err
+		}
 	} else if cmd == "reset-prepare-airgap" {
 		registryIP := koreonToml.PrepareAirgap.RegistryIP
 
 		if registryIP == "" {
-			logger.Fatal(fmt.Sprintln("Destroy: Prepare Air Gap > Registry IP Address is required."))
+			logger.Error("Destroy: Prepare Air Gap > Registry IP Address is required.")
+			errorCnt++
 		} else {
 			koreon_toml.PrepareAirgap = koreonToml.PrepareAirgap
 		}
@@ -476,60 +639,261 @@ func ValidateKoreonTomlConfig(koreOnConfigFilePath string, cmd string) (model.Ko
 	} else if cmd == "add-on" {
 		k8sVersion := koreonToml.PrepareAirgap.K8sVersion
 
-		supportK8sVersion := IsSupportVersion(k8sVersion, confK8sVersion)
+		supportK8sVersion, err := IsSupportVersion(k8sVersion, confK8sVersion)
+		if err != nil {
+			return koreonToml, err
+		}
 
 		// Get image support version
 		supportK8sList := GetSupportVersion(supportK8sVersion, "k8s_support_image")
 		if supportK8sList == nil {
-			logger.Fatal("Prepare Air Gap > Support package and container image not found.:\n")
-			errorCnt++
+			return koreonToml, fmt.Errorf("support package and container image of kubernetes %s not found", supportK8sVersion)
 		}
 		// Get package support version
 		supportPackageList := GetSupportVersion(supportK8sVersion, "k8s_support_package")
 		if supportPackageList == nil {
-			logger.Fatal("Prepare Air Gap > Support package and container image not found.:\n")
-			errorCnt++
+			return koreonToml, fmt.Errorf("support package and container image of kubernetes %s not found", supportK8sVersion)
 		}
 
 		// Set image support version
 		k8sSupportImagesVersion, err := setField(&koreonToml.SupportVersion.ImageVersion, supportK8sList)
 		if err != nil {
-			logger.Fatal(k8sSupportImagesVersion)
-			errorCnt++
+			return koreonToml, err
 		} else if err := json.Unmarshal(k8sSupportImagesVersion, &koreon_toml.ListVersion); err != nil {
-			logger.Fatal(err)
+			return koreonToml, err
 		}
 
 		// Set package support version
 		packageSupportVersion, err := setField(&koreonToml.SupportVersion.PackageVersion, supportPackageList)
 		if err != nil {
-			logger.Fatal(packageSupportVersion)
-			errorCnt++
+			return koreonToml, err
 		} else if err := json.Unmarshal(packageSupportVersion, &koreon_toml.ListVersion); err != nil {
-			logger.Fatal(err)
+			return koreonToml, err
 		}
 
 	}
 
 	if errorCnt > 0 {
-		logger.Error("there are one or more errors")
-		os.Exit(1)
-		return koreonToml, false
+		return koreonToml, fmt.Errorf("%s: there are one or more errors", filepath.Base(koreOnConfigFilePath))
 	}
-	return koreonToml, true
+	return koreonToml, nil
 }
 
-func checkSharedStorage(koreonToml model.KoreOnToml) int {
-	errorCnt = 0
+// checkNetwork - pod-cidr, service-cidr and the node addresses of each address family (dual-stack)
+func checkNetwork(koreonToml *model.KoreOnToml) int {
+	cnt := 0
+	for _, err := range network.Validate(koreonToml) {
+		logger.Errorf("%s", err.Error())
+		cnt++
+	}
 
-	if koreonToml.SharedStorage.Install {
-		if koreonToml.SharedStorage.StorageIP == "" {
-			logger.Fatal("shared-storage > storage-ip is required.")
-			errorCnt++
+	return cnt
+}
+
+// checkCni - [kubernetes] cni and [kubernetes.<cni>] of the network plugin
+func checkCni(koreonToml *model.KoreOnToml) int {
+	cnt := 0
+	plugin, err := cni.Get(koreonToml.Kubernetes.Cni)
+	if err != nil {
+		logger.Errorf("kubernetes > %s", err.Error())
+		return cnt + 1
+	}
+	for _, err := range plugin.Validate(koreonToml) {
+		logger.Errorf("kubernetes.%s > %s", koreonToml.Kubernetes.Cni, err.Error())
+		cnt++
+	}
+
+	return cnt
+}
+
+// checkCri - [kubernetes] container-runtime and [kubernetes.<runtime>] against the kubernetes version.
+// The cri-o version of the support matrix is replaced by [kubernetes.cri-o] version.
+func checkCri(koreonToml *model.KoreOnToml) int {
+	cnt := 0
+	name := koreonToml.Kubernetes.ContainerRuntime
+	if name == "" {
+		name = cri.TypeContainerd
+	}
+	runtime, err := cri.Get(name)
+	if err != nil {
+		logger.Errorf("kubernetes > %s", err.Error())
+		return cnt + 1
+	}
+
+	if name == cri.TypeCrio && koreonToml.Kubernetes.Crio.Version != "" {
+		crioVersion, err := ResolveVersion(koreonToml.Kubernetes.Crio.Version, "SupportCrioVersion")
+		if err != nil {
+			logger.Errorf("kubernetes.cri-o > version %s", err.Error())
+			return cnt + 1
 		}
+		koreonToml.SupportVersion.PackageVersion.Crio = crioVersion
+	}
+
+	for _, err := range runtime.Validate(koreonToml) {
+		logger.Errorf("kubernetes.%s > %s", name, err.Error())
+		cnt++
+	}
+
+	return cnt
+}
+
+// checkComponents - extra-args, feature-gates and the kubelet fields of the kubernetes components
+func checkComponents(koreonToml *model.KoreOnToml) int {
+	cnt := 0
+	for _, err := range component.Validate(koreonToml) {
+		logger.Errorf("%s", err.Error())
+		cnt++
 	}
 
-	return errorCnt
+	return cnt
+}
+
+// checkOidc - [kubernetes.oidc] of koreon.toml. The issuer and its tokens are checked by
+// 'koreonctl oidc check' (--stand-in without network).
+func checkOidc(koreonToml *model.KoreOnToml) int {
+	cnt := 0
+	for _, err := range oidc.Validate(koreonToml, conf.KoreOnConfigDir) {
+		logger.Errorf("kubernetes.oidc > %s", err.Error())
+		cnt++
+	}
+	return cnt
+}
+
+// checkSharedStorage - [shared-storage] of the storage-type backend
+func checkSharedStorage(koreonToml *model.KoreOnToml) int {
+	cnt := 0
+	if !koreonToml.SharedStorage.Install {
+		return cnt
+	}
+
+	backend, err := storage.Get(koreonToml.SharedStorage.StorageType)
+	if err != nil {
+		logger.Errorf("shared-storage > %s", err.Error())
+		return cnt + 1
+	}
+	for _, err := range backend.Validate(koreonToml) {
+		logger.Errorf("shared-storage > %s: %s", koreonToml.SharedStorage.StorageType, err.Error())
+		cnt++
+	}
+
+	return cnt
+}
+
+// checkExternalRegistry - [private-registry.external] without a network round trip. The registry domain is taken from the url.
+// The connection and the credentials are checked by the provider before the playbook runs.
+func checkExternalRegistry(koreonToml *model.KoreOnToml) int {
+	cnt := 0
+	external := koreonToml.PrivateRegistry.External
+	if external.URL == "" {
+		return cnt
+	}
+
+	if koreonToml.PrivateRegistry.Install {
+		logger.Errorf("private-registry > install must be false when using an external registry.")
+		cnt++
+	}
+	if (external.Username == "") != (external.Password == "") {
+		logger.Errorf("private-registry.external > username and password are required together.")
+		cnt++
+	}
+	if external.CAFile != "" && !FileExists(filepath.Join(conf.KoreOnConfigDir, filepath.Base(external.CAFile))) {
+		logger.Errorf("private-registry.external > ca-file %s is not found. Copy it to the config directory.", filepath.Base(external.CAFile))
+		cnt++
+	}
+
+	endpoint, err := ExternalRegistry(*koreonToml, conf.KoreOnConfigDir)
+	if err != nil {
+		logger.Errorf("private-registry.external > %s", err.Error())
+		return cnt + 1
+	}
+	koreonToml.PrivateRegistry.RegistryDomain = endpoint.Host
+	koreonToml.PrivateRegistry.RegistryIP = ""
+	koreonToml.PrivateRegistry.PrivateIP = ""
+
+	return cnt
+}
+
+// ExternalRegistry - Registry endpoint of [private-registry.external] (host[/path-prefix]).
+// caDir is the directory of the ca-file.
+func ExternalRegistry(koreonToml model.KoreOnToml, caDir string) (*mirror.Endpoint, error) {
+	external := koreonToml.PrivateRegistry.External
+	u, err := url.Parse(external.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("url %q must be https://<host>[:port] or http://<host>[:port]", external.URL)
+	}
+	if strings.Trim(u.Path, "/") != "" {
+		return nil, fmt.Errorf("url %q must not have a path. Use path-prefix", external.URL)
+	}
+
+	options := mirror.RegistryOptions{Username: external.Username, Password: external.Password}
+	if external.CAFile != "" {
+		options.CAFile = filepath.Join(caDir, filepath.Base(external.CAFile))
+	}
+
+	endpoint := u.Scheme + "://" + u.Host
+	if prefix := strings.Trim(external.PathPrefix, "/"); prefix != "" {
+		endpoint += "/" + prefix
+	}
+	return mirror.ParseEndpoint(endpoint, options)
+}
+
+// checkRegistryProjects - [private-registry.projects] used by 'registry-manager'
+func checkRegistryProjects(koreonToml model.KoreOnToml) int {
+	cnt := 0
+	nameRule := regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
+
+	for name, project := range koreonToml.PrivateRegistry.Projects {
+		prefix := "private-registry.projects." + name
+		if !nameRule.MatchString(name) || len(name) > 255 {
+			logger.Errorf("%s > Project name must be lower case letters, digits and '._-'.", prefix)
+			cnt++
+		}
+
+		if proxy := project.ProxyCache; proxy != nil {
+			if !harbor.IsProxyCacheType(proxy.Type) {
+				logger.Errorf("%s.proxy-cache > type must be one of %s.", prefix, strings.Join(harbor.ProxyCacheTypes, ", "))
+				cnt++
+			}
+			if !strings.HasPrefix(proxy.URL, "https://") && !strings.HasPrefix(proxy.URL, "http://") {
+				logger.Errorf("%s.proxy-cache > url is required. (e.g. https://hub.docker.com)", prefix)
+				cnt++
+			}
+			if proxy.Password != "" && proxy.Username == "" {
+				logger.Errorf("%s.proxy-cache > username is required with password.", prefix)
+				cnt++
+			}
+		}
+
+		if retention := project.Retention; retention != nil {
+			if retention.KeepLast <= 0 && retention.KeepDays <= 0 {
+				logger.Errorf("%s.retention > keep-last or keep-days is required.", prefix)
+				cnt++
+			}
+			if retention.Schedule != "" && len(strings.Fields(retention.Schedule)) != 6 {
+				logger.Errorf("%s.retention > schedule is a cron expression with seconds. (e.g. \"0 0 0 * * *\")", prefix)
+				cnt++
+			}
+		}
+
+		for robotName, robot := range project.Robots {
+			if !nameRule.MatchString(robotName) {
+				logger.Errorf("%s.robots.%s > Robot name must be lower case letters, digits and '._-'.", prefix, robotName)
+				cnt++
+			}
+			if len(robot.Permissions) == 0 {
+				logger.Errorf("%s.robots.%s > permissions is required.", prefix, robotName)
+				cnt++
+			}
+			for _, p := range robot.Permissions {
+				if _, ok := harbor.RobotPermissions[p]; !ok {
+					logger.Errorf("%s.robots.%s > Unknown permission %q. (pull, push, delete, chart-pull, chart-push)", prefix, robotName, p)
+					cnt++
+				}
+			}
+		}
+	}
+
+	return cnt
 }
 
 func setField(item interface{}, supportList map[string]interface{}) ([]byte, error) {
@@ -545,11 +909,20 @@ func setField(item interface{}, supportList map[string]interface{}) ([]byte, err
 		typeField := v.Type().Field(i)
 		tag := typeField.Tag.Get("validate")
 		r := strings.Split(tag, ",")
-		if len(r) != 2 {
+		optional := len(r) == 3 && r[2] == "optional"
+		if len(r) != 2 && !optional {
 			return nil, fmt.Errorf("tag entry error in %s field", typeField.Name)
 		}
-		value := IsSupportVersion(fmt.Sprintf("%v", supportList[string(r[0])]), r[1])
-		v.Field(i).SetString(value)
+		// not every k8s version ships the optional components (e.g. clusterctl)
+		if _, ok := supportList[string(r[0])]; ok {
+			value, err := ResolveVersion(fmt.Sprintf("%v", supportList[string(r[0])]), r[1])
+			if err != nil {
+				return nil, err
+			}
+			v.Field(i).SetString(value)
+		} else if !optional {
+			return nil, fmt.Errorf("%s has no version in the support list (%s)", r[0], r[1])
+		}
 
 		// list Versions
 		versions[typeField.Name] = ListSupportVersion(r[1])
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"kore-on/pkg/model"
+	"kore-on/pkg/version"
+)
+
+func testApp(dependsOn ...string) model.AddonApp {
+	return model.AddonApp{Install: true, ChartRef: "https://charts.example.com", ChartName: "app", DependsOn: dependsOn}
+}
+
+func TestAddonAppList(t *testing.T) {
+	cases := []struct {
+		name  string
+		apps  map[string]model.AddonApp
+		order []string
+		err   string
+	}{
+		{
+			name:  "name order",
+			apps:  map[string]model.AddonApp{"metallb": testApp(), "cert-manager": testApp(), "ingress-nginx": testApp()},
+			order: []string{"cert-manager", "ingress-nginx", "metallb"},
+		},
+		{
+			name: "depends_on",
+			apps: map[string]model.AddonApp{
+				"ingress-nginx":         testApp("metallb"),
+				"metallb":               testApp(),
+				"kube-prometheus-stack": testApp("ingress-nginx", "cert-manager"),
+				"cert-manager":          testApp(),
+			},
+			order: []string{"cert-manager", "metallb", "ingress-nginx", "kube-prometheus-stack"},
+		},
+		{
+			name:  "chain",
+			apps:  map[string]model.AddonApp{"a": testApp("b"), "b": testApp("c"), "c": testApp()},
+			order: []string{"c", "b", "a"},
+		},
+		{
+			name: "not installed",
+			apps: map[string]model.AddonApp{
+				"metallb":       testApp(),
+				"ingress-nginx": testApp(),
+				"koreboard":     {ChartRef: "https://charts.example.com"},
+			},
+			order: []string{"ingress-nginx", "metallb"},
+		},
+		{
+			name: "depends on an app not installed",
+			apps: map[string]model.AddonApp{"ingress-nginx": testApp("metallb"), "metallb": {ChartRef: "https://charts.example.com"}},
+			err:  `apps.ingress-nginx: depends_on "metallb" is not an app to install`,
+		},
+		{
+			name: "depends on an unknown app",
+			apps: map[string]model.AddonApp{"ingress-nginx": testApp("metalb")},
+			err:  `apps.ingress-nginx: depends_on "metalb" is not an app to install`,
+		},
+		{
+			name: "depends on itself",
+			apps: map[string]model.AddonApp{"metallb": testApp("metallb")},
+			err:  "apps.metallb: depends on itself",
+		},
+		{
+			name: "cycle",
+			apps: map[string]model.AddonApp{"a": testApp("c"), "b": testApp("a"), "c": testApp("b"), "d": testApp()},
+			err:  "apps: depends_on has a cycle in a, b, c",
+		},
+		{
+			name: "chart_ref",
+			apps: map[string]model.AddonApp{"metallb": {Install: true}},
+			err:  "apps.metallb: chart_ref is required",
+		},
+		{
+			name: "chart_name of a repository",
+			apps: map[string]model.AddonApp{"metallb": {Install: true, ChartRef: "https://metallb.github.io/metallb"}},
+			err:  "apps.metallb: chart_name is required for the chart repository",
+		},
+		{
+			name:  "chart archive",
+			apps:  map[string]model.AddonApp{"metallb": {Install: true, ChartRef: "charts/metallb-0.13.9.tgz"}},
+			order: []string{"metallb"},
+		},
+		{
+			name: "wait timeout",
+			apps: map[string]model.AddonApp{"metallb": func() model.AddonApp {
+				app := testApp()
+				app.Wait.Timeout = "5"
+				return app
+			}()},
+			err: `apps.metallb: wait.timeout "5" is not a duration`,
+		},
+	}
+	for _, c := range cases {
+		order, err := AddonAppList(c.apps)
+		switch {
+		case c.err != "":
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: AddonAppList() = %v, want an error with %q", c.name, err, c.err)
+			}
+		case err != nil:
+			t.Errorf("%s: AddonAppList() = %v", c.name, err)
+		case !reflect.DeepEqual(order, c.order):
+			t.Errorf("%s: AddonAppList() = %v, want %v", c.name, order, c.order)
+		}
+	}
+}
+
+// ValidateKoreonTomlConfig returns the errors to the command instead of exiting
+func TestValidateKoreonTomlConfig(t *testing.T) {
+	setSupportVersion(t)
+	dir := t.TempDir()
+
+	cases := []struct {
+		name string
+		toml string
+		cmd  string
+		err  string
+	}{
+		{name: "not found", cmd: "create", err: "file is not found. Run koreonctl init first"},
+		{name: "syntax", toml: "[kubernetes\nversion = 1", cmd: "create", err: "syntax.toml"},
+		{name: "unsupported", toml: "[kubernetes]\nversion = \"v1.22\"\n", cmd: "create", err: `koreon > SupportK8sVersion: `},
+		{name: "registry", toml: "[private-registry]\ninstall = true\n", cmd: "registry-manager", err: "registry.toml: there are one or more errors"},
+	}
+	for _, c := range cases {
+		path := filepath.Join(dir, c.name+".toml")
+		if c.toml != "" {
+			if err := os.WriteFile(path, []byte(c.toml), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, err := ValidateKoreonTomlConfig(path, c.cmd)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: ValidateKoreonTomlConfig() = %v, want an error with %q", c.name, err, c.err)
+		}
+	}
+
+	if _, err := IsSupportVersion("v1.22", "SupportK8sVersion"); !errors.Is(err, version.ErrUnsupported) {
+		t.Errorf("IsSupportVersion(v1.22) = %v, want ErrUnsupported", err)
+	}
+}
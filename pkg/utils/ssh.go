@@ -1,9 +1,11 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"net"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -73,7 +75,25 @@ func (S *SSH) RunCmd(cmd string) string {
 	return string(out)
 }
 
+// Output - Stdout of the command. The error has the stderr of the command.
+func (S *SSH) Output(cmd string) ([]byte, error) {
+	if S.session == nil {
+		return nil, fmt.Errorf("ssh %s@%s:%d is not connected", S.User, S.IP, S.Port)
+	}
+
+	var stderr bytes.Buffer
+	S.session.Stderr = &stderr
+	out, err := S.session.Output(cmd)
+	if err != nil {
+		return out, fmt.Errorf("%s: %w: %s", cmd, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
 func (S *SSH) Close() {
+	if S.client == nil {
+		return
+	}
 	S.session.Close()
 	S.client.Close()
 }
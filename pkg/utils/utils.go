@@ -9,14 +9,18 @@ import (
 	"io/ioutil"
 	"kore-on/cmd/koreonctl/conf"
 	"kore-on/pkg/logger"
+	"kore-on/pkg/model"
+	"kore-on/pkg/storage"
+	"kore-on/pkg/version"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"runtime"
-	"sort"
 	"strings"
 	"syscall"
+	"text/template"
 
 	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
 	"github.com/fatih/color"
@@ -119,99 +123,186 @@ func IskoreOnConfigFilePath(s string) string {
 	return currDir + sub + s
 }
 
-func IsSupportVersion(version string, conf string) string {
+// IsSupportVersion - Supported version in conf (e.g. SupportK8sVersion) that satisfies the version constraint.
+// The error explains the constraint that nothing matches.
+func IsSupportVersion(version string, conf string) (string, error) {
+	resolved, err := ResolveVersion(version, conf)
+	if err != nil {
+		return "", fmt.Errorf("koreon > %w", err)
+	}
+	return resolved, nil
+}
+
+// ResolveVersion - Highest version in conf that satisfies the constraint ("latest", "v1.24", "~1.24", ">=1.23 <1.26")
+func ResolveVersion(constraint string, conf string) (string, error) {
 	supportversion := viper.GetStringMapStringSlice(conf)
 	if len(supportversion) == 0 {
-		logger.Fatal("koreon > There is no supported version.")
-	}
-	if len(strings.Split(version, ".")) == 2 {
-		k := version
-		v, err := supportversion[version]
-		if !err {
-			logger.Fatal("koreon > There is no supported version.")
-		}
-		if len(v) == 1 && v[0] == "" {
-			version = fmt.Sprintf("%v", k)
-		} else {
-			version = fmt.Sprintf("%v.%v", k, v[len(v)-1])
-		}
+		return "", fmt.Errorf("%s: %w: there is no supported version", conf, version.ErrUnsupported)
 	}
 
-	keys := make([]string, 0, len(supportversion))
-	for k := range supportversion {
-		keys = append(keys, k)
+	resolved, err := version.Resolve(constraint, version.Expand(supportversion))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", conf, err)
 	}
-	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	return resolved, nil
+}
 
-	values, err := supportversion[keys[0]]
-	if !err {
-		logger.Fatal("koreon > There is no supported version.")
+// NextSupportVersion - Next upgrade step from current in conf (e.g. SupportHarborVersion).
+// Returns "" when current is the highest supported version.
+func NextSupportVersion(current string, conf string) (string, error) {
+	supportversion := viper.GetStringMapStringSlice(conf)
+	next, err := version.Next(current, version.Expand(supportversion))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", conf, err)
 	}
+	return next, nil
+}
 
-	latest := fmt.Sprintf("%v.%v", keys[0], values[len(values)-1])
+// GetSupportVersion - Entry of the SupportVersion matrix (key: k8s_support_image, k8s_support_package, helm_chart_package)
+// for the kubernetes version. Returns nil when there is no entry.
+func GetSupportVersion(ver string, key string) map[string]interface{} {
+	getVersion := viper.GetStringMap("SupportVersion")
 
-	if version == "" || version == "latest" {
-		return latest
-	} else {
-		major := version[0:strings.LastIndex(version, ".")]
-		minor := version[len(major)+1 : len(version)+0]
+	matrix, ok := getVersion[key].(map[string]interface{})
+	if !ok {
+		logger.Errorf("koreon > SupportVersion.%s does not exist", key)
+		return nil
+	}
 
-		for _, v := range supportversion[major] {
-			if v == minor {
-				return version
+	v, err := version.Parse(ver)
+	if err != nil {
+		logger.Errorf("koreon > SupportVersion.%s: %s", key, err.Error())
+		return nil
+	}
+
+	for k, entry := range matrix {
+		minor, err := version.Parse(k)
+		if err != nil {
+			continue
+		}
+		if minor.Major == v.Major && minor.Minor == v.Minor {
+			if list, ok := entry.(map[string]interface{}); ok {
+				return list
 			}
 		}
-		// Returns just use major version
-		return version
 	}
+
+	return nil
 }
 
-func GetSupportVersion(version string, key string) map[string]interface{} {
-	getVersion := viper.GetStringMap("SupportVersion")
-	chekVersion := false
+// ResolveSupportVersion - Versions of every component that would be chosen for the kubernetes version
+func ResolveSupportVersion(k8sVersion string, harborVersion string) (model.ResolvedVersion, error) {
+	var resolved model.ResolvedVersion
+	var err error
 
-	for k, v := range getVersion[key].(map[string]interface{}) {
-		if k == version {
-			chekVersion = true
-			return v.(map[string]interface{})
-		}
+	if resolved.Kubernetes, err = ResolveVersion(k8sVersion, "SupportK8sVersion"); err != nil {
+		return resolved, err
 	}
-	if !chekVersion {
-		for k, v := range getVersion[key].(map[string]interface{}) {
-			if k == version[0:strings.LastIndex(version, ".")] {
-				return v.(map[string]interface{})
-			}
+	if resolved.Harbor, err = ResolveVersion(harborVersion, "SupportHarborVersion"); err != nil {
+		return resolved, err
+	}
+
+	supportLists := []struct {
+		key  string
+		item interface{}
+	}{
+		{"k8s_support_image", &resolved.ImageVersion},
+		{"k8s_support_package", &resolved.PackageVersion},
+		{"helm_chart_package", &resolved.HelmChartVersion},
+	}
+	for _, l := range supportLists {
+		supportList := GetSupportVersion(resolved.Kubernetes, l.key)
+		if supportList == nil {
+			return resolved, fmt.Errorf("SupportVersion.%s has no entry for kubernetes %s", l.key, resolved.Kubernetes)
+		}
+		if _, err := setField(l.item, supportList); err != nil {
+			return resolved, fmt.Errorf("SupportVersion.%s of kubernetes %s: %w", l.key, resolved.Kubernetes, err)
 		}
 	}
 
-	return nil
+	return resolved, nil
 }
 
-func ListSupportVersion(conf string) map[string][]string {
-	supportversion := viper.GetStringMapStringSlice(conf)
+// addonChartVersionPrefix - Catalog charts versioned with "v" (csi-driver-nfs-v4.4.0.tgz).
+// The others are versioned without "v" (kore-board-0.5.5.tgz, ingress-nginx-4.5.2.tgz).
+var addonChartVersionPrefix = map[string]bool{
+	"csi-driver-nfs": true,
+	"cert-manager":   true,
+}
 
-	keys := make([]string, 0, len(supportversion))
-	for k := range supportversion {
-		keys = append(keys, k)
+// SetAddonChartVersion - Empty chart_version of the catalog apps (csi-driver-nfs, koreboard) with the chart
+// of helm_chart_package for the kubernetes version in koreon.toml, the latest chart without koreon.toml.
+// The names of the apps whose chart_version is set are returned.
+func SetAddonChartVersion(apps map[string]model.AddonApp, names []string, k8sVersion string) ([]string, error) {
+	resolved := []string{}
+	for _, name := range names {
+		app := apps[name]
+		if app.ChartVersion != "" || strings.HasSuffix(app.ChartRef, ".tgz") {
+			continue
+		}
+		ver, err := AddonChartVersion(name, k8sVersion)
+		if err != nil {
+			return nil, fmt.Errorf("apps.%s: %w", name, err)
+		}
+		if ver == "" {
+			continue
+		}
+		app.ChartVersion = ver
+		apps[name] = app
+		resolved = append(resolved, name)
 	}
-	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
 
-	for k, v := range supportversion {
-		for i, j := range v {
-			supportversion[k][i] = fmt.Sprintf("%v.%v", k, j)
+	return resolved, nil
+}
+
+// AddonChartVersion - Chart version of the catalog app (HelmChartVersion, e.g. csi-driver-nfs) for the kubernetes version.
+// The latest supported chart is used without the kubernetes version. Returns "" when the app is not in the catalog.
+func AddonChartVersion(name string, k8sVersion string) (string, error) {
+	t := reflect.TypeOf(model.HelmChartVersion{})
+	for i := 0; i < t.NumField(); i++ {
+		r := strings.Split(t.Field(i).Tag.Get("validate"), ",")
+		if len(r) < 2 || r[0] != name {
+			continue
+		}
+
+		constraint := "latest"
+		if k8sVersion != "" {
+			k8s, err := ResolveVersion(k8sVersion, "SupportK8sVersion")
+			if err != nil {
+				return "", err
+			}
+			supportList := GetSupportVersion(k8s, "helm_chart_package")
+			if v, ok := supportList[name]; ok {
+				constraint = fmt.Sprintf("%v", v)
+			}
 		}
+		ver, err := ResolveVersion(constraint, r[1])
+		if err != nil {
+			return "", err
+		}
+		if !addonChartVersionPrefix[name] {
+			ver = strings.TrimPrefix(ver, "v")
+		}
+		return ver, nil
 	}
 
-	// b, err := json.Marshal(supportversion)
-	// if err != nil {
-	// 	logger.Fatal(err)
-	// 	os.Exit(1)
-	// }
+	return "", nil
+}
 
-	// b, _ = prettyPrint(b)
-	// return string(b)
-	return supportversion
+// ListSupportVersion - Supported versions in conf grouped by minor version, each list in semantic order
+func ListSupportVersion(conf string) map[string][]string {
+	// a new map, the map of viper.Set is returned as it is
+	supportversion := map[string][]string{}
+	for k, v := range viper.GetStringMapStringSlice(conf) {
+		list, err := version.Sort(version.Expand(map[string][]string{k: v}))
+		if err != nil {
+			logger.Errorf("koreon > %s: %s", conf, err.Error())
+			list = v
+		}
+		supportversion[k] = list
+	}
 
+	return supportversion
 }
 
 func CheckUserInput(prompt string, checkWord string) bool {
@@ -275,33 +366,167 @@ func SensitivePrompt(label string) string {
 	return s
 }
 
-func SetValuesFile(key string, v map[string]interface{}) (map[string]interface{}, error) {
-	addonPath := conf.KoreOnConfigFileSubDir
-	var addonYaml string
-	var dataYaml map[string]interface{}
-	if v["ValuesFile"].(string) != "" {
-		filename := filepath.Base(v["ValuesFile"].(string))
-		addonYaml = addonPath + "/" + filename
-		yamlFile, err := ioutil.ReadFile(addonYaml)
+// SetValuesFile - Chart values of the app keyed by "<snake name>_values" (e.g. csi_driver_nfs_values).
+// The values are rendered as a go template with the context of koreon.toml (nil without koreon.toml).
+func SetValuesFile(name string, app model.AddonApp, valuesContext *model.AddonValuesContext) (map[string]interface{}, error) {
+	var data []byte
+	var err error
+
+	if app.Values != "" {
+		data = []byte(app.Values)
+	} else if app.ValuesFile != "" {
+		addonYaml := conf.KoreOnConfigFileSubDir + "/" + filepath.Base(app.ValuesFile)
+		data, err = ioutil.ReadFile(addonYaml)
 		if err != nil {
-			logger.Fatal(err)
+			return nil, fmt.Errorf("apps.%s: %w", name, err)
 		}
+	} else {
+		return nil, nil
+	}
+
+	data, err = renderAddonValues(name, "values", data, valuesContext)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("apps.%s: values is not a yaml map: %w", name, err)
+	}
+
+	return map[string]interface{}{
+		AddonValuesKey(name): values,
+	}, nil
+}
 
-		var values map[string]interface{}
+// renderAddonValues - Values with {{ .Storage.PrivateIP }}, {{ .Registry.Domain }}... of koreon.toml.
+// An undefined key is an error. "{{" of the chart itself is written as {{ "{{" }}.
+func renderAddonValues(name string, field string, data []byte, valuesContext *model.AddonValuesContext) ([]byte, error) {
+	if !bytes.Contains(data, []byte("{{")) {
+		return data, nil
+	}
+	if valuesContext == nil {
+		return nil, fmt.Errorf("apps.%s: %s refer to the cluster, but %s is not found", name, field, conf.KoreOnConfigFile)
+	}
 
-		err = yaml.Unmarshal(yamlFile, &values)
+	funcMap := template.FuncMap{
+		"join": strings.Join,
+	}
+	temp, err := template.New(name).Funcs(funcMap).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("apps.%s: %s template: %w", name, field, err)
+	}
+	var buff bytes.Buffer
+	if err := temp.Execute(&buff, valuesContext); err != nil {
+		return nil, fmt.Errorf("apps.%s: %s template: %w", name, field, err)
+	}
+	return buff.Bytes(), nil
+}
+
+// SetManifests - Manifests of the app rendered like the values. Every yaml document must be a
+// kubernetes object (apiVersion, kind, metadata.name), the objects are deployed after the chart.
+func SetManifests(name string, app model.AddonApp, valuesContext *model.AddonValuesContext) (string, error) {
+	if strings.TrimSpace(app.Manifests) == "" {
+		return "", nil
+	}
+
+	data, err := renderAddonValues(name, "manifests", []byte(app.Manifests), valuesContext)
+	if err != nil {
+		return "", err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for i := 1; ; i++ {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			logger.Fatal(err)
+			return "", fmt.Errorf("apps.%s: manifests document %d: %w", name, i, err)
+		}
+		if doc == nil {
+			continue
 		}
+		object, ok := doc.(map[interface{}]interface{})
+		if !ok {
+			return "", fmt.Errorf("apps.%s: manifests document %d is not a yaml map", name, i)
+		}
+		metadata, _ := object["metadata"].(map[interface{}]interface{})
+		if object["apiVersion"] == nil || object["kind"] == nil || metadata["name"] == nil {
+			return "", fmt.Errorf("apps.%s: manifests document %d needs apiVersion, kind and metadata.name", name, i)
+		}
+	}
+	return string(data), nil
+}
 
-		valuesKey := strcase.ToSnake(key) + "_values"
+// AddonValuesContextOf - Context of the addon values templates from koreon.toml
+func AddonValuesContextOf(koreonToml model.KoreOnToml) *model.AddonValuesContext {
+	registryDomain := koreonToml.PrivateRegistry.RegistryDomain
+	if registryDomain == "" {
+		registryDomain = koreonToml.PrivateRegistry.RegistryIP
+	}
 
-		dataYaml = map[string]interface{}{
-			valuesKey: values,
-		}
+	// defaults of the storage backend (storage-type, volume-dir, exports), koreon.toml is validated by create
+	sharedStorage := koreonToml.SharedStorage
+	sharedStorage.Exports = append([]model.StorageExport{}, sharedStorage.Exports...)
+	if sharedStorage.StorageType == "" {
+		sharedStorage.StorageType = storage.TypeNFS
 	}
+	if backend, err := storage.Get(sharedStorage.StorageType); err == nil && sharedStorage.Install {
+		k := koreonToml
+		k.SharedStorage = sharedStorage
+		backend.Validate(&k)
+		sharedStorage = k.SharedStorage
+	}
+	exports := []model.AddonStorageExportContext{}
+	for _, e := range sharedStorage.Exports {
+		exports = append(exports, model.AddonStorageExportContext{
+			Path:         e.Path,
+			StorageClass: e.StorageClass,
+			Default:      e.Default,
+		})
+	}
+
+	return &model.AddonValuesContext{
+		Cluster: model.AddonClusterContext{
+			Name:          koreonToml.KoreOn.ClusterName,
+			Version:       koreonToml.Kubernetes.Version,
+			ServiceCidr:   koreonToml.Kubernetes.ServiceCidr,
+			PodCidr:       koreonToml.Kubernetes.PodCidr,
+			NodePortRange: koreonToml.Kubernetes.NodePortRange,
+			ClosedNetwork: koreonToml.KoreOn.ClosedNetwork,
+		},
+		Storage: model.AddonStorageContext{
+			Install:     sharedStorage.Install,
+			StorageType: sharedStorage.StorageType,
+			IP:          sharedStorage.StorageIP,
+			PrivateIP:   sharedStorage.PrivateIP,
+			VolumeDir:   sharedStorage.VolumeDir,
+			VolumeSize:  sharedStorage.VolumeSize,
+			Exports:     exports,
+		},
+		Registry: model.AddonRegistryContext{
+			Install:   koreonToml.PrivateRegistry.Install,
+			Domain:    registryDomain,
+			IP:        koreonToml.PrivateRegistry.RegistryIP,
+			PrivateIP: koreonToml.PrivateRegistry.PrivateIP,
+		},
+		NodePool: model.AddonNodePoolContext{
+			DataDir:         koreonToml.NodePool.DataDir,
+			MasterIP:        koreonToml.NodePool.Master.IP,
+			MasterPrivateIP: koreonToml.NodePool.Master.PrivateIP,
+			LbIP:            koreonToml.NodePool.Master.LbIP,
+			LbPort:          koreonToml.NodePool.Master.LbPort,
+			NodeIP:          koreonToml.NodePool.Node.IP,
+			NodePrivateIP:   koreonToml.NodePool.Node.PrivateIP,
+		},
+	}
+}
 
-	return dataYaml, nil
+// AddonValuesKey - Key of the app values in addon_apps of the extravars file
+func AddonValuesKey(name string) string {
+	return strcase.ToSnake(name) + "_values"
 }
 
 // customTrasnformer
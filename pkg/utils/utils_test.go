@@ -0,0 +1,250 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"kore-on/pkg/logger"
+	"kore-on/pkg/model"
+	"kore-on/pkg/version"
+
+	"github.com/spf13/viper"
+)
+
+func TestMain(m *testing.M) {
+	if err := logger.NewLogger(logger.Config{EnableConsole: true, ConsoleLevel: logger.LevelFatal}, logger.InstanceZapLogger); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func setSupportVersion(t *testing.T) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("SupportK8sVersion", map[string][]string{
+		"v1.23": {"8", "17"},
+		"v1.24": {"0", "9", "10"},
+		"v1.25": {"6"},
+	})
+	viper.Set("SupportVersion", map[string]interface{}{
+		"helm_chart_package": map[string]interface{}{
+			"v1.24": map[string]interface{}{"metallb": "0.13.9"},
+		},
+	})
+}
+
+func TestResolveVersion(t *testing.T) {
+	setSupportVersion(t)
+
+	cases := map[string]string{
+		"":             "v1.25.6",
+		"latest":       "v1.25.6",
+		"v1.24.9":      "v1.24.9",
+		"v1.24":        "v1.24.10",
+		"~1.23":        "v1.23.17",
+		">=1.23 <1.25": "v1.24.10",
+		"^1.23":        "v1.25.6",
+	}
+	for constraint, want := range cases {
+		got, err := ResolveVersion(constraint, "SupportK8sVersion")
+		if err != nil {
+			t.Errorf("ResolveVersion(%q) error: %v", constraint, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ResolveVersion(%q) = %s, want %s", constraint, got, want)
+		}
+	}
+}
+
+func TestResolveVersionUnsupported(t *testing.T) {
+	setSupportVersion(t)
+
+	for _, c := range []struct{ constraint, conf string }{
+		{"v1.26.1", "SupportK8sVersion"},
+		{"v1.22", "SupportK8sVersion"},
+		{"latest", "SupportUnknownVersion"},
+	} {
+		if _, err := ResolveVersion(c.constraint, c.conf); !errors.Is(err, version.ErrUnsupported) {
+			t.Errorf("ResolveVersion(%q, %s) error = %v, want ErrUnsupported", c.constraint, c.conf, err)
+		}
+	}
+}
+
+func TestGetSupportVersion(t *testing.T) {
+	setSupportVersion(t)
+
+	if got := GetSupportVersion("v1.24.10", "helm_chart_package"); got["metallb"] != "0.13.9" {
+		t.Errorf("GetSupportVersion(v1.24.10) = %v", got)
+	}
+	if got := GetSupportVersion("v1.25.6", "helm_chart_package"); got != nil {
+		t.Errorf("GetSupportVersion(v1.25.6) = %v, want nil", got)
+	}
+	if got := GetSupportVersion("v1.24.10", "k8s_support_image"); got != nil {
+		t.Errorf("GetSupportVersion of a missing key = %v, want nil", got)
+	}
+}
+
+func TestSetField(t *testing.T) {
+	setSupportVersion(t)
+	supportList := map[string]interface{}{}
+	for name, conf := range map[string]string{
+		"csi-driver-nfs":        "ChartCsiDriverNfsVersion",
+		"koreboard":             "ChartKoreboardVersion",
+		"ingress-nginx":         "ChartIngressNginxVersion",
+		"metallb":               "ChartMetallbVersion",
+		"cert-manager":          "ChartCertManagerVersion",
+		"kube-prometheus-stack": "ChartKubePrometheusStackVersion",
+	} {
+		viper.Set(conf, map[string][]string{"v1.0": {"0", "1"}})
+		supportList[name] = "v1.0"
+	}
+
+	// longhorn is optional
+	charts := model.HelmChartVersion{}
+	if _, err := setField(&charts, supportList); err != nil {
+		t.Fatal(err)
+	}
+	if charts.Metallb != "v1.0.1" || charts.Longhorn != "" {
+		t.Errorf("setField() = %+v, want metallb v1.0.1 without longhorn", charts)
+	}
+
+	delete(supportList, "metallb")
+	_, err := setField(&model.HelmChartVersion{}, supportList)
+	if err == nil || !strings.Contains(err.Error(), "metallb has no version in the support list (ChartMetallbVersion)") {
+		t.Errorf("setField() without metallb = %v, want an error", err)
+	}
+}
+
+func TestSetAddonChartVersion(t *testing.T) {
+	setSupportVersion(t)
+	viper.Set("ChartMetallbVersion", map[string][]string{"v0.13": {"7", "9"}, "v0.14": {"1"}})
+
+	apps := map[string]model.AddonApp{
+		"metallb": {ChartRef: "https://metallb.github.io/metallb", ChartName: "metallb"},
+		"pinned":  {ChartRef: "https://charts.local", ChartName: "pinned", ChartVersion: "1.0.0"},
+		"custom":  {ChartRef: "https://charts.local", ChartName: "custom"},
+	}
+	names := []string{"custom", "metallb", "pinned"}
+
+	resolved, err := SetAddonChartVersion(apps, names, "v1.24.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 1 || resolved[0] != "metallb" {
+		t.Errorf("resolved = %v, want [metallb]", resolved)
+	}
+	// helm_chart_package of v1.24 pins metallb 0.13.9, the chart is versioned without "v"
+	if got := apps["metallb"].ChartVersion; got != "0.13.9" {
+		t.Errorf("metallb chart_version = %q, want 0.13.9", got)
+	}
+	if got := apps["pinned"].ChartVersion; got != "1.0.0" {
+		t.Errorf("pinned chart_version = %q, want 1.0.0", got)
+	}
+	if got := apps["custom"].ChartVersion; got != "" {
+		t.Errorf("custom chart_version = %q, want empty", got)
+	}
+
+	// without koreon.toml the latest supported chart is used
+	apps["metallb"] = model.AddonApp{ChartRef: "https://metallb.github.io/metallb", ChartName: "metallb"}
+	if _, err := SetAddonChartVersion(apps, names, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := apps["metallb"].ChartVersion; got != "0.14.1" {
+		t.Errorf("metallb chart_version without kubernetes version = %q, want 0.14.1", got)
+	}
+}
+
+func TestSetManifests(t *testing.T) {
+	valuesContext := &model.AddonValuesContext{}
+	valuesContext.Cluster.Name = "test"
+
+	pool := `apiVersion: metallb.io/v1beta1
+kind: IPAddressPool
+metadata:
+  name: {{ .Cluster.Name }}
+spec:
+  addresses:
+  - 192.168.0.240-192.168.0.250
+---
+apiVersion: metallb.io/v1beta1
+kind: L2Advertisement
+metadata:
+  name: default
+`
+	got, err := SetManifests("metallb", model.AddonApp{Manifests: pool}, valuesContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "name: test") {
+		t.Errorf("manifests are not rendered with the cluster:\n%s", got)
+	}
+
+	cases := []struct {
+		name      string
+		manifests string
+		context   *model.AddonValuesContext
+		err       string
+	}{
+		{"empty", "", nil, ""},
+		{"trailing separator", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\n", nil, ""},
+		{"no kind", "apiVersion: v1\nmetadata:\n  name: a\n", nil, "document 1 needs apiVersion, kind and metadata.name"},
+		{"no name", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: ConfigMap\n", nil, "document 2 needs"},
+		{"not a map", "- a\n- b\n", nil, "document 1 is not a yaml map"},
+		{"no koreon.toml", pool, nil, "manifests refer to the cluster"},
+		{"undefined key", "kind: {{ .Cluster.Kind }}", valuesContext, "manifests template"},
+	}
+	for _, c := range cases {
+		_, err := SetManifests("app", model.AddonApp{Manifests: c.manifests}, c.context)
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%s: SetManifests() = %v, want nil", c.name, err)
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			t.Errorf("%s: SetManifests() = %v, want an error with %q", c.name, err, c.err)
+		}
+	}
+}
+
+func TestAddonValuesContextOf(t *testing.T) {
+	koreonToml := model.KoreOnToml{}
+	koreonToml.SharedStorage = model.SharedStorage{Install: true, StorageIP: "192.168.77.20", VolumeDir: "/data/storage"}
+
+	storage := AddonValuesContextOf(koreonToml).Storage
+	if storage.StorageType != "nfs" {
+		t.Errorf("StorageType = %q, want nfs", storage.StorageType)
+	}
+	// volume-dir is the one export of the default StorageClass
+	want := []model.AddonStorageExportContext{{Path: "/data/storage", StorageClass: "default-storage", Default: true}}
+	if !reflect.DeepEqual(storage.Exports, want) {
+		t.Errorf("Exports = %+v, want %+v", storage.Exports, want)
+	}
+
+	koreonToml.SharedStorage = model.SharedStorage{
+		Install:     true,
+		StorageType: "external-nfs",
+		StorageIP:   "192.168.77.30",
+		Exports: []model.StorageExport{
+			{Path: "/export/fast", StorageClass: "fast"},
+			{Path: "/export/slow", StorageClass: "slow", Default: true},
+		},
+	}
+	storage = AddonValuesContextOf(koreonToml).Storage
+	want = []model.AddonStorageExportContext{
+		{Path: "/export/fast", StorageClass: "fast"},
+		{Path: "/export/slow", StorageClass: "slow", Default: true},
+	}
+	if storage.StorageType != "external-nfs" || !reflect.DeepEqual(storage.Exports, want) {
+		t.Errorf("Storage = %+v, want external-nfs with %+v", storage, want)
+	}
+	if storage.VolumeDir != "/export/slow" {
+		t.Errorf("VolumeDir = %q, want the default export /export/slow", storage.VolumeDir)
+	}
+	if koreonToml.SharedStorage.Exports[0].MountOptions != nil {
+		t.Error("exports of koreon.toml are changed by the defaults")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 func CheckKoreonToml(value *model.KoreOnToml) error {
@@ -15,6 +16,43 @@ func CheckKoreonToml(value *model.KoreOnToml) error {
 	return nil
 }
 
+// CheckKubeconfig - kubeconfig of addon --kubeconfig. Only the file is mounted into the kore-on container,
+// so the certificates of the current context must be embedded (*-data) and plugins are not available.
+func CheckKubeconfig(path string) error {
+	config, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return fmt.Errorf("kubeconfig %s: %s", path, err.Error())
+	}
+	context, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return fmt.Errorf("kubeconfig %s has no current-context", path)
+	}
+
+	files := []string{}
+	if cluster, ok := config.Clusters[context.Cluster]; ok && cluster.CertificateAuthority != "" {
+		files = append(files, "certificate-authority")
+	}
+	if user, ok := config.AuthInfos[context.AuthInfo]; ok {
+		if user.ClientCertificate != "" {
+			files = append(files, "client-certificate")
+		}
+		if user.ClientKey != "" {
+			files = append(files, "client-key")
+		}
+		if user.TokenFile != "" {
+			files = append(files, "tokenFile")
+		}
+		if user.Exec != nil || user.AuthProvider != nil {
+			return fmt.Errorf("kubeconfig %s: exec and auth-provider users can not run in the kore-on container. Use a certificate or token user", path)
+		}
+	}
+	if len(files) > 0 {
+		return fmt.Errorf("kubeconfig %s refers to files (%s) that are not in the kore-on container. "+
+			"Embed them with 'kubectl config view --minify --flatten'", path, strings.Join(files, ", "))
+	}
+	return nil
+}
+
 func CheckCommand(cmd *cobra.Command) error {
 	cmdCheck := cmd.Commands()
 
@@ -31,6 +69,9 @@ func CheckCommand(cmd *cobra.Command) error {
 			if cmd.Name() == "bastion" {
 				return nil
 			}
+			if cmd.Name() == "versions" {
+				return nil
+			}
 			args := append([]string{"koreonctl"}, os.Args[1:]...)
 			buf := new(bytes.Buffer)
 			cmd.SetErr(buf)
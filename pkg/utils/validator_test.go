@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: admin@koreon
+contexts:
+- name: admin@koreon
+  context:
+    cluster: koreon
+    user: admin
+clusters:
+- name: koreon
+  cluster:
+    server: https://192.168.77.10:6443
+%s
+users:
+- name: admin
+  user:
+%s
+`
+
+func TestCheckKubeconfig(t *testing.T) {
+	cases := []struct {
+		name    string
+		cluster string
+		user    string
+		err     string
+	}{
+		{"embedded", "    certificate-authority-data: Y2E=", "    client-certificate-data: Y2VydA==\n    client-key-data: a2V5", ""},
+		{"token", "    insecure-skip-tls-verify: true", "    token: abc", ""},
+		{"ca file", "    certificate-authority: /etc/kubernetes/pki/ca.crt", "    token: abc", "certificate-authority"},
+		{"client files", "    certificate-authority-data: Y2E=", "    client-certificate: admin.crt\n    client-key: admin.key", "client-certificate, client-key"},
+		{"exec", "    certificate-authority-data: Y2E=", "    exec:\n      apiVersion: client.authentication.k8s.io/v1beta1\n      command: kubelogin", "exec and auth-provider"},
+	}
+	for _, c := range cases {
+		path := filepath.Join(t.TempDir(), "kubeconfig")
+		if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(testKubeconfig, c.cluster, c.user)), 0600); err != nil {
+			t.Fatal(err)
+		}
+		err := CheckKubeconfig(path)
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%s: CheckKubeconfig() = %v, want nil", c.name, err)
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			t.Errorf("%s: CheckKubeconfig() = %v, want an error with %q", c.name, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package version
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ===== [ Types ] =====
+
+// Constraint - Version constraint. Comparators separated by spaces or commas must all match,
+// alternatives separated by "||" are OR'ed.
+type Constraint struct {
+	original string
+	groups   [][]comparator
+}
+
+type comparator struct {
+	op string
+	v  Version
+}
+
+// ===== [ Implementations ] =====
+
+// Check - Whether the version satisfies the constraint
+func (c *Constraint) Check(v Version) bool {
+	if len(c.groups) == 0 {
+		return true
+	}
+	for _, group := range c.groups {
+		matched := true
+		for _, cmp := range group {
+			if !cmp.check(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// String - Constraint as given
+func (c *Constraint) String() string {
+	return c.original
+}
+
+func (cmp comparator) check(v Version) bool {
+	r := v.Compare(cmp.v)
+	switch cmp.op {
+	case "=":
+		return r == 0
+	case "!=":
+		return r != 0
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "<":
+		return r < 0
+	case "<=":
+		return r <= 0
+	}
+	return false
+}
+
+// ===== [ Private Functions ] =====
+
+// next - Lowest version above every version that starts with v (v1.24 -> v1.25.0, v1 -> v2.0.0)
+func next(v Version) Version {
+	switch v.Parts {
+	case 1:
+		return Version{Major: v.Major + 1, Parts: 3}
+	case 2:
+		return Version{Major: v.Major, Minor: v.Minor + 1, Parts: 3}
+	default:
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1, Parts: 3}
+	}
+}
+
+func parseComparator(s string) ([]comparator, error) {
+	op := ""
+	for _, o := range []string{">=", "<=", "!=", ">", "<", "=", "~", "^"} {
+		if strings.HasPrefix(s, o) {
+			op = o
+			break
+		}
+	}
+
+	v, err := Parse(strings.TrimPrefix(s, op))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidConstraint, s)
+	}
+
+	switch op {
+	case "", "=":
+		// partial versions match the whole series: "1.24" = ">=1.24.0 <1.25.0"
+		if v.Parts < 3 {
+			return []comparator{{">=", v}, {"<", next(v)}}, nil
+		}
+		return []comparator{{"=", v}}, nil
+	case "~":
+		upper := next(Version{Major: v.Major, Minor: v.Minor, Parts: 2})
+		if v.Parts == 1 {
+			upper = next(Version{Major: v.Major, Parts: 1})
+		}
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	case "^":
+		upper := next(Version{Major: v.Major, Parts: 1})
+		if v.Major == 0 && v.Parts > 1 {
+			upper = next(Version{Major: 0, Minor: v.Minor, Parts: 2})
+		}
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	case ">":
+		// "> 1.24" means above every 1.24.x
+		if v.Parts < 3 {
+			return []comparator{{">=", next(v)}}, nil
+		}
+	case "<=":
+		// "<= 1.25" includes every 1.25.x
+		if v.Parts < 3 {
+			return []comparator{{"<", next(v)}}, nil
+		}
+	case "!=":
+		if v.Parts < 3 {
+			return nil, fmt.Errorf("%w: %q, '!=' requires a full version", ErrInvalidConstraint, s)
+		}
+	}
+
+	return []comparator{{op, v}}, nil
+}
+
+// ===== [ Public Functions ] =====
+
+// ParseConstraint - Parse constraints such as "latest", "v1.24", "~1.24", "^1.24" or ">=1.23 <1.26"
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{original: s}
+
+	str := strings.TrimSpace(s)
+	if str == "" || str == "latest" || str == "*" {
+		return c, nil
+	}
+
+	for _, alt := range strings.Split(str, "||") {
+		// allow a space between the operator and the version (">= 1.23")
+		fields := strings.Fields(strings.ReplaceAll(alt, ",", " "))
+		tokens := []string{}
+		for i := 0; i < len(fields); i++ {
+			if strings.Trim(fields[i], "<>=!~^") == "" && i+1 < len(fields) {
+				tokens = append(tokens, fields[i]+fields[i+1])
+				i++
+				continue
+			}
+			tokens = append(tokens, fields[i])
+		}
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidConstraint, s)
+		}
+
+		group := []comparator{}
+		for _, token := range tokens {
+			cmps, err := parseComparator(token)
+			if err != nil {
+				return nil, err
+			}
+			group = append(group, cmps...)
+		}
+		c.groups = append(c.groups, group)
+	}
+
+	return c, nil
+}
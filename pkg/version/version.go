@@ -0,0 +1,206 @@
+// Package version - Semantic version ordering and constraint matching for the support version maps
+package version
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ===== [ Constants and Variables ] =====
+
+var (
+	// ErrInvalidVersion is returned when a string is not a version
+	ErrInvalidVersion = errors.New("invalid version")
+	// ErrInvalidConstraint is returned when a constraint can not be parsed
+	ErrInvalidConstraint = errors.New("invalid version constraint")
+	// ErrUnsupported is returned when no supported version satisfies a constraint
+	ErrUnsupported = errors.New("unsupported version")
+)
+
+// ===== [ Types ] =====
+
+// Version - Parsed version. Original keeps the spelling used in conf/config.yaml (e.g. "v3.3" without patch)
+type Version struct {
+	Major    int
+	Minor    int
+	Patch    int
+	Parts    int
+	Original string
+}
+
+// Versions - Sortable list of versions
+type Versions []Version
+
+// ===== [ Implementations ] =====
+
+// Compare - -1, 0 or 1 when v is lower, equal or higher than o
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// String - Version as written in the support map
+func (v Version) String() string {
+	return v.Original
+}
+
+func (vs Versions) Len() int           { return len(vs) }
+func (vs Versions) Less(i, j int) bool { return vs[i].Compare(vs[j]) < 0 }
+func (vs Versions) Swap(i, j int)      { vs[i], vs[j] = vs[j], vs[i] }
+
+// Strings - Versions as written in the support map
+func (vs Versions) Strings() []string {
+	result := make([]string, 0, len(vs))
+	for _, v := range vs {
+		result = append(result, v.Original)
+	}
+	return result
+}
+
+// ===== [ Public Functions ] =====
+
+// Parse - Parse "v1.24.10", "1.24" or "v3.3". Missing parts are zero.
+func Parse(s string) (Version, error) {
+	v := Version{Original: s}
+
+	str := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if str == "" {
+		return v, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+	}
+
+	parts := strings.Split(str, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+	}
+
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+		}
+		*nums[i] = n
+	}
+	v.Parts = len(parts)
+
+	return v, nil
+}
+
+// Sort - Sort version strings in ascending semantic order. Invalid entries are returned as an error.
+func Sort(list []string) ([]string, error) {
+	versions, err := parseAll(list)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(versions)
+	return versions.Strings(), nil
+}
+
+// Expand - Flatten a support map ({"v1.24": ["0", "1"]}) into full versions ("v1.24.0", "v1.24.1").
+// An empty patch entry means the minor version itself is the release (e.g. pause "v3.3").
+func Expand(supportMap map[string][]string) []string {
+	result := []string{}
+	for minor, patches := range supportMap {
+		for _, patch := range patches {
+			if patch == "" {
+				result = append(result, minor)
+			} else {
+				result = append(result, fmt.Sprintf("%v.%v", minor, patch))
+			}
+		}
+	}
+	return result
+}
+
+// Resolve - Highest of the supported versions that satisfies the constraint.
+//
+//	""/"latest"      highest supported version
+//	"v1.24"          highest v1.24.x (same as "~1.24")
+//	"v1.24.10"       exactly v1.24.10
+//	">=1.23 <1.26"   highest version in the range
+func Resolve(constraint string, supported []string) (string, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+
+	versions, err := parseAll(supported)
+	if err != nil {
+		return "", err
+	}
+	if len(versions) == 0 {
+		return "", fmt.Errorf("%w: there is no supported version", ErrUnsupported)
+	}
+	sort.Sort(sort.Reverse(versions))
+
+	for _, v := range versions {
+		if c.Check(v) {
+			return v.Original, nil
+		}
+	}
+
+	sort.Sort(versions)
+	return "", fmt.Errorf("%w: %q does not match any supported version (supported: %s)", ErrUnsupported, constraint, summarize(versions))
+}
+
+// Next - Next upgrade step from current: the highest supported patch of the same minor version
+// when current is not the highest, otherwise the highest patch of the next supported minor version.
+// Returns "" when current is the highest supported version.
+func Next(current string, supported []string) (string, error) {
+	cur, err := Parse(current)
+	if err != nil {
+		return "", err
+	}
+
+	versions, err := parseAll(supported)
+	if err != nil {
+		return "", err
+	}
+	sort.Sort(versions)
+
+	var step *Version
+	for i, v := range versions {
+		if v.Compare(cur) <= 0 {
+			continue
+		}
+		if step == nil || (v.Major == step.Major && v.Minor == step.Minor) {
+			step = &versions[i]
+		}
+	}
+	if step == nil {
+		return "", nil
+	}
+	return step.Original, nil
+}
+
+// ===== [ Private Functions ] =====
+
+func parseAll(list []string) (Versions, error) {
+	versions := make(Versions, 0, len(list))
+	for _, s := range list {
+		v, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// summarize - "v1.19.10 ~ v1.26.1" style range for error messages
+func summarize(versions Versions) string {
+	if len(versions) == 1 {
+		return versions[0].Original
+	}
+	return versions[0].Original + " ~ " + versions[len(versions)-1].Original
+}
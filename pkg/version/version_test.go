@@ -0,0 +1,107 @@
+package version
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var supported = []string{
+	"v1.23.8", "v1.23.17",
+	"v1.24.0", "v1.24.10", "v1.24.9",
+	"v1.25.6",
+	"v1.26.1",
+	"v2.0.1",
+}
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		constraint string
+		want       string
+	}{
+		// latest
+		{"", "v2.0.1"},
+		{"latest", "v2.0.1"},
+		// exact
+		{"v1.24.10", "v1.24.10"},
+		{"1.24.9", "v1.24.9"},
+		{"=v1.23.8", "v1.23.8"},
+		// minor
+		{"v1.24", "v1.24.10"},
+		{"1.23", "v1.23.17"},
+		{"~1.24", "v1.24.10"},
+		{"~1.24.9", "v1.24.10"},
+		// range
+		{">=1.23 <1.26", "v1.25.6"},
+		{">= 1.23, < 1.25", "v1.24.10"},
+		{"<=1.25", "v1.25.6"},
+		{">1.24 <2", "v1.26.1"},
+		{"v1.23 || v1.25", "v1.25.6"},
+		// caret
+		{"^1.24", "v1.26.1"},
+		{"^1.24.10", "v1.26.1"},
+		{"^2", "v2.0.1"},
+	}
+	for _, c := range cases {
+		got, err := Resolve(c.constraint, supported)
+		if err != nil {
+			t.Errorf("Resolve(%q) error: %v", c.constraint, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("Resolve(%q) = %s, want %s", c.constraint, got, c.want)
+		}
+	}
+}
+
+func TestResolveUnsupported(t *testing.T) {
+	for _, constraint := range []string{"v1.24.11", "v1.22", "~1.27", ">=3", "^0.9"} {
+		_, err := Resolve(constraint, supported)
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Resolve(%q) error = %v, want ErrUnsupported", constraint, err)
+		}
+	}
+
+	if _, err := Resolve("latest", nil); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Resolve without supported versions error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestResolveInvalid(t *testing.T) {
+	for _, constraint := range []string{"v1.x", ">=", "!=1.24", "1.2.3.4"} {
+		_, err := Resolve(constraint, supported)
+		if !errors.Is(err, ErrInvalidConstraint) && !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("Resolve(%q) error = %v, want an invalid constraint", constraint, err)
+		}
+	}
+}
+
+func TestSortAndExpand(t *testing.T) {
+	got, err := Sort(Expand(map[string][]string{"v1.24": {"10", "9", "0"}, "v3.3": {""}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"v1.24.0", "v1.24.9", "v1.24.10", "v3.3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Sort(Expand()) = %v, want %v", got, want)
+	}
+}
+
+func TestNext(t *testing.T) {
+	cases := map[string]string{
+		"v1.24.0":  "v1.24.10",
+		"v1.24.10": "v1.25.6",
+		"v1.26.1":  "v2.0.1",
+		"v2.0.1":   "",
+	}
+	for current, want := range cases {
+		got, err := Next(current, supported)
+		if err != nil {
+			t.Errorf("Next(%s) error: %v", current, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Next(%s) = %q, want %q", current, got, want)
+		}
+	}
+}